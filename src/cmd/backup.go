@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/validatedpatterns/patternizer/internal/backup"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// startBackup backs up the files under paths before command changes them.
+func startBackup(repoRoot, command string, paths []string, rep *report.Report) (*backup.Backup, error) {
+	b, err := backup.Create(repoRoot, command, paths, time.Now())
+	if err != nil {
+		return nil, report.Errorf(report.CodeBackup, "error creating backup: %w", err)
+	}
+	rep.Infof("Backed up %d files to %s; undo the changes with patternizer restore %s", len(b.Files), filepath.ToSlash(filepath.Join(backup.Dir, b.ID)), b.ID)
+	return b, nil
+}
+
+// finishBackup records the files the command created in b, so that restoring
+// it deletes them.
+func finishBackup(b *backup.Backup, rep *report.Report) error {
+	var created []string
+	for _, change := range rep.Files {
+		if change.Action == report.ActionCreated {
+			created = append(created, change.Path)
+		}
+	}
+	if err := b.RecordCreated(created); err != nil {
+		return report.Errorf(report.CodeBackup, "error updating backup: %w", err)
+	}
+	return nil
+}
+
+// runRestore lists the backups of the repository or, given an ID, restores
+// the files of that backup and deletes the files its command created.
+func runRestore(id string, rep *report.Report) (err error) {
+	_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	if id == "" {
+		backups, err := backup.List(repoRoot)
+		if err != nil {
+			return report.Errorf(report.CodeBackup, "error listing backups: %w", err)
+		}
+		if len(backups) == 0 {
+			rep.Infof("No backups found; run init or upgrade with --backup to create one")
+			return nil
+		}
+		for _, b := range backups {
+			rep.Infof("%s  %-8s %s  %d files backed up, %d created", b.ID, b.Command, b.Time.Local().Format(time.RFC3339), len(b.Files), len(b.Created))
+		}
+		return nil
+	}
+
+	b, err := backup.Load(repoRoot, id)
+	if err != nil {
+		return report.Errorf(report.CodeBackup, "error loading backup: %w", err)
+	}
+	paths := b.Paths()
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if err := b.Restore(repoRoot); err != nil {
+		return report.Errorf(report.CodeBackup, "error restoring backup %s: %w", id, err)
+	}
+	rep.Infof("Restored the files changed by %s at %s from backup %s", b.Command, b.Time.Local().Format(time.RFC3339), b.ID)
+	return nil
+}
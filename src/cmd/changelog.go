@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+)
+
+// changelogResult is the output of the changelog command.
+type changelogResult struct {
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Changes []resources.FileChange `json:"changes"`
+}
+
+// runChangelog summarizes the differences between the resource version of the
+// repository (or fromVersion) and toVersion (or the newest embedded version).
+func runChangelog(out io.Writer, format, fromVersion, toVersion string) error {
+	catalog := resources.Embedded()
+
+	if fromVersion == "" {
+		_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+		if err != nil {
+			return fmt.Errorf("error getting pattern information: %w", err)
+		}
+		fromVersion, err = currentResourceVersion(repoRoot, catalog)
+		if err != nil {
+			return err
+		}
+		if fromVersion == "" {
+			versions, err := catalog.Versions()
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("cannot determine the resource version of this repository; pass --from with one of: %s", strings.Join(versions, ", "))
+		}
+	}
+	if toVersion == "" {
+		toVersion = catalog.Current()
+	}
+
+	fromSet, from, err := catalog.Set(fromVersion)
+	if err != nil {
+		return fmt.Errorf("error selecting --from version: %w", err)
+	}
+	toSet, to, err := catalog.Set(toVersion)
+	if err != nil {
+		return fmt.Errorf("error selecting --to version: %w", err)
+	}
+
+	// Compare the files as they are generated with the default settings
+	// rather than the raw templates.
+	if fromSet, err = resources.Render(fromSet, resources.TemplateData{}); err != nil {
+		return err
+	}
+	if toSet, err = resources.Render(toSet, resources.TemplateData{}); err != nil {
+		return err
+	}
+
+	changes, err := resources.Changelog(fromSet, toSet)
+	if err != nil {
+		return fmt.Errorf("error comparing resource versions: %w", err)
+	}
+	result := changelogResult{From: from, To: to, Changes: changes}
+
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	writeChangelogText(out, result)
+	return nil
+}
+
+func writeChangelogText(out io.Writer, result changelogResult) {
+	if result.From == result.To {
+		fmt.Fprintf(out, "Resources are already at version %s\n", result.To)
+		return
+	}
+
+	fmt.Fprintf(out, "Resource changes from %s to %s:\n", result.From, result.To)
+	for _, change := range result.Changes {
+		switch change.Status {
+		case resources.StatusUnchanged:
+			fmt.Fprintf(out, "  %s: unchanged\n", change.Name)
+			continue
+		case resources.StatusChanged:
+			fmt.Fprintf(out, "  %s: changed (+%d -%d lines)\n", change.Name, change.LinesAdded, change.LinesRemoved)
+		default:
+			fmt.Fprintf(out, "  %s: %s\n", change.Name, change.Status)
+		}
+		if len(change.TargetsAdded) > 0 {
+			fmt.Fprintf(out, "    targets added: %s\n", strings.Join(change.TargetsAdded, ", "))
+		}
+		if len(change.TargetsRemoved) > 0 {
+			fmt.Fprintf(out, "    targets removed: %s\n", strings.Join(change.TargetsRemoved, ", "))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+)
+
+var _ = Describe("patternizer changelog", func() {
+	It("should report that an initialized repository is up to date", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+
+		session := runCLI(tempDir, "changelog")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Resources are already at version " + embedded.ResourceVersion))
+	})
+
+	It("should detect the resource version of a repository without metadata", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+		Expect(os.Remove(metadata.Path(tempDir))).To(Succeed())
+
+		session := runCLI(tempDir, "changelog", "--output", "json")
+		var result map[string]interface{}
+		Expect(json.Unmarshal(session.Out.Contents(), &result)).To(Succeed())
+		Expect(result["from"]).To(Equal(embedded.ResourceVersion))
+		Expect(result["to"]).To(Equal(embedded.ResourceVersion))
+	})
+
+	It("should summarize the changes since an archived resource version", func() {
+		session := runCLI(createTestDir(), "changelog", "--from", "v2.2.0", "--output", "json")
+		var result changelogOutput
+		Expect(json.Unmarshal(session.Out.Contents(), &result)).To(Succeed())
+		Expect(result.From).To(Equal("v2.2.0"))
+		Expect(result.To).To(Equal(embedded.ResourceVersion))
+		statuses := make(map[string]string)
+		for _, change := range result.Changes {
+			statuses[change.Name] = change.Status
+		}
+		Expect(statuses).To(HaveKey("pattern.sh"))
+		Expect(statuses).To(HaveKeyWithValue("Makefile-common", "unchanged"))
+	})
+
+	It("should ask for --from when the resource version cannot be determined", func() {
+		tempDir := createTestDir()
+		Expect(os.WriteFile(filepath.Join(tempDir, "pattern.sh"), []byte("#!/bin/bash\n# custom\n"), 0o755)).To(Succeed())
+
+		session := runCLIWithExitCode(tempDir, 1, "changelog")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("pass --from"))
+	})
+})
+
+var _ = Describe("patternizer upgrade --to", func() {
+	It("should reject unknown resource versions", func() {
+		session := runCLIWithExitCode(createTestDir(), 1, "upgrade", "--to", "v0.0.1")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("unknown resource version"))
+	})
+
+	It("should refuse to downgrade resources recorded as newer", func() {
+		tempDir := createTestDir()
+		Expect(metadata.Save(tempDir, &metadata.Metadata{ResourceVersion: "v99.0.0"})).To(Succeed())
+
+		session := runCLIWithExitCode(tempDir, 1, "upgrade", "--to", embedded.ResourceVersion)
+		Expect(string(session.Err.Contents())).To(ContainSubstring("--allow-downgrade"))
+		Expect(filepath.Join(tempDir, "pattern.sh")).NotTo(BeAnExistingFile())
+
+		_ = runCLI(tempDir, "upgrade", "--to", embedded.ResourceVersion, "--allow-downgrade")
+		verifyPattenShCopied(tempDir)
+		m, err := metadata.Load(tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ResourceVersion).To(Equal(embedded.ResourceVersion))
+	})
+
+	It("should write an archived resource set", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+
+		session := runCLIWithExitCode(tempDir, 1, "upgrade", "--to", "v2.2.0")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("--allow-downgrade"))
+
+		_ = runCLI(tempDir, "upgrade", "--to", "v2.2.0", "--allow-downgrade")
+		want, err := embedded.History.ReadFile("history/v2.2.0/resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filepath.Join(tempDir, "pattern.sh"))).To(Equal(want))
+		m, err := metadata.Load(tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ResourceVersion).To(Equal("v2.2.0"))
+
+		session = runCLI(tempDir, "changelog")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Resource changes from v2.2.0 to " + embedded.ResourceVersion))
+	})
+})
+
+// changelogOutput is the JSON output of the changelog command.
+type changelogOutput struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Changes []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	} `json:"changes"`
+}
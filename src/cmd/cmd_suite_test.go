@@ -3,8 +3,10 @@ package cmd_test
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"testing"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/onsi/gomega/gexec"
 	"gopkg.in/yaml.v3"
 
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/resources"
 	"github.com/validatedpatterns/patternizer/internal/types"
 )
 
@@ -51,8 +55,7 @@ var _ = AfterSuite(func() {
 
 func verifyPattenShCopied(dir string) {
 	actual := filepath.Join(dir, "pattern.sh")
-	expected := filepath.Join(resourcesPath, "pattern.sh")
-	verifyFilesMatch(actual, expected)
+	verifyResourceRendered(dir, "pattern.sh")
 
 	// verify pattern.sh is executable
 	info, err := os.Stat(actual)
@@ -61,21 +64,15 @@ func verifyPattenShCopied(dir string) {
 }
 
 func verifyMakefileCommonCopied(dir string) {
-	actual := filepath.Join(dir, "Makefile-common")
-	expected := filepath.Join(resourcesPath, "Makefile-common")
-	verifyFilesMatch(actual, expected)
+	verifyResourceRendered(dir, "Makefile-common")
 }
 
 func verifyMakefileCopied(dir string) {
-	actual := filepath.Join(dir, "Makefile")
-	expected := filepath.Join(resourcesPath, "Makefile")
-	verifyFilesMatch(actual, expected)
+	verifyResourceRendered(dir, "Makefile")
 }
 
 func verifyAnsibleCfgCopied(dir string) {
-	actual := filepath.Join(dir, "ansible.cfg")
-	expected := filepath.Join(resourcesPath, "ansible.cfg")
-	verifyFilesMatch(actual, expected)
+	verifyResourceRendered(dir, "ansible.cfg")
 }
 
 func verifyScaffoldFilesCopied(dir string) {
@@ -91,6 +88,27 @@ func verifySecretTemplateCopied(dir string) {
 	verifyFilesMatch(actual, expected)
 }
 
+// verifyResourceRendered checks that the file name in dir matches the embedded
+// resource rendered with the pattern settings of dir.
+func verifyResourceRendered(dir, name string) {
+	values, err := pattern.LoadGlobalValues(filepath.Base(dir), dir)
+	Expect(err).NotTo(HaveOccurred())
+	data := resources.TemplateData{
+		PatternName:  values.Global.Pattern,
+		ClusterGroup: values.Main.ClusterGroupName,
+		WithSecrets:  !values.Global.SecretLoader.Disabled,
+	}
+
+	rendered, err := resources.Render(os.DirFS(filepath.Dir(resourcesPath)), data)
+	Expect(err).NotTo(HaveOccurred())
+	expected, err := fs.ReadFile(rendered, path.Join(resources.Dir, name))
+	Expect(err).NotTo(HaveOccurred())
+
+	actual, err := os.ReadFile(filepath.Join(dir, name))
+	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Could not read file %s", name))
+	Expect(string(actual)).To(Equal(string(expected)), fmt.Sprintf("%s does not match the rendered resource", name))
+}
+
 func verifyFilesMatch(file1, file2 string) {
 	file1Contents, err := os.ReadFile(file1)
 	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Could not read file %s", file1))
@@ -159,3 +177,25 @@ func addDummyChart(dir, name string) {
 	Expect(os.WriteFile(filepath.Join(path, "values.yaml"), []byte("replicaCount: 1"), 0o644)).To(Succeed())
 	Expect(os.MkdirAll(filepath.Join(path, "templates"), 0o755)).To(Succeed())
 }
+
+func runCLIWithExitCode(dir string, exitCode int, args ...string) *gexec.Session {
+	return runBinaryWithExitCode(binaryPath, dir, exitCode, args...)
+}
+
+func runBinaryWithExitCode(binary, dir string, exitCode int, args ...string) *gexec.Session {
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(session).Should(gexec.Exit(exitCode))
+	return session
+}
+
+// buildVersionedBinary builds patternizer with the given version injected via ldflags.
+func buildVersionedBinary(version string) string {
+	binary, err := gexec.Build("github.com/validatedpatterns/patternizer",
+		"-ldflags", "-X github.com/validatedpatterns/patternizer/internal/version.Version="+version+" -X github.com/validatedpatterns/patternizer/internal/version.Commit=cafe")
+	Expect(err).NotTo(HaveOccurred())
+	return binary
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/gitutil"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+// pendingCommit commits the files a command changed once it is done.
+type pendingCommit struct {
+	repo     *gitutil.Repo
+	repoRoot string
+	branch   string
+}
+
+// prepareCommit checks, before any file is changed, that the changes of the
+// command can be committed, on a new branch if branch is set.
+func prepareCommit(repoRoot, branch string) (*pendingCommit, error) {
+	repo, err := gitutil.Open(repoRoot)
+	if errors.Is(err, gitutil.ErrNotRepository) {
+		return nil, report.Errorf(report.CodeCommit, "--commit and --branch need a git repository; run git init first")
+	}
+	if err != nil {
+		return nil, report.Errorf(report.CodeCommit, "error opening git repository: %w", err)
+	}
+	for _, role := range []string{"author", "committer"} {
+		if _, err := repo.Identity(role, time.Now()); err != nil {
+			return nil, report.Errorf(report.CodeCommit, "cannot commit: %w", err)
+		}
+	}
+	if err := repo.CheckCommit(); err != nil {
+		return nil, report.Errorf(report.CodeCommit, "cannot commit: %w", err)
+	}
+	if branch != "" {
+		if err := gitutil.ValidateBranchName(branch); err != nil {
+			return nil, report.Errorf(report.CodeCommit, "invalid --branch: %w", err)
+		}
+		exists, err := repo.HasBranch(branch)
+		if err != nil {
+			return nil, report.Errorf(report.CodeCommit, "error reading branches: %w", err)
+		}
+		if exists {
+			return nil, report.Errorf(report.CodeCommit, "branch %s already exists; choose another name with --branch", branch)
+		}
+	}
+	return &pendingCommit{repo: repo, repoRoot: repoRoot, branch: branch}, nil
+}
+
+// finish creates the branch, if one was requested, and commits exactly the
+// files recorded in rep.
+func (c *pendingCommit) finish(rep *report.Report) error {
+	if len(rep.Files) == 0 {
+		rep.Infof("No files changed; nothing to commit")
+		return nil
+	}
+	m, err := metadata.Load(c.repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeMetadata, "error reading patternizer metadata: %w", err)
+	}
+
+	now := time.Now()
+	author, err := c.repo.Identity("author", now)
+	if err != nil {
+		return report.Errorf(report.CodeCommit, "cannot commit: %w", err)
+	}
+	committer, err := c.repo.Identity("committer", now)
+	if err != nil {
+		return report.Errorf(report.CodeCommit, "cannot commit: %w", err)
+	}
+	if c.branch != "" {
+		if err := c.repo.CreateBranch(c.branch, committer); err != nil {
+			return report.Errorf(report.CodeCommit, "error creating branch %s: %w", c.branch, err)
+		}
+	}
+
+	paths := make([]string, 0, len(rep.Files))
+	for _, change := range rep.Files {
+		paths = append(paths, filepath.Join(c.repoRoot, filepath.FromSlash(change.Path)))
+	}
+	id, err := c.repo.Commit(paths, commitMessage(rep, m.ResourceVersion), author, committer)
+	if errors.Is(err, gitutil.ErrNothingToCommit) {
+		rep.Infof("The changed files are already committed; nothing to commit")
+		return nil
+	}
+	if err != nil {
+		return report.Errorf(report.CodeCommit, "error committing the changes (the files were changed but not committed): %w", err)
+	}
+	rep.Commit = id
+	branch, err := c.repo.Head()
+	if err != nil {
+		branch = "HEAD"
+	}
+	rep.Infof("Committed %d changed files to %s as %s", len(paths), branch, id[:12])
+	return nil
+}
+
+// commitMessage summarizes the changes of a command run: the charts it
+// added, the resources it refreshed and the other files it changed.
+func commitMessage(rep *report.Report, resourceVersion string) string {
+	var subject string
+	switch rep.Command {
+	case "init":
+		subject = fmt.Sprintf("Initialize pattern %s with patternizer", rep.PatternName)
+	case "migrate":
+		subject = "Migrate pattern from the legacy common/ layout"
+	default:
+		subject = "Upgrade pattern resources to " + resourceVersion
+	}
+
+	var refreshed, values, other []string
+	removedCommon, skillsChanged := 0, false
+	for _, change := range rep.Files {
+		line := fmt.Sprintf("- %s (%s)", change.Path, change.Action)
+		switch {
+		case strings.HasPrefix(change.Path, "common/"):
+			removedCommon++
+		case isSkillPath(change.Path):
+			skillsChanged = true
+		case change.Path == filepath.ToSlash(metadata.RelPath):
+			// The resource version is part of the summary.
+		case slices.Contains(resources.KnownFiles, change.Path):
+			refreshed = append(refreshed, line)
+		case strings.HasPrefix(change.Path, "values-"):
+			values = append(values, line)
+		default:
+			other = append(other, line)
+		}
+	}
+
+	sections := []string{subject}
+	if rep.Command == "init" && len(rep.Charts) > 0 {
+		sections = append(sections, "Added charts:\n- "+strings.Join(rep.Charts, "\n- "))
+	}
+	if len(refreshed) > 0 {
+		sections = append(sections, fmt.Sprintf("Refreshed resources (version %s):\n%s", resourceVersion, strings.Join(refreshed, "\n")))
+	}
+	if len(values) > 0 {
+		sections = append(sections, "Values files:\n"+strings.Join(values, "\n"))
+	}
+	if len(other) > 0 {
+		sections = append(sections, "Other changes:\n"+strings.Join(other, "\n"))
+	}
+	if skillsChanged {
+		sections = append(sections, "Installed skills: "+strings.Join(rep.Skills, ", "))
+	}
+	if removedCommon > 0 {
+		sections = append(sections, fmt.Sprintf("Removed the legacy common/ directory (%d files).", removedCommon))
+	}
+	sections = append(sections, "Generated by patternizer "+version.Get().Version+".")
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+// isSkillPath reports whether path, slash-separated, lies in the skills
+// directory of a skill target.
+func isSkillPath(path string) bool {
+	for _, dir := range fileutils.SkillTargets {
+		if strings.HasPrefix(path, dir+"/skills/") {
+			return true
+		}
+	}
+	return false
+}
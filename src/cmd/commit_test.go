@@ -0,0 +1,117 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// gitOutput runs git in dir and returns its output.
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	Expect(err).NotTo(HaveOccurred(), string(out))
+	return string(out)
+}
+
+var _ = Describe("patternizer --commit", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			Skip("git is not installed")
+		}
+		for name, value := range map[string]string{
+			"GIT_CONFIG_GLOBAL":   "/dev/null",
+			"GIT_AUTHOR_NAME":     "Pat Ternizer",
+			"GIT_AUTHOR_EMAIL":    "pat@example.com",
+			"GIT_COMMITTER_NAME":  "Pat Ternizer",
+			"GIT_COMMITTER_EMAIL": "pat@example.com",
+		} {
+			GinkgoT().Setenv(name, value)
+		}
+		tempDir = createTestDir()
+		addDummyChart(tempDir, "app")
+		commitAll(tempDir)
+	})
+
+	It("should commit the initialized pattern on a new branch", func() {
+		base := gitOutput(tempDir, "rev-parse", "HEAD")
+		session := runCLI(tempDir, "init", "--branch", "patternizer/init")
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Committed \d+ changed files to patternizer/init as [0-9a-f]{12}`))
+
+		Expect(gitOutput(tempDir, "rev-parse", "--abbrev-ref", "HEAD")).To(Equal("patternizer/init\n"))
+		Expect(gitOutput(tempDir, "rev-parse", "HEAD~1")).To(Equal(base))
+		Expect(gitOutput(tempDir, "status", "--porcelain")).To(BeEmpty())
+
+		message := gitOutput(tempDir, "log", "-1", "--format=%B")
+		Expect(message).To(HavePrefix("Initialize pattern %s with patternizer\n", filepath.Base(tempDir)))
+		Expect(message).To(ContainSubstring("Added charts:\n- charts/app\n"))
+		Expect(message).To(MatchRegexp(`Refreshed resources \(version v[0-9.]+\):\n(- .*\n)*- pattern.sh \(created\)`))
+		Expect(message).To(ContainSubstring("- values-global.yaml (created)"))
+		Expect(message).To(ContainSubstring("Installed skills: pattern-author"))
+	})
+
+	It("should commit only the files the command changed", func() {
+		_ = runCLI(tempDir, "init")
+		commitAll(tempDir)
+		Expect(os.WriteFile(filepath.Join(tempDir, "charts", "app", "values.yaml"), []byte("replicaCount: 2"), 0o644)).To(Succeed())
+		Expect(os.Remove(filepath.Join(tempDir, "Makefile-common"))).To(Succeed())
+		commitAll(tempDir)
+		Expect(os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("notes\n"), 0o644)).To(Succeed())
+
+		rep := runCLIJSON(tempDir, "upgrade", "--commit")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Commit).To(HaveLen(40))
+		Expect(gitOutput(tempDir, "show", "--name-status", "--format=%s", "HEAD")).To(MatchRegexp(`^Upgrade pattern resources to v[0-9.]+\n\nA\tMakefile-common\n$`))
+		Expect(gitOutput(tempDir, "status", "--porcelain")).To(Equal("?? notes.md\n"))
+	})
+
+	It("should commit and back up the changes of one run", func() {
+		_ = runCLI(tempDir, "init", "--backup", "--commit")
+		Expect(gitOutput(tempDir, "show", "--name-only", "--format=", "HEAD")).To(ContainSubstring("values-global.yaml\n"))
+
+		session := runCLI(tempDir, "restore")
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`\sinit\s.*0 files backed up, [1-9]\d* created\n`))
+	})
+
+	It("should not commit when nothing changed", func() {
+		_ = runCLI(tempDir, "init")
+		commitAll(tempDir)
+		head := gitOutput(tempDir, "rev-parse", "HEAD")
+
+		session := runCLI(tempDir, "upgrade", "--commit")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("nothing to commit"))
+		Expect(gitOutput(tempDir, "rev-parse", "HEAD")).To(Equal(head))
+	})
+
+	It("should run the commit hooks", func() {
+		hook := filepath.Join(tempDir, ".git", "hooks", "pre-commit")
+		Expect(os.MkdirAll(filepath.Dir(hook), 0o755)).To(Succeed())
+		Expect(os.WriteFile(hook, []byte("#!/bin/sh\necho 'lint failed' >&2\nexit 1\n"), 0o755)).To(Succeed())
+		head := gitOutput(tempDir, "rev-parse", "HEAD")
+
+		session := runCLIWithExitCode(tempDir, 1, "init", "--commit")
+		Expect(string(session.Err.Contents())).To(And(ContainSubstring("the files were changed but not committed"), ContainSubstring("lint failed")))
+		Expect(gitOutput(tempDir, "rev-parse", "HEAD")).To(Equal(head))
+	})
+
+	It("should refuse an existing branch before changing any file", func() {
+		gitOutput(tempDir, "branch", "taken")
+		session := runCLIWithExitCode(tempDir, 1, "init", "--branch", "taken")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("branch taken already exists"))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+
+	It("should need a git repository", func() {
+		dir := createTestDir()
+		session := runCLIWithExitCode(dir, 1, "init", "--commit")
+		Expect(strings.ToLower(string(session.Err.Contents()))).To(ContainSubstring("need a git repository"))
+		Expect(filepath.Join(dir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+})
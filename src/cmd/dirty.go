@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/gitutil"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// checkCleanTree refuses to let a command modify files under paths, relative
+// to repoRoot, that have uncommitted changes, since those changes could not
+// be told apart from the command's or reverted with git. Repositories that
+// are not under Git are not checked.
+func checkCleanTree(repoRoot string, paths []string, allowDirty bool, rep *report.Report) error {
+	repo, err := gitutil.Open(repoRoot)
+	if errors.Is(err, gitutil.ErrNotRepository) {
+		return nil
+	}
+	if err != nil {
+		return report.Errorf(report.CodeDirtyTree, "error opening git repository: %w", err)
+	}
+
+	absPaths := make([]string, 0, len(paths))
+	for _, p := range paths {
+		absPaths = append(absPaths, filepath.Join(repoRoot, p))
+	}
+	changes, err := repo.Status(absPaths)
+	if err != nil {
+		return report.Errorf(report.CodeDirtyTree, "error reading git status (use --allow-dirty to skip the check): %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		files = append(files, change.String())
+	}
+	if !allowDirty {
+		return report.Errorf(report.CodeDirtyTree,
+			"refusing to modify files with uncommitted changes: %s; commit or stash them first (use --allow-dirty to override)",
+			strings.Join(files, ", "))
+	}
+	rep.Warnf(report.CodeDirtyTree, "modifying files with uncommitted changes: %s", strings.Join(files, ", "))
+	return nil
+}
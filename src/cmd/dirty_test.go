@@ -0,0 +1,72 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// commitAll commits every file of dir, creating the repository if needed.
+func commitAll(dir string) {
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+	}
+}
+
+var _ = Describe("patternizer on a dirty git tree", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			Skip("git is not installed")
+		}
+		tempDir = createTestDir()
+		addDummyChart(tempDir, "app")
+		_ = runCLI(tempDir, "init")
+		commitAll(tempDir)
+	})
+
+	It("should refuse to modify files with uncommitted changes", func() {
+		Expect(os.WriteFile(filepath.Join(tempDir, "ansible.cfg"), []byte("[defaults]\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-dev.yaml"), []byte("clusterGroup: {}\n"), 0o644)).To(Succeed())
+
+		for _, command := range []string{"init", "upgrade"} {
+			session := runCLIWithExitCode(tempDir, 1, command)
+			Expect(string(session.Err.Contents())).To(ContainSubstring("refusing to modify files with uncommitted changes: ansible.cfg (modified), values-dev.yaml (untracked)"))
+		}
+		data, err := os.ReadFile(filepath.Join(tempDir, "ansible.cfg"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("[defaults]\n"))
+	})
+
+	It("should modify them with --allow-dirty", func() {
+		Expect(os.Remove(filepath.Join(tempDir, "pattern.sh"))).To(Succeed())
+
+		rep := runCLIJSON(tempDir, "upgrade", "--allow-dirty")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Warnings).To(ContainElement(And(
+			HaveField("Code", "dirty-tree"),
+			HaveField("Message", ContainSubstring("pattern.sh (deleted)")),
+		)))
+		Expect(filepath.Join(tempDir, "pattern.sh")).To(BeAnExistingFile())
+	})
+
+	It("should ignore changes to files it does not manage", func() {
+		Expect(os.WriteFile(filepath.Join(tempDir, "charts", "app", "values.yaml"), []byte("replicaCount: 2"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("notes\n"), 0o644)).To(Succeed())
+
+		rep := runCLIJSON(tempDir, "upgrade")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Warnings).NotTo(ContainElement(HaveField("Code", "dirty-tree")))
+	})
+})
@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/gitutil"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+	"github.com/validatedpatterns/patternizer/internal/runner"
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+// Statuses of a doctor check.
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkError   = "error"
+)
+
+// doctorCheck is the outcome of one doctor check.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// doctorResult is the outcome of runDoctor.
+type doctorResult struct {
+	Success bool          `json:"success"`
+	Checks  []doctorCheck `json:"checks"`
+}
+
+// doctor collects the checks of a run.
+type doctor struct {
+	checks []doctorCheck
+}
+
+func (d *doctor) add(name, status, format string, args ...interface{}) {
+	d.checks = append(d.checks, doctorCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// runDoctor checks, without connecting to a cluster, that the repository is
+// ready for ./pattern.sh make install. It fails when a check reports an error.
+func runDoctor(out io.Writer, format string) error {
+	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return fmt.Errorf("error getting pattern information: %w", err)
+	}
+	// The same directories as those run mounts into the container.
+	homeDir, _ := runner.OSHost{}.HomeDir()
+	wd, _ := runner.OSHost{}.Getwd()
+
+	d := &doctor{}
+	d.checkManagedFiles(repoRoot, patternName)
+	d.checkMakefile(repoRoot)
+	d.checkLegacy(repoRoot)
+	d.checkGit(repoRoot)
+	d.checkKubeconfig(os.Getenv("KUBECONFIG"), homeDir, wd)
+	d.checkSecrets(repoRoot, patternName, homeDir)
+
+	failed := 0
+	for _, check := range d.checks {
+		if check.Status == checkError {
+			failed++
+		}
+	}
+	result := doctorResult{Success: failed == 0, Checks: d.checks}
+
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, check := range d.checks {
+			fmt.Fprintf(out, "%-9s %s: %s\n", "["+check.Status+"]", check.Name, check.Message)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(d.checks))
+	}
+	return nil
+}
+
+// checkManagedFiles checks that the refreshed resource files are present and
+// match the newest embedded resource set.
+func (d *doctor) checkManagedFiles(repoRoot, patternName string) {
+	const name = "managed-files"
+	catalog := resources.Embedded()
+
+	var missing []string
+	for _, file := range append(append([]string{}, resources.RefreshedFiles...), "Makefile") {
+		if _, err := os.Stat(filepath.Join(repoRoot, file)); os.IsNotExist(err) {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) > 0 {
+		d.add(name, checkError, "%s missing; run patternizer upgrade", strings.Join(missing, ", "))
+		return
+	}
+
+	current, err := currentResourceVersion(repoRoot, catalog)
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	if current == "" {
+		d.add(name, checkWarning, "the resource version of the managed files is unknown; run patternizer upgrade")
+		return
+	}
+
+	set, err := d.expectedResources(repoRoot, patternName, catalog, current)
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	var modified []string
+	for _, file := range resources.RefreshedFiles {
+		want, err := fs.ReadFile(set, path.Join(resources.Dir, file))
+		if err != nil {
+			continue
+		}
+		got, err := os.ReadFile(filepath.Join(repoRoot, file))
+		if err != nil {
+			d.add(name, checkError, "error reading %s: %v", file, err)
+			return
+		}
+		if string(got) != string(want) {
+			modified = append(modified, file)
+		}
+	}
+
+	cmp, _ := version.Compare(current, catalog.Current())
+	switch {
+	case len(modified) > 0:
+		d.add(name, checkWarning, "%s differ from resource version %s; upgrade will overwrite the changes", strings.Join(modified, ", "), current)
+	case cmp < 0:
+		d.add(name, checkWarning, "the managed files use resource version %s; run patternizer upgrade to update them to %s", current, catalog.Current())
+	default:
+		d.add(name, checkOK, "the managed files match resource version %s", current)
+	}
+}
+
+// expectedResources renders the resource set of resourceVersion the way init and
+// upgrade would write it in repoRoot.
+func (d *doctor) expectedResources(repoRoot, patternName string, catalog *resources.Catalog, resourceVersion string) (fs.FS, error) {
+	base, _, err := catalog.Set(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	// Warnings about the overrides are reported by init and upgrade.
+	set, cleanup, err := applyResourceOverrides(repoRoot, base, "", cfg, report.New("doctor"))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	data, err := templateData(repoRoot, patternName, "", cfg)
+	if err != nil {
+		return nil, err
+	}
+	return renderResources(set, data)
+}
+
+// checkMakefile checks that the Makefile includes Makefile-common, which
+// provides the install target.
+func (d *doctor) checkMakefile(repoRoot string) {
+	const name = "makefile"
+	makefilePath := filepath.Join(repoRoot, "Makefile")
+	if _, err := os.Stat(makefilePath); os.IsNotExist(err) {
+		d.add(name, checkError, "Makefile is missing; run patternizer upgrade")
+		return
+	}
+	hasInclude, err := fileutils.FileContainsIncludeMakefileCommon(makefilePath)
+	switch {
+	case err != nil:
+		d.add(name, checkError, "error checking Makefile for include: %v", err)
+	case !hasInclude:
+		d.add(name, checkError, "Makefile does not include Makefile-common; run patternizer upgrade")
+	default:
+		collisions, err := makefileCollisions(repoRoot)
+		switch {
+		case err != nil:
+			d.add(name, checkError, "%v", err)
+		case len(collisions) > 0:
+			d.add(name, checkWarning, "%s", strings.Join(collisions, "; "))
+		default:
+			d.add(name, checkOK, "Makefile includes Makefile-common")
+		}
+	}
+}
+
+// checkLegacy checks that the common/ directory and pattern.sh symlink of
+// repositories that predate patternizer are gone.
+func (d *doctor) checkLegacy(repoRoot string) {
+	const name = "legacy-files"
+	var found []string
+	if info, err := os.Lstat(filepath.Join(repoRoot, "common")); err == nil && info.IsDir() {
+		found = append(found, "the common/ directory")
+	}
+	if info, err := os.Lstat(filepath.Join(repoRoot, "pattern.sh")); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		found = append(found, "the pattern.sh symlink")
+	}
+	if len(found) > 0 {
+		d.add(name, checkError, "found %s; run patternizer migrate", strings.Join(found, " and "))
+		return
+	}
+	d.add(name, checkOK, "no legacy common/ directory or pattern.sh symlink")
+}
+
+// checkGit checks that the cluster can fetch the current branch: the origin
+// remote make install defaults to exists, and the branch was pushed.
+func (d *doctor) checkGit(repoRoot string) {
+	const name = "git"
+	repo, err := gitutil.Open(repoRoot)
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	config, err := repo.Config()
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	if _, err := config.RemoteURL("origin"); err != nil {
+		d.add(name, checkError, "no origin remote; add one with 'git remote add origin <url>'")
+		return
+	}
+	branch, err := repo.Head()
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	remote, remoteBranch, err := config.Upstream(branch)
+	if err != nil {
+		d.add(name, checkError, "%v", err)
+		return
+	}
+	d.add(name, checkOK, "branch %s tracks %s/%s", branch, remote, remoteBranch)
+}
+
+// checkKubeconfig checks that every file listed in KUBECONFIG lies under the
+// home directory, which pattern.sh mounts into the utility container.
+// Relative entries are resolved against wd.
+func (d *doctor) checkKubeconfig(kubeconfig, homeDir, wd string) {
+	const name = "kubeconfig"
+	if kubeconfig == "" {
+		d.add(name, checkOK, "KUBECONFIG is not set; ~/.kube/config is used")
+		return
+	}
+	if outside := runner.KubeconfigsOutsideHome(kubeconfig, homeDir, wd); len(outside) > 0 {
+		d.add(name, checkError, "KUBECONFIG %s is outside of the home directory, which is the only one pattern.sh mounts into the container", strings.Join(outside, ", "))
+		return
+	}
+	d.add(name, checkOK, "KUBECONFIG %s is under the home directory", kubeconfig)
+}
+
+// checkSecrets checks that secrets support matches global.secretLoader.disabled.
+func (d *doctor) checkSecrets(repoRoot, patternName, homeDir string) {
+	const name = "secrets"
+	globalValues, err := pattern.LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		d.add(name, checkError, "error reading global values: %v", err)
+		return
+	}
+	clusterGroupName := globalValues.Main.ClusterGroupName
+	clusterGroupValues, err := pattern.LoadClusterGroupValues(clusterGroupName, repoRoot)
+	if err != nil {
+		d.add(name, checkError, "error reading values of cluster group %s: %v", clusterGroupName, err)
+		return
+	}
+	hasVault := false
+	if clusterGroupValues != nil {
+		for _, app := range clusterGroupValues.ClusterGroup.Applications {
+			hasVault = hasVault || app.Chart == pattern.VaultChart
+		}
+	}
+
+	if globalValues.Global.SecretLoader.Disabled {
+		if hasVault {
+			d.add(name, checkWarning, "global.secretLoader.disabled is true but values-%s.yaml deploys %s; run patternizer init --with-secrets to enable secrets", clusterGroupName, pattern.VaultChart)
+			return
+		}
+		d.add(name, checkOK, "secrets are disabled")
+		return
+	}
+
+	var problems []string
+	if !hasVault {
+		problems = append(problems, fmt.Sprintf("values-%s.yaml does not deploy %s", clusterGroupName, pattern.VaultChart))
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "values-secret.yaml.template")); os.IsNotExist(err) {
+		problems = append(problems, "values-secret.yaml.template is missing")
+	}
+	if len(problems) > 0 {
+		d.add(name, checkError, "global.secretLoader.disabled is false but %s; run patternizer init --with-secrets", strings.Join(problems, " and "))
+		return
+	}
+	for _, file := range pattern.SecretsFiles(homeDir, globalValues.Global.Pattern) {
+		if _, err := os.Stat(file); err == nil {
+			d.add(name, checkOK, "secrets are enabled and loaded from %s", file)
+			return
+		}
+	}
+	d.add(name, checkWarning, "secrets are enabled but ~/values-secret-%s.yaml does not exist; the values of values-secret.yaml.template will be loaded", globalValues.Global.Pattern)
+}
@@ -0,0 +1,113 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+const trackedGitConfig = "[remote \"origin\"]\n\turl = https://github.com/org/demo.git\n[branch \"dev\"]\n\tremote = origin\n\tmerge = refs/heads/dev\n"
+
+var _ = Describe("patternizer doctor", func() {
+	var tempDir, homeDir string
+
+	type check struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+
+	runDoctor := func(exitCode int, kubeconfig string) map[string]check {
+		cmd := exec.Command(binaryPath, "doctor", "-o", "json")
+		cmd.Dir = tempDir
+		cmd.Env = append(os.Environ(), "HOME="+homeDir, "KUBECONFIG="+kubeconfig)
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(exitCode))
+
+		var result struct {
+			Success bool    `json:"success"`
+			Checks  []check `json:"checks"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &result)).To(Succeed())
+		Expect(result.Success).To(Equal(exitCode == 0))
+		checks := map[string]check{}
+		for _, c := range result.Checks {
+			checks[c.Name] = c
+		}
+		return checks
+	}
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		homeDir = createTestDir()
+		_ = runCLI(tempDir, "init", "--with-secrets")
+		writeGitRepo(tempDir, trackedGitConfig)
+	})
+
+	It("should pass for a freshly initialized pattern", func() {
+		checks := runDoctor(0, filepath.Join(homeDir, ".kube", "config"))
+		for _, name := range []string{"managed-files", "makefile", "legacy-files", "git", "kubeconfig"} {
+			Expect(checks).To(HaveKeyWithValue(name, HaveField("Status", "ok")))
+		}
+		Expect(checks["secrets"].Status).To(Equal("warning"))
+		Expect(checks["secrets"].Message).To(ContainSubstring("values-secret-%s.yaml does not exist", filepath.Base(tempDir)))
+
+		Expect(os.WriteFile(filepath.Join(homeDir, "values-secret-"+filepath.Base(tempDir)+".yaml"), []byte("version: \"2.0\"\n"), 0o600)).To(Succeed())
+		checks = runDoctor(0, "")
+		Expect(checks["secrets"].Status).To(Equal("ok"))
+	})
+
+	It("should report problems that break make install", func() {
+		Expect(os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte("all:\n"), 0o644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "common"), 0o755)).To(Succeed())
+		writeGitRepo(tempDir, "[remote \"origin\"]\n\turl = https://github.com/org/demo.git\n")
+		Expect(os.Remove(filepath.Join(tempDir, "values-secret.yaml.template"))).To(Succeed())
+
+		checks := runDoctor(1, "/etc/kubeconfig")
+		Expect(checks["makefile"].Status).To(Equal("error"))
+		Expect(checks["legacy-files"].Message).To(ContainSubstring("the common/ directory"))
+		Expect(checks["git"].Message).To(ContainSubstring("branch dev has no upstream configured"))
+		Expect(checks["kubeconfig"].Message).To(ContainSubstring("outside of the home directory"))
+		Expect(checks["secrets"].Message).To(ContainSubstring("values-secret.yaml.template is missing"))
+	})
+
+	It("should check every file of a KUBECONFIG list", func() {
+		inHome := filepath.Join(homeDir, ".kube", "config")
+		checks := runDoctor(0, inHome+string(filepath.ListSeparator)+filepath.Join(homeDir, "other"))
+		Expect(checks["kubeconfig"].Status).To(Equal("ok"))
+
+		// A sibling directory that shares the home directory's prefix is outside of it.
+		sibling := homeDir + "2/config"
+		checks = runDoctor(1, inHome+string(filepath.ListSeparator)+sibling)
+		Expect(checks["kubeconfig"].Status).To(Equal("error"))
+		Expect(checks["kubeconfig"].Message).To(ContainSubstring("KUBECONFIG " + sibling + " is outside"))
+		Expect(checks["kubeconfig"].Message).NotTo(ContainSubstring(inHome))
+	})
+
+	It("should resolve relative KUBECONFIG entries against the current directory", func() {
+		// tempDir does not lie under homeDir.
+		checks := runDoctor(1, "kubeconfig")
+		Expect(checks["kubeconfig"].Status).To(Equal("error"))
+		Expect(checks["kubeconfig"].Message).To(ContainSubstring("KUBECONFIG kubeconfig is outside"))
+	})
+
+	It("should warn about locally modified managed files", func() {
+		Expect(os.WriteFile(filepath.Join(tempDir, "ansible.cfg"), []byte("[defaults]\n"), 0o644)).To(Succeed())
+		checks := runDoctor(0, "")
+		Expect(checks["managed-files"].Status).To(Equal("warning"))
+		Expect(checks["managed-files"].Message).To(ContainSubstring("ansible.cfg differ"))
+	})
+
+	It("should warn when vault is deployed with secrets disabled", func() {
+		_ = runCLI(tempDir, "init", "--allow-dirty")
+		checks := runDoctor(0, "")
+		Expect(checks["secrets"].Status).To(Equal("warning"))
+		Expect(checks["secrets"].Message).To(ContainSubstring("global.secretLoader.disabled is true"))
+	})
+})
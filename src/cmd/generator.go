@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+// checkGeneratorVersion refuses to overwrite managed files that were generated
+// by a newer patternizer than the running one, unless allowDowngrade is set.
+func checkGeneratorVersion(repoRoot string, allowDowngrade bool, rep *report.Report) error {
+	m, err := metadata.Load(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeMetadata, "error reading patternizer metadata: %w", err)
+	}
+
+	running := version.Get().Version
+	if m.GeneratorVersion == "" || m.GeneratorVersion == running {
+		return nil
+	}
+
+	cmp, ok := version.Compare(running, m.GeneratorVersion)
+	if !ok {
+		rep.Warnf(report.CodeVersionUnknown, "cannot compare patternizer version %s with version %s recorded in %s", running, m.GeneratorVersion, metadata.RelPath)
+		return nil
+	}
+	if cmp >= 0 {
+		return nil
+	}
+
+	if !allowDowngrade {
+		return report.Errorf(report.CodeDowngrade,
+			"this repository was generated by patternizer %s, which is newer than the running version %s; refusing to downgrade managed files (use --allow-downgrade to override)",
+			m.GeneratorVersion, running)
+	}
+	rep.Warnf(report.CodeDowngrade, "downgrading managed files from patternizer %s to %s", m.GeneratorVersion, running)
+	return nil
+}
+
+// currentResourceVersion returns the resource version of the files in repoRoot,
+// from the metadata if recorded there or else by comparing the files against
+// every known resource set. It returns an empty string if it cannot be determined.
+func currentResourceVersion(repoRoot string, catalog *resources.Catalog) (string, error) {
+	m, err := metadata.Load(repoRoot)
+	if err != nil {
+		return "", report.Errorf(report.CodeMetadata, "error reading patternizer metadata: %w", err)
+	}
+	if m.ResourceVersion != "" {
+		return m.ResourceVersion, nil
+	}
+
+	detected, ok, err := catalog.Detect(repoRoot)
+	if err != nil {
+		return "", report.Errorf(report.CodeResourceVersion, "error detecting resource version: %w", err)
+	}
+	if !ok {
+		return "", nil
+	}
+	return detected, nil
+}
+
+// checkResourceVersion refuses to replace the managed files of repoRoot with an
+// older resource set than the one they currently match, unless allowDowngrade is set.
+func checkResourceVersion(repoRoot string, catalog *resources.Catalog, target string, allowDowngrade bool, rep *report.Report) error {
+	current, err := currentResourceVersion(repoRoot, catalog)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return nil
+	}
+	if cmp, ok := version.Compare(target, current); !ok || cmp >= 0 {
+		return nil
+	}
+
+	if !allowDowngrade {
+		return report.Errorf(report.CodeDowngrade,
+			"the managed files use resource version %s, which is newer than the requested %s; refusing to downgrade them (use --allow-downgrade to override)",
+			current, target)
+	}
+	rep.Warnf(report.CodeDowngrade, "downgrading managed files from resource version %s to %s", current, target)
+	return nil
+}
+
+// stampMetadata records the running patternizer version and the resource
+// version that was written in the repository metadata.
+func stampMetadata(repoRoot, resourceVersion string) error {
+	m, err := metadata.Load(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeMetadata, "error reading patternizer metadata: %w", err)
+	}
+
+	m.GeneratorVersion = version.Get().Version
+	m.ResourceVersion = resourceVersion
+	if err := metadata.Save(repoRoot, m); err != nil {
+		return report.Errorf(report.CodeMetadata, "error writing patternizer metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+func readGeneratorVersion(dir string) string {
+	m, err := metadata.Load(dir)
+	Expect(err).NotTo(HaveOccurred())
+	return m.GeneratorVersion
+}
+
+var _ = Describe("generator version stamping", Ordered, func() {
+	var oldBinary, newBinary string
+
+	BeforeAll(func() {
+		oldBinary = buildVersionedBinary("v2.1.0")
+		newBinary = buildVersionedBinary("v2.2.0")
+	})
+
+	It("should record the generator version on init and upgrade", func() {
+		tempDir := createTestDir()
+		_ = runBinaryWithExitCode(oldBinary, tempDir, 0, "init")
+		Expect(readGeneratorVersion(tempDir)).To(Equal("v2.1.0"))
+
+		_ = runBinaryWithExitCode(newBinary, tempDir, 0, "upgrade")
+		Expect(readGeneratorVersion(tempDir)).To(Equal("v2.2.0"))
+	})
+
+	It("should refuse to downgrade managed files", func() {
+		tempDir := createTestDir()
+		_ = runBinaryWithExitCode(newBinary, tempDir, 0, "init")
+		Expect(os.WriteFile(filepath.Join(tempDir, "Makefile-common"), []byte("# newer\n"), 0o644)).To(Succeed())
+
+		for _, command := range []string{"init", "upgrade"} {
+			session := runBinaryWithExitCode(oldBinary, tempDir, 1, command)
+			Expect(string(session.Err.Contents())).To(ContainSubstring("--allow-downgrade"))
+		}
+
+		contents, err := os.ReadFile(filepath.Join(tempDir, "Makefile-common"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("# newer\n"))
+		Expect(readGeneratorVersion(tempDir)).To(Equal("v2.2.0"))
+	})
+
+	It("should downgrade with --allow-downgrade and warn about it", func() {
+		tempDir := createTestDir()
+		_ = runBinaryWithExitCode(newBinary, tempDir, 0, "init")
+
+		session := runBinaryWithExitCode(oldBinary, tempDir, 0, "upgrade", "--allow-downgrade", "--output", "json")
+		Expect(string(session.Out.Contents())).To(ContainSubstring(report.CodeDowngrade))
+		Expect(readGeneratorVersion(tempDir)).To(Equal("v2.1.0"))
+	})
+})
@@ -1,70 +1,188 @@
 package cmd
 
 import (
-	"fmt"
+	"cmp"
 	"os"
 	"path/filepath"
 
-	"github.com/validatedpatterns/patternizer/internal/embedded"
 	"github.com/validatedpatterns/patternizer/internal/fileutils"
 	"github.com/validatedpatterns/patternizer/internal/helm"
 	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/prompt"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
 )
 
+// initOptions holds the flags of the init command.
+type initOptions struct {
+	withSecrets    bool
+	allowDowngrade bool
+	resourcesDir   string
+	runtime        string
+	skills         string
+	// force overwrites the local changes to installed skills.
+	force bool
+	changeOptions
+	// settings holds the names chosen with flags.
+	settings pattern.Settings
+	// prompter is set for interactive runs.
+	prompter prompt.Prompter
+}
+
 // runInit handles the initialization logic for the init command.
-func runInit(withSecrets bool) error {
+func runInit(opts initOptions, rep *report.Report) (err error) {
 	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
 	if err != nil {
-		return fmt.Errorf("error getting pattern information: %w", err)
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	if err := validateSettings(opts.settings); err != nil {
+		return err
+	}
+
+	if err := checkGeneratorVersion(repoRoot, opts.allowDowngrade, rep); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+	skillTargets, err := resolveSkillTargets(opts.skills, cfg)
+	if err != nil {
+		return err
+	}
+	skillBundles, closeSkillSources, err := loadSkillBundles(repoRoot, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSkillSources()
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	paths = append(paths, skillSourcePaths(skillBundles)...)
+	finish, err := beginChanges(repoRoot, paths, &opts.changeOptions, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	catalog := resources.Embedded()
+	baseSet, resourceVersion, err := catalog.Set(catalog.Current())
+	if err != nil {
+		return report.Errorf(report.CodeResourceVersion, "error selecting resource version: %w", err)
+	}
+	overriddenSet, cleanup, err := applyResourceOverrides(repoRoot, baseSet, opts.resourcesDir, cfg, rep)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
 
 	chartPaths, err := helm.FindTopLevelCharts(repoRoot)
 	if err != nil {
-		return fmt.Errorf("error finding Helm charts: %w", err)
+		return report.Errorf(report.CodeChartDiscovery, "error finding Helm charts: %w", err)
 	}
 
-	actualPatternName, clusterGroupName, err := pattern.ProcessGlobalValues(patternName, repoRoot, withSecrets)
+	withSecrets := opts.withSecrets
+	settings := opts.settings
+	if opts.prompter != nil {
+		answers, err := askInitAnswers(opts.prompter, patternName, repoRoot, chartPaths, withSecrets, settings)
+		if err != nil {
+			return err
+		}
+		settings = answers.settings
+		chartPaths = answers.chartPaths
+		withSecrets = answers.withSecrets
+	}
+	rep.Charts = append(rep.Charts, chartPaths...)
+
+	data, err := templateData(repoRoot, patternName, opts.runtime, cfg)
 	if err != nil {
-		return fmt.Errorf("error processing global values: %w", err)
+		return err
+	}
+	data.PatternName = cmp.Or(settings.PatternName, data.PatternName)
+	data.ClusterGroup = cmp.Or(settings.ClusterGroupName, data.ClusterGroup)
+	data.WithSecrets = withSecrets
+	resourceSet, err := renderResources(overriddenSet, data)
+	if err != nil {
+		return err
 	}
 
-	if err := pattern.ProcessClusterGroupValues(actualPatternName, clusterGroupName, repoRoot, chartPaths, withSecrets); err != nil {
-		return fmt.Errorf("error processing cluster group values: %w", err)
+	if settings.ClusterGroupName != "" {
+		current, err := pattern.LoadGlobalValues(patternName, repoRoot)
+		if err != nil {
+			return report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+		}
+		if err := renameMainClusterGroup(repoRoot, current.Main.ClusterGroupName, settings.ClusterGroupName, rep); err != nil {
+			return err
+		}
 	}
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/pattern.sh", filepath.Join(repoRoot, "pattern.sh"), 0o755); err != nil {
-		return fmt.Errorf("error copying pattern.sh: %w", err)
+	actualPatternName, clusterGroupName, err := pattern.ProcessGlobalValues(patternName, repoRoot, withSecrets, settings)
+	if err != nil {
+		return report.Errorf(report.CodeGlobalValues, "error processing global values: %w", err)
 	}
+	rep.PatternName = actualPatternName
+	rep.ClusterGroup = clusterGroupName
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/ansible.cfg", filepath.Join(repoRoot, "ansible.cfg"), 0o644); err != nil {
-		return fmt.Errorf("error copying ansible.cfg: %w", err)
+	if err := pattern.ProcessClusterGroupValues(actualPatternName, clusterGroupName, repoRoot, chartPaths, withSecrets, settings); err != nil {
+		return report.Errorf(report.CodeClusterGroup, "error processing cluster group values: %w", err)
 	}
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/Makefile-common", filepath.Join(repoRoot, "Makefile-common"), 0o644); err != nil {
-		return fmt.Errorf("error copying Makefile-common: %w", err)
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/pattern.sh", filepath.Join(repoRoot, "pattern.sh"), 0o755); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying pattern.sh: %w", err)
+	}
+
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/ansible.cfg", filepath.Join(repoRoot, "ansible.cfg"), 0o644); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying ansible.cfg: %w", err)
+	}
+
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/Makefile-common", filepath.Join(repoRoot, "Makefile-common"), 0o644); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying Makefile-common: %w", err)
 	}
 
 	makefileDst := filepath.Join(repoRoot, "Makefile")
 	if _, err := os.Stat(makefileDst); os.IsNotExist(err) {
-		if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/Makefile", makefileDst, 0o644); err != nil {
-			return fmt.Errorf("error copying Makefile: %w", err)
+		if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/Makefile", makefileDst, 0o644); err != nil {
+			return report.Errorf(report.CodeMakefile, "error copying Makefile: %w", err)
 		}
 	}
 
 	if withSecrets {
-		if err := fileutils.HandleSecretsSetup(embedded.Resources, repoRoot); err != nil {
-			return fmt.Errorf("error setting up secrets: %w", err)
+		if err := fileutils.HandleSecretsSetup(resourceSet, repoRoot); err != nil {
+			return report.Errorf(report.CodeSecrets, "error setting up secrets: %w", err)
 		}
 	}
 
-	if err := fileutils.InstallSkills(repoRoot); err != nil {
-		return fmt.Errorf("error installing skills: %w", err)
+	if err := installSkills(repoRoot, skillTargets, skillBundles, opts.force, rep); err != nil {
+		return err
+	}
+
+	if err := stampMetadata(repoRoot, resourceVersion); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully initialized pattern '%s' in %s\n", actualPatternName, repoRoot)
+	rep.Infof("Successfully initialized pattern '%s' in %s", actualPatternName, repoRoot)
 	if withSecrets {
-		fmt.Println("Secrets configuration has been enabled.")
+		rep.Infof("Secrets configuration has been enabled.")
 	}
 
 	return nil
 }
+
+// validateSettings checks the names chosen with flags before any file is changed.
+func validateSettings(settings pattern.Settings) error {
+	if settings.PatternName != "" {
+		if err := pattern.ValidateName(settings.PatternName); err != nil {
+			return report.Errorf(report.CodeGlobalValues, "invalid pattern name: %w", err)
+		}
+	}
+	if settings.ClusterGroupName != "" {
+		if err := pattern.ValidateName(settings.ClusterGroupName); err != nil {
+			return report.Errorf(report.CodeGlobalValues, "invalid clustergroup name: %w", err)
+		}
+	}
+	return nil
+}
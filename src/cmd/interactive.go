@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"cmp"
+	"path/filepath"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/prompt"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+// initAnswers are the choices made in the interactive init wizard.
+type initAnswers struct {
+	settings    pattern.Settings
+	chartPaths  []string
+	withSecrets bool
+}
+
+// askInitAnswers runs the interactive init wizard. The names chosen with
+// flags in defaults, the current values files of repoRoot, or the defaults are
+// offered as the default answers.
+func askInitAnswers(p prompt.Prompter, patternName, repoRoot string, chartPaths []string, withSecrets bool, defaults pattern.Settings) (*initAnswers, error) {
+	globalValues, err := pattern.LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		return nil, report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+	}
+	clusterGroupValues, err := pattern.LoadClusterGroupValues(globalValues.Main.ClusterGroupName, repoRoot)
+	if err != nil {
+		return nil, report.Errorf(report.CodeClusterGroup, "error reading cluster group values: %w", err)
+	}
+
+	answers := &initAnswers{settings: defaults}
+	answers.settings.ChartNamespaces = map[string]string{}
+
+	p.Info("This wizard creates the values files of your pattern. Press Enter to accept the default shown in brackets.")
+	p.Info("")
+	p.Info("The pattern name identifies the pattern in values-global.yaml and is the default namespace of its applications.")
+	answers.settings.PatternName, err = p.Input("Pattern name", cmp.Or(defaults.PatternName, globalValues.Global.Pattern), pattern.ValidateName)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Info("")
+	p.Info("A clustergroup is a set of clusters that share the same configuration: namespaces,")
+	p.Info("operator subscriptions and Argo CD applications. The main clustergroup describes the")
+	p.Info("hub cluster that the pattern is installed on first. Its configuration is stored in")
+	p.Info("values-<clustergroup>.yaml; common names are 'hub' and 'prod'.")
+	answers.settings.ClusterGroupName, err = p.Input("Main clustergroup name", cmp.Or(defaults.ClusterGroupName, globalValues.Main.ClusterGroupName), pattern.ValidateName)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Info("")
+	p.Info("Secrets support deploys Vault and the External Secrets Operator and loads secrets from")
+	p.Info("~/values-secret-<pattern>.yaml, based on the generated values-secret.yaml.template.")
+	answers.withSecrets, err = p.Confirm("Enable secrets", withSecrets || !globalValues.Global.SecretLoader.Disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chartPaths) == 0 {
+		p.Info("")
+		p.Info("No Helm charts were found. Add charts to the repository and run init again to deploy them.")
+		return answers, nil
+	}
+
+	p.Info("")
+	p.Info("Each included Helm chart is deployed by Argo CD as an application of the main clustergroup.")
+	for _, chartPath := range chartPaths {
+		include, err := p.Confirm("Include chart "+chartPath, true)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+		answers.chartPaths = append(answers.chartPaths, chartPath)
+
+		namespace, err := p.Input("  Namespace for "+chartPath, chartNamespace(clusterGroupValues, chartPath, answers.settings.PatternName), pattern.ValidateName)
+		if err != nil {
+			return nil, err
+		}
+		answers.settings.ChartNamespaces[chartPath] = namespace
+	}
+
+	return answers, nil
+}
+
+// chartNamespace returns the namespace the application of chartPath is
+// currently deployed to, or defaultNamespace if there is none.
+func chartNamespace(values *types.ValuesClusterGroup, chartPath, defaultNamespace string) string {
+	if values == nil {
+		return defaultNamespace
+	}
+	app, ok := values.ClusterGroup.Applications[filepath.Base(chartPath)]
+	if !ok || app.Namespace == "" {
+		return defaultNamespace
+	}
+	return app.Namespace
+}
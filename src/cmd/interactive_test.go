@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+func runCLIWithInput(dir, input string, exitCode int, args ...string) *gexec.Session {
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(session).Should(gexec.Exit(exitCode))
+	return session
+}
+
+var _ = Describe("patternizer init --interactive", func() {
+	It("should use the answers instead of the defaults", func() {
+		tempDir := createTestDir()
+		addDummyChart(tempDir, "frontend")
+		addDummyChart(tempDir, "scratch")
+
+		answers := strings.Join([]string{
+			"my-pattern", // pattern name
+			"Hub",        // invalid clustergroup name
+			"hub",        // clustergroup name
+			"y",          // secrets
+			"",           // include charts/frontend
+			"web",        // namespace for charts/frontend
+			"n",          // include charts/scratch
+		}, "\n") + "\n"
+		session := runCLIWithInput(tempDir, answers, 0, "init", "--interactive")
+
+		prompts := string(session.Err.Contents())
+		Expect(prompts).To(ContainSubstring("A clustergroup is a set of clusters"))
+		Expect(prompts).To(ContainSubstring("Pattern name [" + filepath.Base(tempDir) + "]: "))
+		Expect(prompts).To(ContainSubstring("Main clustergroup name [prod]: Invalid answer"))
+		Expect(prompts).To(ContainSubstring("Namespace for charts/frontend [my-pattern]: "))
+
+		expectedGlobal := types.NewDefaultValuesGlobal()
+		expectedGlobal.Global.Pattern = "my-pattern"
+		expectedGlobal.Main.ClusterGroupName = "hub"
+		expectedGlobal.Global.SecretLoader.Disabled = false
+		verifyGlobalValues(filepath.Join(tempDir, "values-global.yaml"), expectedGlobal)
+
+		expectedClusterGroup := types.NewDefaultValuesClusterGroup("my-pattern", "hub", []string{"charts/frontend"}, true)
+		app := expectedClusterGroup.ClusterGroup.Applications["frontend"]
+		app.Namespace = "web"
+		expectedClusterGroup.ClusterGroup.Applications["frontend"] = app
+		expectedClusterGroup.ClusterGroup.Namespaces["web"] = nil
+		// Round-trip the expected values so that they compare equal to decoded YAML.
+		data, err := yaml.Marshal(expectedClusterGroup)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded types.ValuesClusterGroup
+		Expect(yaml.Unmarshal(data, &decoded)).To(Succeed())
+		verifyClusterGroupValues(filepath.Join(tempDir, "values-hub.yaml"), &decoded)
+		Expect(filepath.Join(tempDir, "values-prod.yaml")).NotTo(BeAnExistingFile())
+
+		verifySecretTemplateCopied(tempDir)
+		verifyScaffoldFilesCopied(tempDir)
+	})
+
+	It("should offer the existing values as defaults", func() {
+		tempDir := createTestDir()
+		addDummyChart(tempDir, "frontend")
+		_ = runCLIWithInput(tempDir, "shop\nhub\ny\ny\nweb\n", 0, "init", "-i")
+
+		session := runCLIWithInput(tempDir, "\n\n\n\n\n", 0, "init", "--interactive")
+		prompts := string(session.Err.Contents())
+		Expect(prompts).To(ContainSubstring("Pattern name [shop]: "))
+		Expect(prompts).To(ContainSubstring("Main clustergroup name [hub]: "))
+		Expect(prompts).To(ContainSubstring("Enable secrets [Y/n]: "))
+		Expect(prompts).To(ContainSubstring("Namespace for charts/frontend [web]: "))
+	})
+
+	It("should fail without changing files when the answers run out", func() {
+		tempDir := createTestDir()
+		session := runCLIWithInput(tempDir, "my-pattern\n", 1, "init", "--interactive")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("no answer"))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+})
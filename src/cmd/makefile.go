@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/validatedpatterns/patternizer/internal/makefile"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// makefileCommon is the file the Makefile of a pattern includes for the
+// common targets.
+const makefileCommon = "Makefile-common"
+
+// addMakefileInclude inserts 'include Makefile-common' into the Makefile of
+// repoRoot if it lacks one, after the variables set at its top and before
+// its first rule, and warns when that changes the goal make runs by default.
+func addMakefileInclude(repoRoot string, rep *report.Report) error {
+	makefilePath := filepath.Join(repoRoot, "Makefile")
+	info, err := os.Stat(makefilePath)
+	if err != nil {
+		return report.Errorf(report.CodeMakefile, "error accessing Makefile: %w", err)
+	}
+	data, err := os.ReadFile(makefilePath)
+	if err != nil {
+		return report.Errorf(report.CodeMakefile, "error reading Makefile: %w", err)
+	}
+	local := makefile.Parse(data)
+	if local.Includes(makefileCommon) {
+		return nil
+	}
+
+	updated, line := makefile.InsertInclude(data, makefileCommon)
+	if err := os.WriteFile(makefilePath, updated, info.Mode()); err != nil {
+		return report.Errorf(report.CodeMakefile, "error updating Makefile: %w", err)
+	}
+	rep.Warnf(report.CodeMakefileInclude, "added 'include %s' to the existing Makefile at line %d", makefileCommon, line)
+
+	common, err := readMakefile(filepath.Join(repoRoot, makefileCommon))
+	if err != nil {
+		return err
+	}
+	goal := local.DefaultGoal()
+	if goal != "" && !local.Assigns(".DEFAULT_GOAL") && common.DefaultGoal() != goal {
+		rep.Warnf(report.CodeMakefileInclude, "make without a target now runs '%s' from %s instead of '%s'; set .DEFAULT_GOAL := %s in the Makefile to keep it",
+			common.DefaultGoal(), makefileCommon, goal, goal)
+	}
+	return nil
+}
+
+// makefileCollisions describes the targets that the Makefile of repoRoot
+// gives a recipe and Makefile-common also defines. Only one recipe of a
+// target is used, so local targets such as install or show silently replace
+// the common ones, are replaced by them, or run after their prerequisites.
+func makefileCollisions(repoRoot string) ([]string, error) {
+	local, err := readMakefile(filepath.Join(repoRoot, "Makefile"))
+	if err != nil {
+		return nil, err
+	}
+	common, err := readMakefile(filepath.Join(repoRoot, makefileCommon))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, collision := range local.Collisions(makefileCommon, common) {
+		switch {
+		case !collision.IncludedRecipe:
+			messages = append(messages, fmt.Sprintf("the Makefile target '%s' (line %d) also runs the prerequisites %s gives it",
+				collision.Target, collision.Line, makefileCommon))
+		case collision.Overrides:
+			messages = append(messages, fmt.Sprintf("the Makefile target '%s' (line %d) overrides the one of %s",
+				collision.Target, collision.Line, makefileCommon))
+		default:
+			messages = append(messages, fmt.Sprintf("the Makefile target '%s' (line %d) comes before 'include %s', whose recipe replaces it",
+				collision.Target, collision.Line, makefileCommon))
+		}
+	}
+	return messages, nil
+}
+
+func readMakefile(path string) (*makefile.File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, report.Errorf(report.CodeMakefile, "error reading %s: %w", filepath.Base(path), err)
+	}
+	return makefile.Parse(data), nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// migrateOptions holds the flags of the migrate command.
+type migrateOptions struct {
+	dryRun  bool
+	force   bool
+	upgrade upgradeOptions
+}
+
+// runMigrate converts a repository from the legacy common/ layout: it rewrites
+// the references to common/ and then upgrades the repository, which removes
+// common/. It stops before changing anything when references remain that it
+// cannot convert, unless forced.
+func runMigrate(opts migrateOptions, rep *report.Report) error {
+	_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	plan, err := pattern.PlanMigration(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeMigration, "error planning migration: %w", err)
+	}
+	if !plan.Legacy {
+		rep.Infof("No legacy common/ layout found in %s; nothing to migrate", repoRoot)
+		return nil
+	}
+	for _, finding := range plan.Converted {
+		rep.Infof("%s", finding)
+	}
+	for _, finding := range plan.Unconverted {
+		rep.Warnf(report.CodeMigration, "cannot convert %s", finding)
+	}
+
+	if opts.dryRun {
+		rep.Infof("Dry run: %d references would be converted and %d need manual changes; no files were changed", len(plan.Converted), len(plan.Unconverted))
+		return nil
+	}
+	if len(plan.Unconverted) > 0 && !opts.force {
+		// Failed runs only print the error in text mode, so it lists the references.
+		var refs []string
+		for _, finding := range plan.Unconverted {
+			refs = append(refs, finding.String())
+		}
+		return report.Errorf(report.CodeMigration, "cannot convert %d references to common/ (%s); update them and run migrate again, or pass --force to remove common/ anyway",
+			len(refs), strings.Join(refs, "; "))
+	}
+
+	upgrade := opts.upgrade
+	upgrade.edits = plan.Edits
+	upgrade.force = opts.force
+	if err := runUpgrade(upgrade, rep); err != nil {
+		return err
+	}
+	rep.Infof("Migrated %s from the legacy common/ layout", repoRoot)
+	return nil
+}
@@ -0,0 +1,105 @@
+package cmd_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// writeLegacyPattern writes a pattern that still uses the common/ subtree.
+func writeLegacyPattern(dir, makefile string) {
+	files := map[string]string{
+		"values-global.yaml": "global:\n  pattern: legacy\nmain:\n  clusterGroupName: hub\n",
+		"values-hub.yaml":    "clusterGroup:\n  name: hub\n  isHubCluster: true\n  applications:\n    vault:\n      name: vault\n      namespace: vault\n      path: common/hashicorp-vault\n",
+		"Makefile":           makefile,
+		"common/Makefile":    "operator-deploy:\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+	Expect(os.Symlink("common/scripts/pattern-util.sh", filepath.Join(dir, "pattern.sh"))).To(Succeed())
+}
+
+var _ = Describe("patternizer migrate", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+	})
+
+	It("should convert the legacy references and remove common/", func() {
+		writeLegacyPattern(tempDir, "include common/Makefile\n\ninstall: operator-deploy\n\t./common/scripts/pattern-util.sh make load-secrets\n")
+
+		rep := runCLIJSON(tempDir, "migrate")
+		Expect(rep.Success).To(BeTrue())
+		change, ok := findFileChange(rep, "common/Makefile")
+		Expect(ok).To(BeTrue())
+		Expect(change.Action).To(Equal(report.ActionDeleted))
+		change, ok = findFileChange(rep, "values-hub.yaml")
+		Expect(ok).To(BeTrue())
+		Expect(change.Action).To(Equal(report.ActionModified))
+
+		_, err := os.Stat(filepath.Join(tempDir, "common"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		verifyPattenShCopied(tempDir)
+		verifyMakefileCommonCopied(tempDir)
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("include Makefile-common\n\ninstall: operator-deploy\n\t./pattern.sh make load-secrets\n"))
+		data, err = os.ReadFile(filepath.Join(tempDir, "values-hub.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("chart: hashicorp-vault\n"))
+		Expect(string(data)).NotTo(ContainSubstring("isHubCluster"))
+		data, err = os.ReadFile(filepath.Join(tempDir, "values-global.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("multiSourceConfig:\n    enabled: true\n"))
+	})
+
+	It("should change nothing while references cannot be converted", func() {
+		makefile := "install:\n\tcommon/scripts/custom.sh\n"
+		writeLegacyPattern(tempDir, makefile)
+
+		session := runCLIWithExitCode(tempDir, 1, "migrate")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("cannot convert 1 references to common/ (Makefile:2: references common/scripts/custom.sh)"))
+		Expect(filepath.Join(tempDir, "common", "Makefile")).To(BeAnExistingFile())
+		data, err := os.ReadFile(filepath.Join(tempDir, "values-hub.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("path: common/hashicorp-vault"))
+
+		session = runCLI(tempDir, "migrate", "--dry-run")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("no files were changed"))
+		Expect(filepath.Join(tempDir, "common", "Makefile")).To(BeAnExistingFile())
+
+		_ = runCLI(tempDir, "migrate", "--force")
+		_, err = os.Stat(filepath.Join(tempDir, "common"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+	})
+
+	It("should report that a migrated repository has nothing to migrate", func() {
+		_ = runCLI(tempDir, "init")
+		session := runCLI(tempDir, "migrate")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("nothing to migrate"))
+	})
+
+	It("should leave the global values of a repository without the legacy layout alone", func() {
+		values := "global:\n  pattern: modern\nmain:\n  clusterGroupName: hub\n"
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-global.yaml"), []byte(values), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-hub.yaml"), []byte("clusterGroup:\n  name: hub\n"), 0o644)).To(Succeed())
+
+		rep := runCLIJSON(tempDir, "migrate")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Files).To(BeEmpty())
+		Expect(rep.Messages).To(ConsistOf(ContainSubstring("nothing to migrate")))
+		data, err := os.ReadFile(filepath.Join(tempDir, "values-global.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(values))
+	})
+})
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/validatedpatterns/patternizer/internal/backup"
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// managedPaths lists the files and directories, relative to the repository root,
+// that patternizer commands may create, modify or delete.
+func managedPaths(repoRoot string) ([]string, error) {
+	paths := []string{
+		"common",
+		"pattern.sh",
+		"ansible.cfg",
+		"Makefile",
+		"Makefile-common",
+		"values-secret.yaml.template",
+		pattern.OverridesDir,
+		pattern.PreservedCommonDir,
+		metadata.RelPath,
+	}
+
+	values, err := valuesFiles(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, values...)
+	paths = append(paths, fileutils.SkillPaths()...)
+
+	// Installed skills that are no longer embedded, or that come from a
+	// skill source, are updated or removed by the next upgrade.
+	embedded := fileutils.EmbeddedSkillBundle()
+	m, err := metadata.Load(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	for name, skill := range m.Skills {
+		if embedded.Has(name) {
+			continue
+		}
+		for _, target := range skill.Targets {
+			if _, ok := fileutils.SkillTargets[target]; ok {
+				paths = append(paths, fileutils.SkillDir(target, name))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// valuesFiles lists the values files in repoRoot, relative to it.
+func valuesFiles(repoRoot string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(repoRoot, "values-*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing values files: %w", err)
+	}
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		files = append(files, filepath.Base(match))
+	}
+	return files, nil
+}
+
+// snapshot hashes the files under paths, relative to repoRoot, and the values
+// files present now, so that values files a command creates or renames are
+// recorded as well.
+func snapshot(repoRoot string, paths []string) (map[string]string, error) {
+	values, err := valuesFiles(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return report.Snapshot(repoRoot, append(slices.Clone(paths), values...))
+}
+
+// finishReport records the outcome of a command run and writes the report
+// in the format selected by the global --output flag.
+func finishReport(cmd *cobra.Command, rep *report.Report, runErr error) error {
+	format, _ := cmd.Flags().GetString("output")
+
+	if runErr != nil {
+		rep.Fail(runErr)
+		// In text mode cobra prints the error itself; the warnings and
+		// messages gathered before the failure go to stderr with it.
+		if format != report.FormatJSON {
+			if err := rep.Write(cmd.ErrOrStderr(), format); err != nil {
+				return fmt.Errorf("error writing report: %w", err)
+			}
+			return runErr
+		}
+	} else {
+		rep.Success = true
+	}
+
+	if err := rep.Write(cmd.OutOrStdout(), format); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+	return runErr
+}
+
+// changeOptions holds the flags that guard the changes of init, upgrade and
+// migrate.
+type changeOptions struct {
+	backup     bool
+	allowDirty bool
+	commit     bool
+	// branch is created for the commit; it implies commit.
+	branch string
+}
+
+// beginChanges prepares a command for changing the files under paths,
+// relative to repoRoot: it refuses uncommitted changes to them unless
+// allowed, snapshots them, backs them up and checks that the changes can be
+// committed, as opts asks. Commands without these flags pass nil opts, and
+// their changes are only recorded.
+//
+// The command defers finish with its named error result once beginChanges
+// succeeds. finish records the changes in rep, then commits them, then
+// completes the backup with the files the command created, and sets the
+// error to the first failure unless the command already failed.
+func beginChanges(repoRoot string, paths []string, opts *changeOptions, rep *report.Report) (finish func(*error), err error) {
+	if opts != nil {
+		if err := checkCleanTree(repoRoot, paths, opts.allowDirty, rep); err != nil {
+			return nil, err
+		}
+	}
+	before, err := snapshot(repoRoot, paths)
+	if err != nil {
+		return nil, report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	var b *backup.Backup
+	var pending *pendingCommit
+	if opts != nil && opts.backup {
+		if b, err = startBackup(repoRoot, rep.Command, paths, rep); err != nil {
+			return nil, err
+		}
+	}
+	if opts != nil && (opts.commit || opts.branch != "") {
+		if pending, err = prepareCommit(repoRoot, opts.branch); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(errp *error) {
+		if recordErr := recordChanges(rep, repoRoot, paths, before); recordErr != nil && *errp == nil {
+			*errp = recordErr
+		}
+		if pending != nil && *errp == nil {
+			*errp = pending.finish(rep)
+		}
+		if b != nil {
+			if backupErr := finishBackup(b, rep); backupErr != nil && *errp == nil {
+				*errp = backupErr
+			}
+		}
+	}, nil
+}
+
+// recordChanges snapshots paths again and records in the report how the
+// files changed since before, which snapshot took of the same paths.
+func recordChanges(rep *report.Report, repoRoot string, paths []string, before map[string]string) error {
+	after, err := snapshot(repoRoot, paths)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error recording file changes: %w", err)
+	}
+	rep.RecordChanges(before, after)
+	return nil
+}
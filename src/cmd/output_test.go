@@ -0,0 +1,135 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+func runCLIJSON(dir string, args ...string) report.Report {
+	session := runCLI(dir, append(args, "--output", "json")...)
+
+	var rep report.Report
+	Expect(json.Unmarshal(session.Out.Contents(), &rep)).To(Succeed(), "stdout should contain only the JSON report")
+	return rep
+}
+
+func findFileChange(rep report.Report, path string) (report.FileChange, bool) {
+	for _, change := range rep.Files {
+		if change.Path == path {
+			return change, true
+		}
+	}
+	return report.FileChange{}, false
+}
+
+var _ = Describe("patternizer --output json", func() {
+	Context("when running init on a directory containing helm charts", Ordered, func() {
+		var tempDir string
+		var rep report.Report
+
+		BeforeAll(func() {
+			tempDir = createTestDir()
+			addDummyChart(tempDir, "test-app1")
+			rep = runCLIJSON(tempDir, "init", "--with-secrets")
+		})
+
+		It("should report the pattern, clustergroup and charts", func() {
+			Expect(rep.Command).To(Equal("init"))
+			Expect(rep.Success).To(BeTrue())
+			Expect(rep.PatternName).To(Equal(filepath.Base(tempDir)))
+			Expect(rep.ClusterGroup).To(Equal("prod"))
+			Expect(rep.Charts).To(ConsistOf("charts/test-app1"))
+			Expect(rep.Skills).To(ConsistOf("pattern-author"))
+			Expect(rep.Errors).To(BeEmpty())
+		})
+
+		It("should report created files with their hashes", func() {
+			for _, path := range []string{"values-global.yaml", "values-prod.yaml", "pattern.sh", "Makefile", "Makefile-common", "ansible.cfg", "values-secret.yaml.template", ".claude/skills/pattern-author/SKILL.md"} {
+				change, ok := findFileChange(rep, path)
+				Expect(ok).To(BeTrue(), "expected %s in the report", path)
+				Expect(change.Action).To(Equal(report.ActionCreated))
+
+				hash, err := report.HashFile(filepath.Join(tempDir, path))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(change.NewHash).To(Equal(hash))
+			}
+		})
+
+		It("should report nothing changed on a second run", func() {
+			second := runCLIJSON(tempDir, "init", "--with-secrets")
+			Expect(second.Files).To(BeEmpty())
+		})
+	})
+
+	Context("when running upgrade on a repo with a legacy common directory", Ordered, func() {
+		var tempDir string
+		var rep report.Report
+
+		BeforeAll(func() {
+			tempDir = createTestDir()
+			Expect(os.MkdirAll(filepath.Join(tempDir, "common", "scripts"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tempDir, "common", "scripts", "test.sh"), []byte("#!/bin/sh\n"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte("all:\n\t@echo hi\n"), 0o644)).To(Succeed())
+			rep = runCLIJSON(tempDir, "upgrade")
+		})
+
+		It("should report deleted and modified files", func() {
+			deleted, ok := findFileChange(rep, "common/scripts/test.sh")
+			Expect(ok).To(BeTrue())
+			Expect(deleted.Action).To(Equal(report.ActionDeleted))
+			Expect(deleted.OldHash).To(Equal(report.HashBytes([]byte("#!/bin/sh\n"))))
+
+			modified, ok := findFileChange(rep, "Makefile")
+			Expect(ok).To(BeTrue())
+			Expect(modified.Action).To(Equal(report.ActionModified))
+		})
+
+		It("should report warnings with codes", func() {
+			codes := []string{}
+			for _, warning := range rep.Warnings {
+				codes = append(codes, warning.Code)
+			}
+			Expect(codes).To(ContainElements(report.CodeLegacyCommon, report.CodeMakefileInclude))
+		})
+	})
+
+	Context("when the command fails", func() {
+		It("should still print a JSON report with a coded error", func() {
+			tempDir := createTestDir()
+			Expect(os.WriteFile(filepath.Join(tempDir, "values-global.yaml"), []byte("global: [not, a, map"), 0o644)).To(Succeed())
+
+			session := runCLIWithExitCode(tempDir, 1, "init", "--output", "json")
+
+			var rep report.Report
+			Expect(json.Unmarshal(session.Out.Contents(), &rep)).To(Succeed())
+			Expect(rep.Success).To(BeFalse())
+			Expect(rep.Errors).To(HaveLen(1))
+			Expect(rep.Errors[0].Code).To(Equal(report.CodeGlobalValues))
+		})
+	})
+})
+
+var _ = Describe("patternizer text output", func() {
+	Context("when the command fails", func() {
+		It("should print the warnings gathered so far to stderr with the error", func() {
+			tempDir := createTestDir()
+			Expect(os.Symlink("common/scripts/pattern-util.sh", filepath.Join(tempDir, "pattern.sh"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(tempDir, "Makefile-common"), 0o755)).To(Succeed())
+
+			session := runCLIWithExitCode(tempDir, 1, "upgrade")
+
+			Expect(session.Out.Contents()).To(BeEmpty())
+			stderr := string(session.Err.Contents())
+			Expect(stderr).To(ContainSubstring("Warning: replacing pattern.sh symlink with a regular file\n"))
+			Expect(stderr).To(ContainSubstring("Error: error copying Makefile-common"))
+			Expect(strings.Index(stderr, "Warning:")).To(BeNumerically("<", strings.Index(stderr, "Error:")))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// overrideOptions holds the flags of the add override command.
+type overrideOptions struct {
+	platform       string
+	clusterVersion string
+	clusterGroup   string
+	// globals holds the --global KEY=VALUE flags.
+	globals []string
+	shared  bool
+}
+
+// runAddOverride handles the add override command.
+func runAddOverride(opts overrideOptions, rep *report.Report) (err error) {
+	dirName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	override := pattern.Override{
+		Platform:       opts.platform,
+		ClusterVersion: opts.clusterVersion,
+		ClusterGroup:   opts.clusterGroup,
+		Shared:         opts.shared,
+	}
+	for _, global := range opts.globals {
+		key, value, ok := strings.Cut(global, "=")
+		if !ok {
+			return report.Errorf(report.CodeOverride, "invalid --global %q: expected KEY=VALUE", global)
+		}
+		override.Globals = append(override.Globals, pattern.GlobalValue{Key: key, Value: value})
+	}
+
+	values, err := pattern.LoadGlobalValues(dirName, repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+	}
+	rep.PatternName = values.Global.Pattern
+	rep.ClusterGroup = values.Main.ClusterGroupName
+
+	plan, err := pattern.PlanOverride(repoRoot, values.Main.ClusterGroupName, override)
+	if err != nil {
+		return report.Errorf(report.CodeOverride, "error adding override: %w", err)
+	}
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if err := fileutils.ApplyEdits(plan.Edits); err != nil {
+		return report.Errorf(report.CodeOverride, "error adding override: %w", err)
+	}
+
+	if plan.Exists {
+		rep.Infof("%s already exists", plan.Path)
+	} else {
+		rep.Infof("Created %s", plan.Path)
+	}
+	if plan.RegisteredIn != "" {
+		rep.Infof("Registered '%s' in clusterGroup.sharedValueFiles of %s", plan.ValueFile, plan.RegisteredIn)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("patternizer add override", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		_ = runCLI(tempDir, "init")
+	})
+
+	It("should create a platform values file in the repository root", func() {
+		session := runCLI(tempDir, "add", "override", "--platform", "aws")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Created values-AWS.yaml"))
+		Expect(filepath.Join(tempDir, "values-AWS.yaml")).To(BeAnExistingFile())
+	})
+
+	It("should create and register a shared values file", func() {
+		session := runCLI(tempDir, "add", "override", "--shared", "--platform", "AWS", "--cluster-version", "4.16")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Created overrides/values-AWS-4.16.yaml"))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("in clusterGroup.sharedValueFiles of values-prod.yaml"))
+		Expect(filepath.Join(tempDir, "overrides", "values-AWS-4.16.yaml")).To(BeAnExistingFile())
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "values-prod.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		var values struct {
+			ClusterGroup struct {
+				SharedValueFiles []string `yaml:"sharedValueFiles"`
+			} `yaml:"clusterGroup"`
+		}
+		Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+		Expect(values.ClusterGroup.SharedValueFiles).To(ConsistOf("/overrides/values-{{ $.Values.global.clusterPlatform }}-{{ $.Values.global.clusterVersion }}.yaml"))
+
+		// Running init again keeps the registration.
+		_ = runCLI(tempDir, "init")
+		data, err = os.ReadFile(filepath.Join(tempDir, "values-prod.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("sharedValueFiles"))
+	})
+
+	It("should reject files the framework would never load", func() {
+		session := runCLIWithExitCode(tempDir, 1, "add", "override", "--cluster-version", "4.16")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("values-4.16.yaml would never be loaded"))
+		Expect(filepath.Join(tempDir, "values-4.16.yaml")).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/validatedpatterns/patternizer/internal/gitutil"
+	"github.com/validatedpatterns/patternizer/internal/manifests"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// runPatternCR prints the Pattern CR of the repository. The Git source not
+// given in git is taken from the upstream of the current branch.
+func runPatternCR(out io.Writer, format string, git manifests.GitSource) error {
+	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return fmt.Errorf("error getting pattern information: %w", err)
+	}
+	globalValues, err := pattern.LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		return fmt.Errorf("error reading global values: %w", err)
+	}
+
+	if git, err = completeGitSource(repoRoot, git); err != nil {
+		return err
+	}
+
+	cr, err := manifests.PatternCR(globalValues, git)
+	if err != nil {
+		return fmt.Errorf("error generating the Pattern CR: %w", err)
+	}
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(cr)
+	}
+	data, err := manifests.Encode([]manifests.Manifest{cr})
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// completeGitSource fills in the repository and revision not set in git from
+// the upstream of the current branch.
+func completeGitSource(repoRoot string, git manifests.GitSource) (manifests.GitSource, error) {
+	if git.TargetRepo != "" && git.TargetRevision != "" {
+		return git, nil
+	}
+	upstream, err := upstreamSource(repoRoot)
+	if err != nil {
+		return git, err
+	}
+	if git.TargetRepo == "" {
+		git.TargetRepo = upstream.TargetRepo
+	}
+	if git.TargetRevision == "" {
+		git.TargetRevision = upstream.TargetRevision
+	}
+	return git, nil
+}
+
+// upstreamSource returns the HTTPS URL and branch of the upstream of the
+// current branch, which is what the cluster will fetch. Unlike make install,
+// it fails when the branch was never pushed, since the cluster could not
+// fetch it.
+func upstreamSource(repoRoot string) (manifests.GitSource, error) {
+	repo, err := gitutil.Open(repoRoot)
+	if err != nil {
+		return manifests.GitSource{}, fmt.Errorf("error opening the git repository: %w", err)
+	}
+	branch, err := repo.Head()
+	if err != nil {
+		return manifests.GitSource{}, fmt.Errorf("error reading the current branch: %w", err)
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return manifests.GitSource{}, fmt.Errorf("error reading the git configuration: %w", err)
+	}
+	remote, remoteBranch, err := config.Upstream(branch)
+	if err != nil {
+		return manifests.GitSource{}, err
+	}
+	url, err := config.RemoteURL(remote)
+	if err != nil {
+		return manifests.GitSource{}, err
+	}
+	return manifests.GitSource{TargetRepo: gitutil.HTTPSURL(url), TargetRevision: remoteBranch}, nil
+}
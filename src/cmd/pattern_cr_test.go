@@ -0,0 +1,56 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeGitRepo creates a minimal .git directory in dir whose HEAD points at
+// the dev branch.
+func writeGitRepo(dir, config string) {
+	gitDir := filepath.Join(dir, ".git")
+	Expect(os.MkdirAll(gitDir, 0o755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/dev\n"), 0o644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(gitDir, "config"), []byte(config), 0o644)).To(Succeed())
+}
+
+var _ = Describe("patternizer pattern-cr", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		_ = runCLI(tempDir, "init")
+	})
+
+	It("should use the upstream of the current branch", func() {
+		writeGitRepo(tempDir, "[remote \"origin\"]\n\turl = git@github.com:org/demo.git\n[branch \"dev\"]\n\tremote = origin\n\tmerge = refs/heads/dev\n")
+		session := runCLI(tempDir, "pattern-cr")
+		output := string(session.Out.Contents())
+		Expect(output).To(HavePrefix("apiVersion: gitops.hybrid-cloud-patterns.io/v1alpha1\nkind: Pattern\n"))
+		Expect(output).To(ContainSubstring("name: " + filepath.Base(tempDir) + "\n"))
+		Expect(output).To(ContainSubstring("clusterGroupName: prod\n"))
+		Expect(output).To(ContainSubstring("targetRepo: https://github.com/org/demo.git\n"))
+		Expect(output).To(ContainSubstring("targetRevision: dev\n"))
+	})
+
+	It("should fail when the branch has no upstream", func() {
+		writeGitRepo(tempDir, "[remote \"origin\"]\n\turl = git@github.com:org/demo.git\n")
+		session := runCLIWithExitCode(tempDir, 1, "pattern-cr")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("branch dev has no upstream configured"))
+	})
+
+	It("should not need git when the source is given", func() {
+		session := runCLI(tempDir, "pattern-cr", "--target-repo", "https://example.com/demo.git", "--target-revision", "v1", "-o", "json")
+		var cr struct {
+			Spec struct {
+				GitSpec map[string]string `json:"gitSpec"`
+			} `json:"spec"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &cr)).To(Succeed())
+		Expect(cr.Spec.GitSpec).To(Equal(map[string]string{"targetRepo": "https://example.com/demo.git", "targetRevision": "v1"}))
+	})
+})
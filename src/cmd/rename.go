@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// runRenamePattern handles the rename pattern command.
+func runRenamePattern(newName string, rep *report.Report) error {
+	dirName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	values, err := pattern.LoadGlobalValues(dirName, repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+	}
+	oldName := values.Global.Pattern
+
+	// The secrets files live in the home directory; without one only the
+	// repository is renamed.
+	homeDir, _ := os.UserHomeDir()
+	plan, err := pattern.PlanPatternRename(repoRoot, homeDir, oldName, newName)
+	if err != nil {
+		return report.Errorf(report.CodeRename, "error renaming pattern: %w", err)
+	}
+	if err := applyRenamePlan(repoRoot, plan, rep); err != nil {
+		return err
+	}
+
+	rep.PatternName = newName
+	rep.ClusterGroup = values.Main.ClusterGroupName
+	rep.Infof("Renamed pattern '%s' to '%s'", oldName, newName)
+	return nil
+}
+
+// runRenameClusterGroup handles the rename clustergroup command.
+func runRenameClusterGroup(oldName, newName string, rep *report.Report) error {
+	dirName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	plan, err := pattern.PlanClusterGroupRename(repoRoot, oldName, newName)
+	if err != nil {
+		return report.Errorf(report.CodeRename, "error renaming clustergroup: %w", err)
+	}
+	if err := applyRenamePlan(repoRoot, plan, rep); err != nil {
+		return err
+	}
+
+	values, err := pattern.LoadGlobalValues(dirName, repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+	}
+	rep.PatternName = values.Global.Pattern
+	rep.ClusterGroup = values.Main.ClusterGroupName
+	rep.Infof("Renamed clustergroup '%s' to '%s'", oldName, newName)
+	return nil
+}
+
+// renameMainClusterGroup renames the main cluster group oldName to newName,
+// like rename clustergroup, when init selects another one. Nothing is done if
+// the names are equal or no values file refers to oldName yet.
+func renameMainClusterGroup(repoRoot, oldName, newName string, rep *report.Report) error {
+	if oldName == newName {
+		return nil
+	}
+	plan, err := pattern.PlanClusterGroupRename(repoRoot, oldName, newName)
+	if errors.Is(err, pattern.ErrClusterGroupNotFound) {
+		return nil
+	}
+	if err != nil {
+		return report.Errorf(report.CodeClusterGroup, "error renaming cluster group: %w", err)
+	}
+	if err := fileutils.ApplyEdits(plan.Edits); err != nil {
+		return report.Errorf(report.CodeClusterGroup, "error renaming cluster group: %w", err)
+	}
+	reportMoves(repoRoot, plan, rep)
+	return nil
+}
+
+// applyRenamePlan applies all changes of a rename at once and records them in
+// the report.
+func applyRenamePlan(repoRoot string, plan *pattern.RenamePlan, rep *report.Report) (err error) {
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if err := fileutils.ApplyEdits(plan.Edits); err != nil {
+		return report.Errorf(report.CodeRename, "error applying rename: %w", err)
+	}
+	reportMoves(repoRoot, plan, rep)
+	return nil
+}
+
+// reportMoves records the files moved by plan in the report.
+func reportMoves(repoRoot string, plan *pattern.RenamePlan, rep *report.Report) {
+	for _, move := range plan.Moves {
+		rep.Infof("Renamed %s to %s", displayPath(repoRoot, move.From), displayPath(repoRoot, move.To))
+	}
+}
+
+// displayPath returns path relative to repoRoot if it lies within it.
+func displayPath(repoRoot, path string) string {
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
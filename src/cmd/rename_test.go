@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+)
+
+// runCLIWithHome runs patternizer with HOME set to home.
+func runCLIWithHome(dir, home string, exitCode int, args ...string) *gexec.Session {
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+home)
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(session).Should(gexec.Exit(exitCode))
+	return session
+}
+
+var _ = Describe("patternizer rename", func() {
+	var tempDir, homeDir, patternName string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		homeDir = createTestDir()
+		patternName = filepath.Base(tempDir)
+		addDummyChart(tempDir, "app")
+		_ = runCLI(tempDir, "init", "--with-secrets")
+	})
+
+	It("should rename the pattern in the values files and the secrets file", func() {
+		secretsFile := filepath.Join(homeDir, "values-secret-"+patternName+".yaml")
+		Expect(os.WriteFile(secretsFile, []byte("version: \"2.0\"\n"), 0o600)).To(Succeed())
+
+		session := runCLIWithHome(tempDir, homeDir, 0, "rename", "pattern", "shop")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Renamed pattern '%s' to 'shop'", patternName))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Renamed %s to %s", secretsFile, filepath.Join(homeDir, "values-secret-shop.yaml")))
+
+		globalValues, err := pattern.LoadGlobalValues(patternName, tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(globalValues.Global.Pattern).To(Equal("shop"))
+
+		values, err := pattern.LoadClusterGroupValues("prod", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.ClusterGroup.Namespaces).To(HaveKey("shop"))
+		Expect(values.ClusterGroup.Namespaces).NotTo(HaveKey(patternName))
+		Expect(values.ClusterGroup.Applications["app"].Namespace).To(Equal("shop"))
+
+		Expect(secretsFile).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(homeDir, "values-secret-shop.yaml")).To(BeAnExistingFile())
+	})
+
+	It("should rename the clustergroup and its values file", func() {
+		session := runCLIWithHome(tempDir, homeDir, 0, "rename", "clustergroup", "prod", "hub")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Renamed values-prod.yaml to values-hub.yaml"))
+
+		globalValues, err := pattern.LoadGlobalValues(patternName, tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(globalValues.Main.ClusterGroupName).To(Equal("hub"))
+
+		Expect(filepath.Join(tempDir, "values-prod.yaml")).NotTo(BeAnExistingFile())
+		values, err := pattern.LoadClusterGroupValues("hub", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.ClusterGroup.Name).To(Equal("hub"))
+		Expect(values.ClusterGroup.Applications).To(HaveKey("app"))
+	})
+
+	It("should leave the repository untouched when the rename is rejected", func() {
+		globalBefore, err := os.ReadFile(filepath.Join(tempDir, "values-global.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-hub.yaml"), []byte("clusterGroup:\n  name: hub\n"), 0o644)).To(Succeed())
+
+		session := runCLIWithHome(tempDir, homeDir, 1, "rename", "clustergroup", "prod", "hub")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("already exists"))
+
+		Expect(os.ReadFile(filepath.Join(tempDir, "values-global.yaml"))).To(Equal(globalBefore))
+		Expect(filepath.Join(tempDir, "values-prod.yaml")).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/manifests"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/values"
+)
+
+// renderOptions holds the flags of the render command.
+type renderOptions struct {
+	cluster        values.Cluster
+	repoURL        string
+	targetRevision string
+	outputDir      string
+}
+
+// runRender prints the Argo CD and OLM objects the clustergroup chart creates
+// for a cluster group, or writes them to one file each in opts.outputDir.
+func runRender(out io.Writer, format string, opts renderOptions) error {
+	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return fmt.Errorf("error getting pattern information: %w", err)
+	}
+	globalValues, err := pattern.LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		return fmt.Errorf("error reading global values: %w", err)
+	}
+	clusterGroupName := opts.cluster.ClusterGroup
+	if clusterGroupName == "" {
+		clusterGroupName = globalValues.Main.ClusterGroupName
+	}
+	clusterGroupValues, err := pattern.LoadClusterGroupValues(clusterGroupName, repoRoot)
+	if err != nil {
+		return fmt.Errorf("error reading values of cluster group %s: %w", clusterGroupName, err)
+	}
+	if clusterGroupValues == nil {
+		return fmt.Errorf("values-%s.yaml not found", clusterGroupName)
+	}
+	if clusterGroupValues.ClusterGroup.Name == "" {
+		clusterGroupValues.ClusterGroup.Name = clusterGroupName
+	}
+
+	git, err := completeGitSource(repoRoot, manifests.GitSource{TargetRepo: opts.repoURL, TargetRevision: opts.targetRevision})
+	if err != nil {
+		return fmt.Errorf("%w; use --repo-url and --target-revision to set the pattern repository", err)
+	}
+
+	rendered, err := manifests.Render(globalValues, clusterGroupValues, manifests.Options{
+		RepoURL:        git.TargetRepo,
+		TargetRevision: git.TargetRevision,
+		Cluster:        opts.cluster,
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering cluster group %s: %w", clusterGroupName, err)
+	}
+
+	if opts.outputDir != "" {
+		return writeManifestFiles(out, opts.outputDir, rendered)
+	}
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rendered)
+	}
+	data, err := manifests.Encode(rendered)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// writeManifestFiles writes every manifest to <kind>-<name>.yaml in dir, so
+// that two renders can be compared with diff -r.
+func writeManifestFiles(out io.Writer, dir string, rendered []manifests.Manifest) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	for _, manifest := range rendered {
+		data, err := manifests.Encode([]manifests.Manifest{manifest})
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s-%s.yaml", strings.ToLower(manifest.Kind()), manifest.Name())
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+	}
+	fmt.Fprintf(out, "Wrote %d manifests to %s\n", len(rendered), dir)
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("patternizer render", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		addDummyChart(tempDir, "app")
+		_ = runCLI(tempDir, "init")
+	})
+
+	It("should print the manifests as YAML", func() {
+		session := runCLI(tempDir, "render", "--platform", "AWS", "--repo-url", "https://github.com/example/demo.git", "--target-revision", "main")
+		output := string(session.Out.Contents())
+		Expect(output).To(ContainSubstring("kind: Namespace\n"))
+		Expect(output).To(ContainSubstring("kind: Application\n"))
+		Expect(output).To(ContainSubstring("$patternref/values-AWS-prod.yaml"))
+		Expect(output).To(ContainSubstring("repoURL: https://github.com/example/demo.git"))
+	})
+
+	It("should print JSON", func() {
+		session := runCLI(tempDir, "render", "-o", "json", "--repo-url", "https://github.com/example/demo.git", "--target-revision", "main")
+		var manifests []map[string]interface{}
+		Expect(json.Unmarshal(session.Out.Contents(), &manifests)).To(Succeed())
+		Expect(manifests).To(ContainElement(HaveKeyWithValue("kind", "Application")))
+	})
+
+	It("should write one file per manifest", func() {
+		outDir := filepath.Join(tempDir, "rendered")
+		session := runCLI(tempDir, "render", "--output-dir", outDir, "--repo-url", "https://github.com/example/demo.git", "--target-revision", "main")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Wrote"))
+		Expect(filepath.Join(outDir, "application-app.yaml")).To(BeAnExistingFile())
+		data, err := os.ReadFile(filepath.Join(outDir, "namespace-"+filepath.Base(tempDir)+".yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("kind: Namespace"))
+	})
+
+	It("should point at the upstream of the current branch by default", func() {
+		writeGitRepo(tempDir, "[remote \"origin\"]\n\turl = git@github.com:org/demo.git\n[branch \"dev\"]\n\tremote = origin\n\tmerge = refs/heads/dev\n")
+		session := runCLI(tempDir, "render")
+		output := string(session.Out.Contents())
+		Expect(output).To(ContainSubstring("repoURL: https://github.com/org/demo.git\n"))
+		Expect(output).To(ContainSubstring("targetRevision: dev\n"))
+		Expect(output).NotTo(ContainSubstring("example"))
+	})
+
+	It("should fail when the branch has no upstream and no repository is given", func() {
+		writeGitRepo(tempDir, "[remote \"origin\"]\n\turl = git@github.com:org/demo.git\n")
+		session := runCLIWithExitCode(tempDir, 1, "render")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("use --repo-url and --target-revision"))
+	})
+
+	It("should fail for an unknown clustergroup", func() {
+		session := runCLIWithExitCode(tempDir, 1, "render", "--clustergroup", "missing")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("values-missing.yaml not found"))
+	})
+})
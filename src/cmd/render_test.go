@@ -0,0 +1,73 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/config"
+)
+
+var _ = Describe("resource templates", func() {
+	It("should render the repository configuration into the generated files", func() {
+		tempDir := createTestDir()
+		Expect(os.MkdirAll(filepath.Dir(config.Path(tempDir)), 0o755)).To(Succeed())
+		Expect(os.WriteFile(config.Path(tempDir), []byte("utilityContainer: mirror.example.com/utility-container\ndisconnectedHome: mirror.example.com/vp\n"), 0o644)).To(Succeed())
+
+		_ = runCLI(tempDir, "init")
+
+		script, err := os.ReadFile(filepath.Join(tempDir, "pattern.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(script)).To(ContainSubstring(`PATTERN_UTILITY_CONTAINER="mirror.example.com/utility-container"`))
+		Expect(string(script)).To(ContainSubstring(`PATTERN_DISCONNECTED_HOME="mirror.example.com/vp"`))
+
+		makefile, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(makefile)).To(HavePrefix("# Generated by patternizer for the " + filepath.Base(tempDir) + " pattern\n"))
+	})
+
+	It("should fail before changing files when an override is not a valid template", func() {
+		tempDir := createTestDir()
+		overrides := createTestDir()
+		createResourceOverrides(overrides)
+		Expect(os.WriteFile(filepath.Join(overrides, "pattern.sh"), []byte("podman inspect --format '{{.Id}}'\n"), 0o644)).To(Succeed())
+
+		session := runCLIWithExitCode(tempDir, 1, "init", "--resources-dir", overrides)
+		Expect(string(session.Err.Contents())).To(ContainSubstring("error rendering resources"))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+
+	Context("when selecting the container runtime", func() {
+		readScript := func(dir string) string {
+			script, err := os.ReadFile(filepath.Join(dir, "pattern.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			return string(script)
+		}
+
+		It("should generate a docker script with --container-runtime", func() {
+			tempDir := createTestDir()
+			_ = runCLI(tempDir, "init", "--container-runtime", "docker")
+			Expect(readScript(tempDir)).To(ContainSubstring("\nCONTAINER_RUNTIME=docker\n"))
+		})
+
+		It("should use the containerRuntime setting on upgrade", func() {
+			tempDir := createTestDir()
+			_ = runCLI(tempDir, "init")
+			verifyPattenShCopied(tempDir)
+
+			Expect(os.WriteFile(config.Path(tempDir), []byte("containerRuntime: auto\n"), 0o644)).To(Succeed())
+			_ = runCLI(tempDir, "upgrade")
+			Expect(readScript(tempDir)).To(ContainSubstring(`CONTAINER_RUNTIME="${PATTERN_CONTAINER_RUNTIME:-}"`))
+
+			_ = runCLI(tempDir, "upgrade", "--container-runtime", "podman")
+			verifyPattenShCopied(tempDir)
+		})
+
+		It("should reject unknown runtimes", func() {
+			session := runCLIWithExitCode(createTestDir(), 1, "init", "--container-runtime", "lxc")
+			Expect(string(session.Err.Contents())).To(ContainSubstring("unsupported container runtime"))
+		})
+	})
+})
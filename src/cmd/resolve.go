@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/values"
+)
+
+// runResolve prints the effective values of the applications of a cluster
+// group, or only of the named applications.
+func runResolve(out io.Writer, format string, cluster values.Cluster, apps []string) error {
+	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return fmt.Errorf("error getting pattern information: %w", err)
+	}
+	if cluster.ClusterGroup == "" {
+		globalValues, err := pattern.LoadGlobalValues(patternName, repoRoot)
+		if err != nil {
+			return fmt.Errorf("error reading global values: %w", err)
+		}
+		cluster.ClusterGroup = globalValues.Main.ClusterGroupName
+	}
+
+	result, err := values.Resolve(repoRoot, cluster)
+	if err != nil {
+		return fmt.Errorf("error resolving values: %w", err)
+	}
+	if len(apps) > 0 {
+		for _, name := range apps {
+			if !slices.ContainsFunc(result.Applications, func(app values.Application) bool { return app.Key == name || app.Name == name }) {
+				return fmt.Errorf("application %q not found in cluster group %s", name, cluster.ClusterGroup)
+			}
+		}
+		result.Applications = slices.DeleteFunc(result.Applications, func(app values.Application) bool {
+			return !slices.Contains(apps, app.Key) && !slices.Contains(apps, app.Name)
+		})
+	}
+
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	return writeResolveText(out, result)
+}
+
+func writeResolveText(out io.Writer, result *values.Result) error {
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(out, "Warning: %s\n", warning)
+	}
+
+	cluster := result.Cluster
+	var details []string
+	if cluster.Platform != "" {
+		details = append(details, "platform "+cluster.Platform)
+	}
+	if cluster.ClusterVersion != "" {
+		details = append(details, "version "+cluster.ClusterVersion)
+	}
+	if cluster.LocalClusterName != "" {
+		details = append(details, "local cluster "+cluster.LocalClusterName)
+	}
+	fmt.Fprintf(out, "Cluster group %s", cluster.ClusterGroup)
+	if len(details) > 0 {
+		fmt.Fprintf(out, " (%s)", strings.Join(details, ", "))
+	}
+	fmt.Fprintln(out)
+	if len(result.Applications) == 0 {
+		fmt.Fprintln(out, "No applications")
+	}
+
+	for _, app := range result.Applications {
+		fmt.Fprintf(out, "\nApplication %s", app.Name)
+		var where []string
+		if app.Namespace != "" {
+			where = append(where, "namespace "+app.Namespace)
+		}
+		if app.Path != "" {
+			where = append(where, "path "+app.Path)
+		}
+		if app.Chart != "" {
+			where = append(where, "chart "+app.Chart)
+		}
+		if len(where) > 0 {
+			fmt.Fprintf(out, " (%s)", strings.Join(where, ", "))
+		}
+		fmt.Fprintln(out)
+
+		fmt.Fprintln(out, "  Value files:")
+		for _, file := range app.ValueFiles {
+			if file.Found {
+				fmt.Fprintf(out, "    %s\n", file.Path)
+			} else {
+				fmt.Fprintf(out, "    %s (not found)\n", file.Path)
+			}
+		}
+
+		fmt.Fprintln(out, "  Values:")
+		paths := make([]string, 0, len(app.Sources))
+		for p := range app.Sources {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			value, err := json.Marshal((&values.Values{Tree: app.Values}).Lookup(values.SplitPath(p)...))
+			if err != nil {
+				return fmt.Errorf("error formatting %s: %w", p, err)
+			}
+			fmt.Fprintf(out, "    %s: %s  # %s\n", p, value, app.Sources[p])
+		}
+	}
+	return nil
+}
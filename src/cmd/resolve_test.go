@@ -0,0 +1,58 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("patternizer resolve", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		addDummyChart(tempDir, "app")
+		_ = runCLI(tempDir, "init")
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-AWS.yaml"), []byte("replicaCount: 3\n"), 0o644)).To(Succeed())
+	})
+
+	It("should print the effective values with their source", func() {
+		session := runCLI(tempDir, "resolve", "--platform", "AWS", "--cluster-version", "4.21")
+		output := string(session.Out.Contents())
+		Expect(output).To(ContainSubstring("Cluster group prod (platform AWS, version 4.21)"))
+		Expect(output).To(ContainSubstring("Application app (namespace %s, path charts/app)", filepath.Base(tempDir)))
+		Expect(output).To(ContainSubstring("    /values-AWS-4.21.yaml (not found)\n"))
+		Expect(output).To(ContainSubstring("    replicaCount: 3  # /values-AWS.yaml\n"))
+		Expect(output).To(ContainSubstring(`    global.clusterPlatform: "AWS"  # parameters`))
+
+		session = runCLI(tempDir, "resolve")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("    replicaCount: 1  # /charts/app/values.yaml\n"))
+	})
+
+	It("should print JSON", func() {
+		session := runCLI(tempDir, "resolve", "app", "--platform", "AWS", "-o", "json")
+		var result struct {
+			Cluster struct {
+				ClusterGroup string `json:"clusterGroup"`
+			} `json:"cluster"`
+			Applications []struct {
+				Name    string                 `json:"name"`
+				Values  map[string]interface{} `json:"values"`
+				Sources map[string]string      `json:"sources"`
+			} `json:"applications"`
+		}
+		Expect(json.Unmarshal(session.Out.Contents(), &result)).To(Succeed())
+		Expect(result.Cluster.ClusterGroup).To(Equal("prod"))
+		Expect(result.Applications).To(HaveLen(1))
+		Expect(result.Applications[0].Values).To(HaveKeyWithValue("replicaCount", BeNumerically("==", 3)))
+		Expect(result.Applications[0].Sources).To(HaveKeyWithValue("replicaCount", "/values-AWS.yaml"))
+	})
+
+	It("should fail for an unknown application", func() {
+		session := runCLIWithExitCode(tempDir, 1, "resolve", "missing")
+		Expect(string(session.Err.Contents())).To(ContainSubstring(`application "missing" not found`))
+	})
+})
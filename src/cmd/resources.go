@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"cmp"
+	"io/fs"
+
+	"github.com/validatedpatterns/patternizer/internal/config"
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+)
+
+// loadConfig reads the patternizer configuration of repoRoot.
+func loadConfig(repoRoot string) (*config.Config, error) {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return nil, report.Errorf(report.CodeConfig, "error reading patternizer configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyResourceOverrides layers the resources from resourcesDir, or from the
+// resourcesDir setting of cfg if it is empty, on top of base. The returned
+// cleanup function must be called once the resources have been written.
+func applyResourceOverrides(repoRoot string, base fs.FS, resourcesDir string, cfg *config.Config, rep *report.Report) (fs.FS, func(), error) {
+	if resourcesDir == "" {
+		resourcesDir = config.ResolvePath(repoRoot, cfg.ResourcesDir)
+	}
+	if resourcesDir == "" {
+		return base, func() {}, nil
+	}
+
+	overrides, cleanup, err := fileutils.OpenDirOrTarball(resourcesDir)
+	if err != nil {
+		return nil, nil, report.Errorf(report.CodeResourceOverrides, "error opening resource overrides: %w", err)
+	}
+
+	set, unknown, err := resources.WithOverrides(base, overrides)
+	if err != nil {
+		cleanup()
+		return nil, nil, report.Errorf(report.CodeResourceOverrides, "error in resource overrides %s: %w", resourcesDir, err)
+	}
+	for _, name := range unknown {
+		rep.Warnf(report.CodeResourceOverrides, "ignoring unknown file %s in resource overrides %s", name, resourcesDir)
+	}
+	rep.Infof("Using resource overrides from %s", resourcesDir)
+
+	return set, cleanup, nil
+}
+
+// templateData builds the context the resources are rendered with from the
+// global values of repoRoot and cfg. A non-empty containerRuntime overrides
+// the containerRuntime setting of cfg.
+func templateData(repoRoot, patternName, containerRuntime string, cfg *config.Config) (resources.TemplateData, error) {
+	values, err := pattern.LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		return resources.TemplateData{}, report.Errorf(report.CodeGlobalValues, "error reading global values: %w", err)
+	}
+	return resources.TemplateData{
+		PatternName:      values.Global.Pattern,
+		ClusterGroup:     values.Main.ClusterGroupName,
+		WithSecrets:      !values.Global.SecretLoader.Disabled,
+		UtilityContainer: cfg.UtilityContainer,
+		DisconnectedHome: cfg.DisconnectedHome,
+		ContainerRuntime: cmp.Or(containerRuntime, cfg.ContainerRuntime),
+	}, nil
+}
+
+// renderResources renders the templates of set with data.
+func renderResources(set fs.FS, data resources.TemplateData) (fs.FS, error) {
+	rendered, err := resources.Render(set, data)
+	if err != nil {
+		return nil, report.Errorf(report.CodeResourceTemplate, "error rendering resources: %w", err)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,72 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/validatedpatterns/patternizer/internal/config"
+)
+
+const orgPatternSh = "#!/bin/bash\n# org fork of pattern.sh\n"
+
+func createResourceOverrides(dir string) {
+	Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "pattern.sh"), []byte(orgPatternSh), 0o644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "Makefile-common"), []byte("org-target:\n\t@echo org\n"), 0o644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "ansible.cfg"), []byte("[defaults]\n"), 0o644)).To(Succeed())
+}
+
+func verifyFileContents(path, expected string) {
+	data, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(data)).To(Equal(expected))
+}
+
+var _ = Describe("resource overrides", func() {
+	It("should use the files from --resources-dir on init", func() {
+		tempDir := createTestDir()
+		overrides := filepath.Join(createTestDir(), "org-resources")
+		createResourceOverrides(overrides)
+
+		_ = runCLI(tempDir, "init", "--resources-dir", overrides)
+
+		verifyFileContents(filepath.Join(tempDir, "pattern.sh"), orgPatternSh)
+		info, err := os.Stat(filepath.Join(tempDir, "pattern.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & 0o111).NotTo(BeZero())
+		verifyFileContents(filepath.Join(tempDir, "Makefile-common"), "org-target:\n\t@echo org\n")
+		verifyMakefileCopied(tempDir)
+	})
+
+	It("should use a tarball configured in the repository configuration on upgrade", func() {
+		tempDir := createTestDir()
+		overrides := filepath.Join(createTestDir(), "org-resources")
+		createResourceOverrides(overrides)
+
+		tarCmd := exec.Command("tar", "-czf", filepath.Join(tempDir, "org-resources.tgz"), "-C", filepath.Dir(overrides), "org-resources")
+		session, err := gexec.Start(tarCmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(os.MkdirAll(filepath.Dir(config.Path(tempDir)), 0o755)).To(Succeed())
+		Expect(os.WriteFile(config.Path(tempDir), []byte("resourcesDir: org-resources.tgz\n"), 0o644)).To(Succeed())
+
+		_ = runCLI(tempDir, "upgrade")
+		verifyFileContents(filepath.Join(tempDir, "pattern.sh"), orgPatternSh)
+	})
+
+	It("should fail without touching files when required overrides are missing", func() {
+		tempDir := createTestDir()
+		overrides := createTestDir()
+		Expect(os.WriteFile(filepath.Join(overrides, "pattern.sh"), []byte(orgPatternSh), 0o644)).To(Succeed())
+
+		session := runCLIWithExitCode(tempDir, 1, "init", "--resources-dir", overrides)
+		Expect(string(session.Err.Contents())).To(ContainSubstring("missing required files"))
+		Expect(filepath.Join(tempDir, "pattern.sh")).NotTo(BeAnExistingFile())
+	})
+})
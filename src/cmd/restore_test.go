@@ -0,0 +1,61 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("patternizer restore", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+	})
+
+	It("should undo an upgrade made with --backup", func() {
+		_ = runCLI(tempDir, "init")
+		custom := "all:\n\t@echo custom\n"
+		Expect(os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte(custom), 0o644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tempDir, "common"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, "common", "Makefile"), []byte("operator-deploy:\n"), 0o644)).To(Succeed())
+
+		session := runCLI(tempDir, "upgrade", "--backup")
+		match := regexp.MustCompile(`patternizer restore (\S+)`).FindStringSubmatch(string(session.Out.Contents()))
+		Expect(match).NotTo(BeNil())
+		id := match[1]
+
+		session = runCLI(tempDir, "restore")
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`%s\s+upgrade\s`, regexp.QuoteMeta(id)))
+
+		rep := runCLIJSON(tempDir, "restore", id)
+		Expect(rep.Success).To(BeTrue())
+		_, ok := findFileChange(rep, "common/Makefile")
+		Expect(ok).To(BeTrue())
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(custom))
+		Expect(filepath.Join(tempDir, "common", "Makefile")).To(BeAnExistingFile())
+	})
+
+	It("should delete the files an init made with --backup created", func() {
+		_ = runCLI(tempDir, "init", "--backup")
+		Expect(filepath.Join(tempDir, "values-global.yaml")).To(BeAnExistingFile())
+
+		session := runCLI(tempDir, "restore")
+		id := regexp.MustCompile(`(?m)^(\S+)\s+init\s`).FindStringSubmatch(string(session.Out.Contents()))
+		Expect(id).NotTo(BeNil())
+		_ = runCLI(tempDir, "restore", id[1])
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, "pattern.sh")).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail for an unknown backup", func() {
+		session := runCLIWithExitCode(tempDir, 1, "restore", "20200101T000000Z")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("backup 20200101T000000Z not found"))
+	})
+})
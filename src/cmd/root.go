@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/validatedpatterns/patternizer/internal/manifests"
+	"github.com/validatedpatterns/patternizer/internal/prompt"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/values"
+	"github.com/validatedpatterns/patternizer/internal/version"
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	var withSecrets bool
-	var replaceMakefile bool
+	var initOpts initOptions
+	var upgradeOpts upgradeOptions
+	var outputFormat string
+	var interactive bool
 
 	var rootCmd = &cobra.Command{
 		Use:   "patternizer",
@@ -18,8 +27,16 @@ func Execute() {
 		Long: `patternizer is a CLI tool for creating and managing validated pattern configurations.
 It helps generate the necessary YAML files and setup for Validated Patterns including
 values-global.yaml, values-<clustergroup>.yaml, and optional secrets configuration.`,
+		Version: version.Get().Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return report.ValidateFormat(outputFormat)
+		},
 	}
 
+	rootCmd.SetVersionTemplate(version.Get().String() + "\n")
+
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", report.FormatText, "Output format: text or json")
+
 	var initCmd = &cobra.Command{
 		Use:     "init",
 		Aliases: []string{"create", "bootstrap"},
@@ -28,16 +45,40 @@ values-global.yaml, values-<clustergroup>.yaml, and optional secrets configurati
 for a validated pattern, including values-global.yaml and values-<clustergroup>.yaml.
 
 When --with-secrets is specified, it also copies the secrets template and
-configures the pattern.sh script for secrets usage.`,
+configures the pattern.sh script for secrets usage.
+
+With --interactive, init explains and asks for the pattern name, the main
+clustergroup, secrets support and the Helm charts to deploy, offering the
+current values as defaults.
+
+In a git repository, init refuses to modify files that have uncommitted
+changes unless --allow-dirty is given.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 && args[0] == "help" {
 				return cmd.Help()
 			}
-			return runInit(withSecrets)
+			if interactive {
+				initOpts.prompter = prompt.New(cmd.InOrStdin(), cmd.ErrOrStderr())
+			}
+			rep := report.New("init")
+			return finishReport(cmd, rep, runInit(initOpts, rep))
 		},
 	}
 
-	initCmd.Flags().BoolVar(&withSecrets, "with-secrets", false, "Include secrets template and configure pattern for secrets usage")
+	initCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Prompt for the pattern name, clustergroup, secrets and charts")
+	initCmd.Flags().BoolVar(&initOpts.withSecrets, "with-secrets", false, "Include secrets template and configure pattern for secrets usage")
+	initCmd.Flags().StringVar(&initOpts.settings.PatternName, "pattern-name", "", "Pattern name (defaults to global.pattern or the repository directory name)")
+	initCmd.Flags().StringVar(&initOpts.settings.ClusterGroupName, "clustergroup", "", "Main clustergroup name (defaults to main.clusterGroupName or 'prod'); renames an existing values-<clustergroup>.yaml")
+	initCmd.Flags().StringVar(&initOpts.settings.ClusterGroupChartVersion, "clustergroup-chart-version", "", "Version of the clustergroup Helm chart (keeps the current version, '0.9.*' for new patterns)")
+	initCmd.Flags().BoolVar(&initOpts.allowDowngrade, "allow-downgrade", false, "Allow overwriting files generated by a newer patternizer version")
+	initCmd.Flags().StringVar(&initOpts.resourcesDir, "resources-dir", "", "Directory or tarball whose files override the embedded resources")
+	initCmd.Flags().StringVar(&initOpts.runtime, "container-runtime", "", "Container runtime used by pattern.sh: podman, docker or auto (default podman)")
+	initCmd.Flags().BoolVar(&initOpts.backup, "backup", false, "Back up the files about to be changed to .patternizer/backups/<timestamp>/")
+	initCmd.Flags().BoolVar(&initOpts.allowDirty, "allow-dirty", false, "Allow modifying files that have uncommitted changes in git")
+	initCmd.Flags().StringVar(&initOpts.skills, "skills", "", skillsFlagUsage)
+	initCmd.Flags().BoolVar(&initOpts.force, "force", false, forceSkillsFlagUsage)
+	initCmd.Flags().BoolVar(&initOpts.commit, "commit", false, "Commit the changed files with a generated message")
+	initCmd.Flags().StringVar(&initOpts.branch, "branch", "", "Create this branch for the commit (implies --commit)")
 
 	rootCmd.AddCommand(initCmd)
 
@@ -48,22 +89,442 @@ configures the pattern.sh script for secrets usage.`,
 		Long: `Upgrade an existing pattern repository by refreshing common assets.
 
 This will remove the legacy common/ directory and pattern.sh symlink if present,
-copy updated Makefile-common and pattern.sh, and optionally replace or update the Makefile.`,
+copy updated Makefile-common and pattern.sh, and optionally replace or update the Makefile.
+
+In a git repository, upgrade refuses to modify files that have uncommitted
+changes unless --allow-dirty is given.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 && args[0] == "help" {
 				return cmd.Help()
 			}
-			return runUpgrade(replaceMakefile)
+			rep := report.New("upgrade")
+			return finishReport(cmd, rep, runUpgrade(upgradeOpts, rep))
 		},
 	}
 
-	upgradeCmd.Flags().BoolVar(&replaceMakefile, "replace-makefile", false, "Replace the existing Makefile with the default")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.replaceMakefile, "replace-makefile", false, "Replace the existing Makefile with the default")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.allowDowngrade, "allow-downgrade", false, "Allow overwriting files generated by a newer patternizer version")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.toVersion, "to", "", "Resource version to upgrade to (defaults to the newest embedded version)")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.resourcesDir, "resources-dir", "", "Directory or tarball whose files override the embedded resources")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.runtime, "container-runtime", "", "Container runtime used by pattern.sh: podman, docker or auto (default podman)")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.backup, "backup", false, "Back up the files about to be changed to .patternizer/backups/<timestamp>/")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.allowDirty, "allow-dirty", false, "Allow modifying files that have uncommitted changes in git")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.skills, "skills", "", skillsFlagUsage)
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.force, "force", false, forceSkillsFlagUsage)
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.commit, "commit", false, "Commit the changed files with a generated message")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.branch, "branch", "", "Create this branch for the commit (implies --commit)")
 	rootCmd.AddCommand(upgradeCmd)
 
+	var migrateOpts migrateOptions
+	var migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate a pattern from the legacy common/ layout",
+		Long: `Migrate a pattern repository that still uses the legacy common/ subtree.
+
+References to common/Makefile and common/scripts/pattern-util.sh in the Makefile
+and scripts are rewritten to Makefile-common and pattern.sh. Applications that
+deploy a chart from common/ (acm, hashicorp-vault, golang-external-secrets,
+letsencrypt) are deployed from the chart repository instead,
+main.multiSourceConfig is enabled, global.singleArgoCD is set unless the
+pattern chose a value, and clusterGroup.isHubCluster is replaced by
+main.clusterGroupName. The repository is then upgraded, which removes common/.
+
+References that cannot be converted are reported, and nothing is changed until
+they are fixed or --force is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("migrate")
+			return finishReport(cmd, rep, runMigrate(migrateOpts, rep))
+		},
+	}
+
+	migrateCmd.Flags().BoolVar(&migrateOpts.dryRun, "dry-run", false, "Report the legacy references without changing any file")
+	migrateCmd.Flags().BoolVar(&migrateOpts.force, "force", false, "Remove common/ even if some references cannot be converted, and overwrite local changes to installed skills")
+	migrateCmd.Flags().BoolVar(&migrateOpts.upgrade.allowDowngrade, "allow-downgrade", false, "Allow overwriting files generated by a newer patternizer version")
+	migrateCmd.Flags().StringVar(&migrateOpts.upgrade.runtime, "container-runtime", "", "Container runtime used by pattern.sh: podman, docker or auto (default podman)")
+	migrateCmd.Flags().BoolVar(&migrateOpts.upgrade.backup, "backup", false, "Back up the files about to be changed to .patternizer/backups/<timestamp>/")
+	migrateCmd.Flags().BoolVar(&migrateOpts.upgrade.allowDirty, "allow-dirty", false, "Allow modifying files that have uncommitted changes in git")
+	migrateCmd.Flags().StringVar(&migrateOpts.upgrade.skills, "skills", "", skillsFlagUsage)
+	migrateCmd.Flags().BoolVar(&migrateOpts.upgrade.commit, "commit", false, "Commit the changed files with a generated message")
+	migrateCmd.Flags().StringVar(&migrateOpts.upgrade.branch, "branch", "", "Create this branch for the commit (implies --commit)")
+	rootCmd.AddCommand(migrateCmd)
+
+	var restoreCmd = &cobra.Command{
+		Use:   "restore [BACKUP_ID]",
+		Short: "List or restore the backups made with --backup",
+		Long: `List the backups that init, upgrade and migrate made with --backup, or restore one.
+
+Restoring a backup puts back the files the command changed or deleted, and
+deletes the files it created. The backups are kept in .patternizer/backups/,
+which is ignored by git.`,
+		Example: `  patternizer restore
+  patternizer restore 20261018T204854Z`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			}
+			rep := report.New("restore")
+			return finishReport(cmd, rep, runRestore(id, rep))
+		},
+	}
+	rootCmd.AddCommand(restoreCmd)
+
+	var changelogFrom, changelogTo string
+
+	var changelogCmd = &cobra.Command{
+		Use:   "changelog",
+		Short: "Summarize resource changes between versions",
+		Long: `Summarize what differs in the managed resources (pattern.sh, Makefile-common,
+ansible.cfg, ...) between the version currently used by the repository and a
+target version.
+
+The current version is read from .patternizer/metadata.yaml, or detected by
+comparing the files in the repository with every embedded resource set.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChangelog(cmd.OutOrStdout(), outputFormat, changelogFrom, changelogTo)
+		},
+	}
+
+	changelogCmd.Flags().StringVar(&changelogFrom, "from", "", "Resource version to compare from (defaults to the repository's current version)")
+	changelogCmd.Flags().StringVar(&changelogTo, "to", "", "Resource version to compare to (defaults to the newest embedded version)")
+	rootCmd.AddCommand(changelogCmd)
+
+	var resolveCluster values.Cluster
+
+	var resolveCmd = &cobra.Command{
+		Use:   "resolve [application...]",
+		Short: "Show the effective values of the applications of a clustergroup",
+		Long: `Show the values each application of a clustergroup sees on a given cluster,
+without connecting to it.
+
+The values files are loaded in the framework's precedence order:
+
+  values-global.yaml
+  values-<clustergroup>.yaml
+  values-<platform>.yaml
+  values-<platform>-<version>.yaml
+  values-<platform>-<clustergroup>.yaml
+  values-<version>-<clustergroup>.yaml
+  values-<local cluster>.yaml
+  global.extraValueFiles
+  clusterGroup.sharedValueFiles
+  extraValueFiles of the application
+
+on top of the values.yaml of local charts, and merged like Helm does. The
+overrides of the application and the cluster parameters (global.clusterPlatform,
+global.clusterVersion, global.localClusterName) are applied last. Every value is
+printed with the file it comes from.`,
+		Example: `  patternizer resolve --platform AWS --cluster-version 4.21
+  patternizer resolve --clustergroup hub --platform Azure my-app`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResolve(cmd.OutOrStdout(), outputFormat, resolveCluster, args)
+		},
+	}
+
+	resolveCmd.Flags().StringVar(&resolveCluster.ClusterGroup, "clustergroup", "", "Clustergroup (defaults to main.clusterGroupName)")
+	resolveCmd.Flags().StringVar(&resolveCluster.Platform, "platform", "", "Cluster platform (global.clusterPlatform), e.g. AWS")
+	resolveCmd.Flags().StringVar(&resolveCluster.ClusterVersion, "cluster-version", "", "OpenShift major.minor version (global.clusterVersion), e.g. 4.21")
+	resolveCmd.Flags().StringVar(&resolveCluster.LocalClusterName, "local-cluster", "", "Name of the cluster (global.localClusterName)")
+	rootCmd.AddCommand(resolveCmd)
+
+	var renderOpts renderOptions
+
+	var renderCmd = &cobra.Command{
+		Use:   "render",
+		Short: "Render the Argo CD applications of a clustergroup",
+		Long: `Render the Namespace, OperatorGroup, Subscription, AppProject and Application
+objects the clustergroup chart creates from values-global.yaml and
+values-<clustergroup>.yaml, without a cluster or the upstream chart.
+
+Applications use multiple sources with the pattern repository as $patternref
+when main.multiSourceConfig.enabled is set, and list the values files of the
+framework's hierarchy with ignoreMissingValueFiles. The Git repository and
+revision default to the remote and branch the current branch tracks, like
+pattern-cr. Render before and after a
+values change and diff the output, or write one file per object with
+--output-dir and compare the directories.`,
+		Example: `  patternizer render > before.yaml
+  patternizer render --platform AWS --cluster-version 4.21 --output-dir /tmp/rendered`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRender(cmd.OutOrStdout(), outputFormat, renderOpts)
+		},
+	}
+
+	renderCmd.Flags().StringVar(&renderOpts.cluster.ClusterGroup, "clustergroup", "", "Clustergroup (defaults to main.clusterGroupName)")
+	renderCmd.Flags().StringVar(&renderOpts.cluster.Platform, "platform", "", "Cluster platform (global.clusterPlatform), e.g. AWS")
+	renderCmd.Flags().StringVar(&renderOpts.cluster.ClusterVersion, "cluster-version", "", "OpenShift major.minor version (global.clusterVersion), e.g. 4.21")
+	renderCmd.Flags().StringVar(&renderOpts.cluster.LocalClusterName, "local-cluster", "", "Name of the cluster (global.localClusterName)")
+	renderCmd.Flags().StringVar(&renderOpts.repoURL, "repo-url", "", "Git URL of the pattern repository (defaults to the upstream remote)")
+	renderCmd.Flags().StringVar(&renderOpts.targetRevision, "target-revision", "", "Branch, tag or commit of the pattern repository (defaults to the upstream branch)")
+	renderCmd.Flags().StringVar(&renderOpts.outputDir, "output-dir", "", "Write one YAML file per object to this directory")
+	rootCmd.AddCommand(renderCmd)
+
+	var patternCRSource manifests.GitSource
+
+	var patternCRCmd = &cobra.Command{
+		Use:   "pattern-cr",
+		Short: "Print the Pattern CR that make install would create",
+		Long: `Print the Pattern custom resource that make install creates through the
+pattern-install chart, to apply it directly, e.g. from another GitOps
+repository.
+
+The CR is built from values-global.yaml: the pattern name, main.clusterGroupName
+and main.multiSourceConfig. The Git repository and revision are the remote and
+branch the current branch tracks, with SSH URLs converted to HTTPS. The command
+fails when the current branch has no upstream, since the cluster could not
+fetch it; use --target-repo and --target-revision to set them explicitly.`,
+		Example: `  patternizer pattern-cr > pattern.yaml
+  patternizer pattern-cr --target-revision v1.2 | oc apply -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPatternCR(cmd.OutOrStdout(), outputFormat, patternCRSource)
+		},
+	}
+
+	patternCRCmd.Flags().StringVar(&patternCRSource.TargetRepo, "target-repo", "", "Git URL of the pattern repository (defaults to the upstream remote)")
+	patternCRCmd.Flags().StringVar(&patternCRSource.TargetRevision, "target-revision", "", "Branch of the pattern repository (defaults to the upstream branch)")
+	rootCmd.AddCommand(patternCRCmd)
+
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the pattern is ready to be installed",
+		Long: `Check, without connecting to a cluster, that the repository is ready for
+./pattern.sh make install:
+
+  managed-files  pattern.sh, Makefile-common and ansible.cfg are present and
+                 match the newest embedded resources
+  makefile       the Makefile includes Makefile-common
+  legacy-files   the legacy common/ directory and pattern.sh symlink are gone
+  git            the origin remote exists and the current branch has an upstream
+  kubeconfig     KUBECONFIG lies under the home directory, which pattern.sh
+                 mounts into the container
+  secrets        the clustergroup and secrets template match
+                 global.secretLoader.disabled
+
+The command fails when a check reports an error; warnings do not fail it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd.OutOrStdout(), outputFormat)
+		},
+	}
+	rootCmd.AddCommand(doctorCmd)
+
+	var runRuntime string
+
+	var runCmd = &cobra.Command{
+		Use:   "run [flags] command [args...]",
+		Short: "Run a command in the utility container",
+		Long: `Run a command in the Validated Patterns utility container, like pattern.sh.
+
+The current directory and your home directory are mounted into the container,
+your user is mapped into it, and the environment variables used by the pattern
+tooling (KUBECONFIG, TARGET_*, VP_*, ...) are passed through. The image is taken
+from PATTERN_UTILITY_CONTAINER, PATTERN_DISCONNECTED_HOME or the utilityContainer
+and disconnectedHome settings of .patternizer/config.yaml. The runtime defaults
+to the containerRuntime setting, or to podman like the generated pattern.sh;
+auto uses podman with a fallback to docker.
+
+When patternizer already runs inside a container, the command is run directly.`,
+		Example: `  patternizer run make install
+  patternizer run --runtime docker make show`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runRun(cmd.ErrOrStderr(), runRuntime, args)
+			var exitErr *exitCodeError
+			if errors.As(err, &exitErr) {
+				// The command already reported its failure.
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	// Stop parsing flags at the first argument so that flags of the command
+	// run in the container are passed through.
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().StringVar(&runRuntime, "runtime", "", "Container runtime: podman, docker or auto (default podman)")
+	rootCmd.AddCommand(runCmd)
+
+	var addCmd = &cobra.Command{
+		Use:   "add",
+		Short: "Add files to the pattern",
+	}
+
+	var overrideOpts overrideOptions
+
+	var addOverrideCmd = &cobra.Command{
+		Use:   "override",
+		Short: "Add a values override file for a platform, version or clustergroup",
+		Long: `Add a values override file that applies to the clusters of a platform, an
+OpenShift version, a clustergroup, or a combination of them.
+
+The framework reads these files from the repository root:
+
+  values-<platform>.yaml
+  values-<platform>-<version>.yaml
+  values-<platform>-<clustergroup>.yaml
+  values-<version>-<clustergroup>.yaml
+
+Other combinations, and files selected by custom global values, need --shared:
+the file is created in overrides/ and its templated path is registered in the
+clusterGroup.sharedValueFiles of the clustergroup (the main one by default).
+Combinations the framework would never load are rejected.`,
+		Example: `  patternizer add override --platform AWS
+  patternizer add override --cluster-version 4.16 --clustergroup hub
+  patternizer add override --shared --platform Azure --global region=eastus`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("add override")
+			return finishReport(cmd, rep, runAddOverride(overrideOpts, rep))
+		},
+	}
+
+	addOverrideCmd.Flags().StringVar(&overrideOpts.platform, "platform", "", "Cluster platform (global.clusterPlatform), e.g. AWS, Azure, GCP, BareMetal")
+	addOverrideCmd.Flags().StringVar(&overrideOpts.clusterVersion, "cluster-version", "", "OpenShift major.minor version (global.clusterVersion), e.g. 4.16")
+	addOverrideCmd.Flags().StringVar(&overrideOpts.clusterGroup, "clustergroup", "", "Clustergroup name")
+	addOverrideCmd.Flags().StringArrayVar(&overrideOpts.globals, "global", nil, "Custom global value KEY=VALUE set in values-global.yaml (requires --shared; repeatable)")
+	addOverrideCmd.Flags().BoolVar(&overrideOpts.shared, "shared", false, "Create the file in overrides/ and register it in clusterGroup.sharedValueFiles")
+	addCmd.AddCommand(addOverrideCmd)
+	rootCmd.AddCommand(addCmd)
+
+	var renameCmd = &cobra.Command{
+		Use:   "rename",
+		Short: "Rename the pattern or a clustergroup",
+		Long: `Rename the pattern or a clustergroup consistently across every values file.
+
+All files are rewritten at once: if any of them cannot be written, the files
+already changed are restored.`,
+	}
+
+	var renamePatternCmd = &cobra.Command{
+		Use:   "pattern NEW_NAME",
+		Short: "Rename the pattern",
+		Long: `Rename the pattern by updating global.pattern, the namespace named after the
+pattern and the applications and subscriptions deployed to it in every
+values-*.yaml file, and by renaming ~/values-secret-<pattern>.yaml (and its
+copies in ~/.config/hybrid-cloud-patterns and ~/.config/validated-patterns).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("rename")
+			return finishReport(cmd, rep, runRenamePattern(args[0], rep))
+		},
+	}
+
+	var renameClusterGroupCmd = &cobra.Command{
+		Use:   "clustergroup OLD_NAME NEW_NAME",
+		Short: "Rename a clustergroup",
+		Long: `Rename a clustergroup by renaming values-<clustergroup>.yaml and its
+clusterGroup.name, and by updating main.clusterGroupName and the
+managedClusterGroups entries that refer to it.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("rename")
+			return finishReport(cmd, rep, runRenameClusterGroup(args[0], args[1], rep))
+		},
+	}
+
+	renameCmd.AddCommand(renamePatternCmd, renameClusterGroupCmd)
+	rootCmd.AddCommand(renameCmd)
+
+	var skillsCmd = &cobra.Command{
+		Use:   "skills",
+		Short: "List, install, update or remove the AI coding skills",
+		Long: `Manage the AI coding skills that patternizer ships and installs into the
+skill directories of AI tools, such as .claude/skills/ and .cursor/skills/.
+
+The installed skills, their version, targets and files are recorded in
+.patternizer/metadata.yaml. Updating a skill removes the files its new version
+no longer has, and removing it deletes only the files patternizer installed.
+Other skills in the same directories are never touched.`,
+	}
+
+	var skillsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the embedded and installed skills and their versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("skills list")
+			return finishReport(cmd, rep, runSkillsList(rep))
+		},
+	}
+
+	var skillsInstallTargets string
+	var skillsForce bool
+
+	var skillsInstallCmd = &cobra.Command{
+		Use:   "install [SKILL...]",
+		Short: "Install skills for the chosen AI tools",
+		Long: `Install the named skills, or all skills patternizer ships, for the AI tools
+chosen with --skills or the skills setting of .patternizer/config.yaml, and
+remove them from the other tools.`,
+		Example: `  patternizer skills install
+  patternizer skills install pattern-author --skills github`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("skills install")
+			return finishReport(cmd, rep, runSkillsInstall(args, skillsInstallTargets, skillsForce, rep))
+		},
+	}
+	skillsInstallCmd.Flags().StringVar(&skillsInstallTargets, "skills", "", skillsFlagUsage)
+	skillsInstallCmd.Flags().BoolVar(&skillsForce, "force", false, forceSkillsFlagUsage)
+
+	var skillsUpdateCmd = &cobra.Command{
+		Use:   "update [SKILL...]",
+		Short: "Update the installed skills to the embedded version",
+		Long: `Update the named installed skills, or all of them, to the version patternizer
+ships, for the AI tools they are installed for. Files dropped from the new
+version are removed, and installed skills that patternizer no longer ships are
+removed entirely. Skills whose installed files were changed locally are left
+alone unless --force is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("skills update")
+			return finishReport(cmd, rep, runSkillsUpdate(args, skillsForce, rep))
+		},
+	}
+	skillsUpdateCmd.Flags().BoolVar(&skillsForce, "force", false, forceSkillsFlagUsage)
+
+	var skillsRemoveCmd = &cobra.Command{
+		Use:   "remove [SKILL...]",
+		Short: "Remove installed skills",
+		Long: `Remove the named installed skills, or all of them, from every AI tool. Only the
+files patternizer installed are deleted; files added to a skill directory by
+hand and skills patternizer did not install are kept. Skills whose installed
+files were changed locally are kept unless --force is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rep := report.New("skills remove")
+			return finishReport(cmd, rep, runSkillsRemove(args, skillsForce, rep))
+		},
+	}
+	skillsRemoveCmd.Flags().BoolVar(&skillsForce, "force", false, "Remove skills even if their installed files were changed locally")
+
+	skillsCmd.AddCommand(skillsListCmd, skillsInstallCmd, skillsUpdateCmd, skillsRemoveCmd)
+	rootCmd.AddCommand(skillsCmd)
+
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print version information",
+		Long: `Print the version, git commit and build date of patternizer, together with
+the SHA-256 of every embedded resource (pattern.sh, Makefile-common, etc.) and skill.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersion(cmd.OutOrStdout(), outputFormat)
+		},
+	}
+
+	rootCmd.AddCommand(versionCmd)
+
 	// Hide the completion command from help since this is primarily used in containers
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
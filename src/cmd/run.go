@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/runner"
+)
+
+// exitCodeError reports that a command run by patternizer exited with a
+// non-zero code, which patternizer exits with in turn.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// runRun runs args in the utility container, like pattern.sh. If the command
+// fails, the returned error is an *exitCodeError with its exit code.
+func runRun(stderr io.Writer, runtime string, args []string) error {
+	_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return fmt.Errorf("error getting pattern information: %w", err)
+	}
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	inv, err := runner.Build(runner.OSHost{}, runner.Options{
+		Runtime:          cmp.Or(runtime, cfg.ContainerRuntime),
+		Image:            cfg.UtilityContainer,
+		DisconnectedHome: cfg.DisconnectedHome,
+	}, args)
+	if err != nil {
+		return err
+	}
+	for _, note := range inv.Notes {
+		fmt.Fprintln(stderr, note)
+	}
+
+	command := exec.Command(inv.Path, inv.Args...)
+	command.Env = append(os.Environ(), inv.Env...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &exitCodeError{code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("error running %s: %w", inv.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("patternizer run", func() {
+	// KUBERNETES_SERVICE_HOST makes patternizer behave as if it already ran in
+	// a container, so the command is run directly without a container runtime.
+	runInContainer := func(dir string, exitCode int, args ...string) *gexec.Session {
+		cmd := exec.Command(binaryPath, append([]string{"run"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "KUBERNETES_SERVICE_HOST=10.0.0.1")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(exitCode))
+		return session
+	}
+
+	It("should pass the command's flags and output through", func() {
+		session := runInContainer(createTestDir(), 0, "echo", "-n", "hello")
+		Expect(string(session.Out.Contents())).To(Equal("hello"))
+		Expect(string(session.Err.Contents())).To(ContainSubstring("Already running in a container"))
+	})
+
+	It("should exit with the command's exit code", func() {
+		session := runInContainer(createTestDir(), 3, "sh", "-c", "echo failed >&2; exit 3")
+		Expect(string(session.Err.Contents())).To(HaveSuffix("failed\n"))
+	})
+
+	It("should reject an unknown runtime", func() {
+		session := runCLIWithExitCode(createTestDir(), 1, "run", "--runtime", "lxc", "make")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("unsupported container runtime"))
+	})
+})
@@ -0,0 +1,90 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+var _ = Describe("patternizer init with explicit names", func() {
+	It("should use the names and chart version from the flags on a new repository", func() {
+		tempDir := createTestDir()
+		addDummyChart(tempDir, "app")
+
+		_ = runCLI(tempDir, "init", "--pattern-name", "demo", "--clustergroup", "hub", "--clustergroup-chart-version", "0.10.*")
+
+		expectedGlobal := types.NewDefaultValuesGlobal()
+		expectedGlobal.Global.Pattern = "demo"
+		expectedGlobal.Main.ClusterGroupName = "hub"
+		expectedGlobal.Main.MultiSourceConfig.ClusterGroupChartVersion = "0.10.*"
+		verifyGlobalValues(filepath.Join(tempDir, "values-global.yaml"), expectedGlobal)
+
+		values, err := pattern.LoadClusterGroupValues("hub", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.ClusterGroup.Name).To(Equal("hub"))
+		Expect(values.ClusterGroup.Applications["app"].Namespace).To(Equal("demo"))
+		Expect(filepath.Join(tempDir, "values-prod.yaml")).NotTo(BeAnExistingFile())
+	})
+
+	It("should rename the clustergroup of an existing repository", func() {
+		tempDir := createTestDir()
+		addDummyChart(tempDir, "app")
+		_ = runCLI(tempDir, "init")
+
+		// Customize the generated clustergroup to check it survives the rename.
+		prodFile := filepath.Join(tempDir, "values-prod.yaml")
+		data, err := os.ReadFile(prodFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(prodFile, append(data, []byte("  isHubCluster: true\n")...), 0o644)).To(Succeed())
+
+		session := runCLI(tempDir, "init", "--clustergroup", "hub")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Renamed values-prod.yaml to values-hub.yaml"))
+
+		Expect(prodFile).NotTo(BeAnExistingFile())
+		globalValues, err := pattern.LoadGlobalValues(filepath.Base(tempDir), tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(globalValues.Main.ClusterGroupName).To(Equal("hub"))
+
+		values, err := pattern.LoadClusterGroupValues("hub", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.ClusterGroup.Name).To(Equal("hub"))
+		Expect(values.ClusterGroup.OtherFields).To(HaveKeyWithValue("isHubCluster", true))
+		Expect(values.ClusterGroup.Applications).To(HaveKey("app"))
+	})
+
+	It("should update the references to the renamed clustergroup", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-edge.yaml"), []byte("clusterGroup:\n  name: edge\n  managedClusterGroups:\n    - name: prod\n      helmOverrides: []\n"), 0o644)).To(Succeed())
+
+		_ = runCLI(tempDir, "init", "--clustergroup", "hub")
+		values, err := pattern.LoadClusterGroupValues("edge", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.ClusterGroup.OtherFields["managedClusterGroups"]).To(ConsistOf(HaveKeyWithValue("name", "hub")))
+	})
+
+	It("should refuse to rename onto an existing clustergroup file", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-hub.yaml"), []byte("clusterGroup:\n  name: hub\n"), 0o644)).To(Succeed())
+
+		session := runCLIWithExitCode(tempDir, 1, "init", "--clustergroup", "hub")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("values-hub.yaml: the file already exists"))
+
+		globalValues, err := pattern.LoadGlobalValues(filepath.Base(tempDir), tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(globalValues.Main.ClusterGroupName).To(Equal("prod"))
+	})
+
+	It("should reject invalid names", func() {
+		tempDir := createTestDir()
+		session := runCLIWithExitCode(tempDir, 1, "init", "--clustergroup", "Hub_1")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("invalid clustergroup name"))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,471 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/config"
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+// skillsFlagUsage is the help of the --skills flag of init, upgrade and migrate.
+var skillsFlagUsage = "AI tools to install skills for: none, all or a comma-separated list of " +
+	strings.Join(fileutils.SkillTargetNames(), ", ") +
+	" (defaults to the skills setting of " + config.RelPath + ", else " + strings.Join(fileutils.DefaultSkillTargets, ",") + ")"
+
+// forceSkillsFlagUsage is the help of the --force flag of the commands that
+// install skills.
+const forceSkillsFlagUsage = "Overwrite installed skills even if their files were changed locally"
+
+// resolveSkillTargets returns the skill targets chosen with the --skills
+// flag or, if it is empty, in the repository configuration.
+func resolveSkillTargets(flag string, cfg *config.Config) ([]string, error) {
+	if flag == "" {
+		return cfg.SkillTargets(), nil
+	}
+	targets, err := fileutils.ParseSkillTargets([]string{flag})
+	if err != nil {
+		return nil, report.Errorf(report.CodeSkills, "invalid --skills: %w", err)
+	}
+	return targets, nil
+}
+
+// loadSkillBundles returns the embedded skills and those of the skill
+// sources of cfg, each of which is validated. A skill name may only be
+// provided once. The returned cleanup function must be called once the
+// skills have been installed.
+func loadSkillBundles(repoRoot string, cfg *config.Config) ([]*fileutils.SkillBundle, func(), error) {
+	bundles := []*fileutils.SkillBundle{fileutils.EmbeddedSkillBundle()}
+	var closers []func()
+	cleanup := func() {
+		for _, closeSource := range closers {
+			closeSource()
+		}
+	}
+	for _, source := range cfg.SkillSources {
+		fsys, closeSource, err := fileutils.OpenDirOrTarball(config.ResolvePath(repoRoot, source))
+		if err != nil {
+			cleanup()
+			return nil, nil, report.Errorf(report.CodeSkills, "error opening skill source %s: %w", source, err)
+		}
+		closers = append(closers, closeSource)
+		bundle, err := fileutils.LoadSkillBundle(fsys, source)
+		if err != nil {
+			cleanup()
+			return nil, nil, report.Errorf(report.CodeSkills, "invalid skill source %s: %w", source, err)
+		}
+		for _, name := range bundle.Names() {
+			if other := fileutils.FindSkillBundle(bundles, name); other != nil {
+				cleanup()
+				return nil, nil, report.Errorf(report.CodeSkills, "skill %s of %s is already provided by %s", name, source, other.Label())
+			}
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, cleanup, nil
+}
+
+// skillSourcePaths returns the directories that the skills of the configured
+// skill sources are installed to for any of the targets.
+func skillSourcePaths(bundles []*fileutils.SkillBundle) []string {
+	var paths []string
+	for _, bundle := range bundles {
+		if bundle.Source != "" {
+			paths = append(paths, bundle.Paths()...)
+		}
+	}
+	return paths
+}
+
+// installSkills installs the skills of bundles for targets, removes them from
+// the other targets and records them in the skill manifest. Skills removed
+// with skills remove are left out, and so are skills with local changes
+// unless force is set.
+func installSkills(repoRoot string, targets []string, bundles []*fileutils.SkillBundle, force bool, rep *report.Report) error {
+	m, err := loadSkillManifest(repoRoot)
+	if err != nil {
+		return err
+	}
+	skip := slices.Clone(m.RemovedSkills)
+	for _, name := range slices.Sorted(maps.Keys(m.Skills)) {
+		if skipModifiedSkill(repoRoot, name, manifestEntry(m, name), force, rep) {
+			skip = append(skip, name)
+		}
+	}
+	skills, err := fileutils.InstallSkills(repoRoot, targets, bundles, m.Skills, skip)
+	if err != nil {
+		return report.Errorf(report.CodeSkills, "error installing skills: %w", err)
+	}
+	if err := saveSkillManifest(repoRoot, m); err != nil {
+		return err
+	}
+	rep.Skills = append(rep.Skills, skills...)
+	for _, skill := range skills {
+		if source := m.Skills[skill].Source; source != "" {
+			rep.Infof("Installed skill '%s' from %s for %s", skill, source, strings.Join(targets, ", "))
+			continue
+		}
+		rep.Infof("Installed skill '%s' for %s", skill, strings.Join(targets, ", "))
+	}
+	return nil
+}
+
+// skipModifiedSkill reports whether an installed skill has local changes
+// that must be kept, in which case the skill is left alone. With force, the
+// changes are overwritten or removed with a warning instead. previous is the
+// manifest of the skill, if any.
+func skipModifiedSkill(repoRoot, name string, previous *skillbundle.InstalledSkill, force bool, rep *report.Report) bool {
+	if previous == nil {
+		return false
+	}
+	modified := fileutils.ModifiedSkillFiles(repoRoot, name, *previous)
+	if len(modified) == 0 {
+		return false
+	}
+	if force {
+		rep.Warnf(report.CodeSkills, "overwriting local changes to %s", strings.Join(modified, ", "))
+		return false
+	}
+	rep.Warnf(report.CodeSkills, "leaving skill %s alone because of local changes to %s; pass --force to overwrite them", name, strings.Join(modified, ", "))
+	return true
+}
+
+// runSkillsList reports, for every available or installed skill, the
+// available and the installed version and whether the installed copy is up
+// to date.
+func runSkillsList(rep *report.Report) error {
+	repoRoot, m, err := startSkillsCommand(rep)
+	if err != nil {
+		return err
+	}
+	_, bundles, cleanup, err := openSkillBundles(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var names []string
+	for _, bundle := range bundles {
+		names = append(names, bundle.Names()...)
+	}
+	for name := range m.Skills {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		available := "not available"
+		var latest skillbundle.InstalledSkill
+		bundle := fileutils.FindSkillBundle(bundles, name)
+		if bundle != nil {
+			if latest, err = bundle.Skill(name); err != nil {
+				return report.Errorf(report.CodeSkills, "%w", err)
+			}
+			available = "embedded " + latest.DisplayVersion()
+			if bundle.Source != "" {
+				available = "source " + bundle.Source + " " + latest.DisplayVersion()
+			}
+		}
+		installed, ok := m.Skills[name]
+		found := fileutils.FindSkill(repoRoot, name)
+		switch {
+		case !ok && slices.Contains(m.RemovedSkills, name):
+			rep.Infof("%s: %s, removed; init and upgrade leave it out until patternizer skills install %s", name, available, name)
+		case !ok && len(found) > 0 && bundle != nil && bundle.Source == "":
+			rep.Infof("%s: %s, installed for %s without a manifest; run patternizer skills update", name, available, strings.Join(found, ", "))
+		case !ok:
+			rep.Infof("%s: %s, not installed", name, available)
+		default:
+			rep.Skills = append(rep.Skills, name)
+			state := "up to date"
+			if bundle == nil {
+				state = fmt.Sprintf("no longer provided by %s; run patternizer skills update to remove it", cmp.Or(installed.Source, "patternizer"))
+			} else if installed.SHA256 != latest.SHA256 {
+				state = "outdated; run patternizer skills update"
+			}
+			if modified := fileutils.ModifiedSkillFiles(repoRoot, name, installed); len(modified) > 0 {
+				state += "; locally modified: " + strings.Join(modified, ", ")
+			}
+			rep.Infof("%s: %s, installed %s for %s (%s)", name, available, installed.DisplayVersion(), strings.Join(installed.Targets, ", "), state)
+		}
+	}
+	return nil
+}
+
+// runSkillsInstall installs the named skills, or all embedded skills and
+// those of the configured skill sources, for the targets chosen with
+// skillsFlag or in the configuration.
+func runSkillsInstall(names []string, skillsFlag string, force bool, rep *report.Report) (err error) {
+	repoRoot, m, err := startSkillsCommand(rep)
+	if err != nil {
+		return err
+	}
+	cfg, bundles, cleanup, err := openSkillBundles(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	targets, err := resolveSkillTargets(skillsFlag, cfg)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return report.Errorf(report.CodeSkills, "no skill targets selected; use patternizer skills remove to remove skills")
+	}
+	var available []string
+	for _, bundle := range bundles {
+		available = append(available, bundle.Names()...)
+	}
+	if len(names) == 0 {
+		names = available
+	}
+	for _, name := range names {
+		if !slices.Contains(available, name) {
+			return report.Errorf(report.CodeSkills, "unknown skill %q; available skills: %s", name, strings.Join(available, ", "))
+		}
+	}
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	paths = append(paths, skillSourcePaths(bundles)...)
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	for _, name := range names {
+		bundle := fileutils.FindSkillBundle(bundles, name)
+		previous := manifestEntry(m, name)
+		if skipModifiedSkill(repoRoot, name, previous, force, rep) {
+			continue
+		}
+		installed, err := fileutils.InstallSkill(repoRoot, bundle, name, targets, previous)
+		if err != nil {
+			return report.Errorf(report.CodeSkills, "error installing skill %s: %w", name, err)
+		}
+		m.Skills[name] = installed
+		m.RemovedSkills = slices.DeleteFunc(m.RemovedSkills, func(removed string) bool { return removed == name })
+		rep.Skills = append(rep.Skills, name)
+		rep.Infof("Installed skill '%s' %s from %s for %s", name, installed.DisplayVersion(), bundle.Label(), strings.Join(targets, ", "))
+	}
+	return saveSkillManifest(repoRoot, m)
+}
+
+// runSkillsUpdate updates the named installed skills, or all of them, to the
+// version that patternizer or their skill source provides, for the targets
+// they are installed for, pruning the files the new version no longer has.
+// Installed skills that are no longer provided are removed.
+func runSkillsUpdate(names []string, force bool, rep *report.Report) (err error) {
+	repoRoot, m, err := startSkillsCommand(rep)
+	if err != nil {
+		return err
+	}
+	_, bundles, cleanup, err := openSkillBundles(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if len(names) == 0 {
+		names = installedSkills(repoRoot, m)
+		if len(names) == 0 {
+			rep.Infof("No skills installed; install them with patternizer skills install")
+			return nil
+		}
+	}
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	paths = append(paths, skillSourcePaths(bundles)...)
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	for _, name := range names {
+		previous := manifestEntry(m, name)
+		bundle := fileutils.FindSkillBundle(bundles, name)
+		if bundle == nil {
+			if previous == nil {
+				return report.Errorf(report.CodeSkills, "skill %q is not installed by patternizer", name)
+			}
+			if err := fileutils.RemoveSkill(repoRoot, name, previous); err != nil {
+				return report.Errorf(report.CodeSkills, "error removing skill %s: %w", name, err)
+			}
+			delete(m.Skills, name)
+			rep.Infof("Removed skill '%s', which is no longer provided by %s", name, cmp.Or(previous.Source, "patternizer"))
+			continue
+		}
+		latest, err := bundle.Skill(name)
+		if err != nil {
+			return report.Errorf(report.CodeSkills, "%w", err)
+		}
+
+		targets := fileutils.FindSkill(repoRoot, name)
+		if previous != nil {
+			targets = previous.Targets
+		}
+		if len(targets) == 0 {
+			rep.Infof("Skill '%s' is not installed; install it with patternizer skills install %s", name, name)
+			continue
+		}
+		if skipModifiedSkill(repoRoot, name, previous, force, rep) {
+			continue
+		}
+		installed, err := fileutils.InstallSkill(repoRoot, bundle, name, targets, previous)
+		if err != nil {
+			return report.Errorf(report.CodeSkills, "error updating skill %s: %w", name, err)
+		}
+		m.Skills[name] = installed
+		rep.Skills = append(rep.Skills, name)
+		switch {
+		case previous == nil:
+			rep.Infof("Updated skill '%s' to %s for %s and recorded it in %s", name, latest.DisplayVersion(), strings.Join(targets, ", "), metadata.RelPath)
+		case previous.SHA256 == latest.SHA256:
+			rep.Infof("Skill '%s' %s is up to date for %s", name, latest.DisplayVersion(), strings.Join(targets, ", "))
+		default:
+			rep.Infof("Updated skill '%s' from %s to %s for %s", name, previous.DisplayVersion(), latest.DisplayVersion(), strings.Join(targets, ", "))
+		}
+	}
+	return saveSkillManifest(repoRoot, m)
+}
+
+// runSkillsRemove removes the named installed skills, or all of them, from
+// every target and records the removal, so that init and upgrade do not
+// install them again. Skills that patternizer did not install are left alone.
+func runSkillsRemove(names []string, force bool, rep *report.Report) (err error) {
+	repoRoot, m, err := startSkillsCommand(rep)
+	if err != nil {
+		return err
+	}
+	installed := installedSkills(repoRoot, m)
+	if len(names) == 0 {
+		names = installed
+		if len(names) == 0 {
+			rep.Infof("No skills installed")
+			return nil
+		}
+	}
+	for _, name := range names {
+		if !slices.Contains(installed, name) {
+			return report.Errorf(report.CodeSkills, "skill %q is not installed by patternizer", name)
+		}
+	}
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	finish, err := beginChanges(repoRoot, paths, nil, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	for _, name := range names {
+		if skipModifiedSkill(repoRoot, name, manifestEntry(m, name), force, rep) {
+			continue
+		}
+		if err := fileutils.RemoveSkill(repoRoot, name, manifestEntry(m, name)); err != nil {
+			return report.Errorf(report.CodeSkills, "error removing skill %s: %w", name, err)
+		}
+		delete(m.Skills, name)
+		if !slices.Contains(m.RemovedSkills, name) {
+			m.RemovedSkills = append(m.RemovedSkills, name)
+		}
+		rep.Infof("Removed skill '%s'; init and upgrade leave it out until patternizer skills install %s", name, name)
+	}
+	sort.Strings(m.RemovedSkills)
+	return saveSkillManifest(repoRoot, m)
+}
+
+// startSkillsCommand finds the repository root and loads its skill manifest.
+func startSkillsCommand(rep *report.Report) (string, *metadata.Metadata, error) {
+	_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+	if err != nil {
+		return "", nil, report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+	m, err := loadSkillManifest(repoRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	return repoRoot, m, nil
+}
+
+// installedSkills returns the names of the skills in the manifest and of the
+// embedded skills installed by a patternizer that did not record a manifest.
+func installedSkills(repoRoot string, m *metadata.Metadata) []string {
+	var names []string
+	for name := range m.Skills {
+		names = append(names, name)
+	}
+	for _, name := range fileutils.EmbeddedSkillBundle().Names() {
+		if _, ok := m.Skills[name]; !ok && len(fileutils.FindSkill(repoRoot, name)) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openSkillBundles loads the configuration of repoRoot and the skills it
+// makes available. The returned cleanup function must be called once the
+// skills have been installed.
+func openSkillBundles(repoRoot string) (*config.Config, []*fileutils.SkillBundle, func(), error) {
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bundles, cleanup, err := loadSkillBundles(repoRoot, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cfg, bundles, cleanup, nil
+}
+
+// manifestEntry returns the manifest of the installed skill name, or nil if
+// it has none.
+func manifestEntry(m *metadata.Metadata, name string) *skillbundle.InstalledSkill {
+	installed, ok := m.Skills[name]
+	if !ok {
+		return nil
+	}
+	return &installed
+}
+
+// loadSkillManifest reads the metadata of repoRoot with an initialized skill
+// manifest.
+func loadSkillManifest(repoRoot string) (*metadata.Metadata, error) {
+	m, err := metadata.Load(repoRoot)
+	if err != nil {
+		return nil, report.Errorf(report.CodeMetadata, "error reading patternizer metadata: %w", err)
+	}
+	if m.Skills == nil {
+		m.Skills = make(map[string]skillbundle.InstalledSkill)
+	}
+	return m, nil
+}
+
+// saveSkillManifest writes the metadata of repoRoot with its skill manifest.
+func saveSkillManifest(repoRoot string, m *metadata.Metadata) error {
+	if err := metadata.Save(repoRoot, m); err != nil {
+		return report.Errorf(report.CodeMetadata, "error writing patternizer metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+var _ = Describe("patternizer --skills", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+	})
+
+	It("should not create skill directories with none", func() {
+		rep := runCLIJSON(tempDir, "init", "--skills", "none")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Skills).To(BeEmpty())
+		Expect(filepath.Join(tempDir, ".claude")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, ".cursor")).NotTo(BeAnExistingFile())
+	})
+
+	It("should move the skills to the chosen targets on upgrade", func() {
+		_ = runCLI(tempDir, "init")
+		rep := runCLIJSON(tempDir, "upgrade", "--skills", "github,windsurf")
+		Expect(rep.Success).To(BeTrue())
+
+		Expect(filepath.Join(tempDir, ".github", "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, ".windsurf", "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, ".claude")).NotTo(BeAnExistingFile())
+		change, ok := findFileChange(rep, ".claude/skills/pattern-author/SKILL.md")
+		Expect(ok).To(BeTrue())
+		Expect(string(change.Action)).To(Equal("deleted"))
+	})
+
+	It("should use the skills setting of the configuration", func() {
+		Expect(os.MkdirAll(filepath.Join(tempDir, ".patternizer"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, ".patternizer", "config.yaml"), []byte("skills: [agents]\n"), 0o644)).To(Succeed())
+
+		_ = runCLI(tempDir, "init")
+		Expect(filepath.Join(tempDir, ".agents", "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, ".claude")).NotTo(BeAnExistingFile())
+
+		_ = runCLI(tempDir, "upgrade", "--skills", "all")
+		for _, target := range []string{".agents", ".claude", ".cursor", ".github", ".windsurf"} {
+			Expect(filepath.Join(tempDir, target, "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		}
+	})
+
+	It("should reject unknown targets before changing any file", func() {
+		session := runCLIWithExitCode(tempDir, 1, "init", "--skills", "claude,vim")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("unknown skill target \"vim\""))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("patternizer skills", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+	})
+
+	skillFile := func(target, file string) string {
+		return filepath.Join(tempDir, target, "skills", "pattern-author", file)
+	}
+
+	It("should list the embedded and installed versions", func() {
+		session := runCLI(tempDir, "skills", "list")
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`pattern-author: embedded sha256:[0-9a-f]{12}, not installed`))
+
+		_ = runCLI(tempDir, "init")
+		Expect(os.WriteFile(skillFile(".cursor", "SKILL.md"), []byte("changed"), 0o644)).To(Succeed())
+		session = runCLI(tempDir, "skills", "list")
+		Expect(string(session.Out.Contents())).To(MatchRegexp(
+			`pattern-author: embedded (sha256:[0-9a-f]{12}), installed (sha256:[0-9a-f]{12}) for claude, cursor \(up to date; locally modified: .cursor/skills/pattern-author/SKILL.md\)`))
+	})
+
+	It("should update the installed skills and prune stale files", func() {
+		_ = runCLI(tempDir, "init", "--skills", "claude")
+		metadataPath := filepath.Join(tempDir, ".patternizer", "metadata.yaml")
+		data, err := os.ReadFile(metadataPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("pattern-author:"))
+
+		// A file shipped by an earlier version of the skill.
+		Expect(os.WriteFile(skillFile(".claude", "old.md"), []byte("old"), 0o644)).To(Succeed())
+		data = []byte(strings.Replace(string(data), "files:\n", "files:\n      old.md: "+report.HashBytes([]byte("old"))+"\n", 1))
+		Expect(os.WriteFile(metadataPath, data, 0o644)).To(Succeed())
+
+		rep := runCLIJSON(tempDir, "skills", "update")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Skills).To(Equal([]string{"pattern-author"}))
+		change, ok := findFileChange(rep, ".claude/skills/pattern-author/old.md")
+		Expect(ok).To(BeTrue())
+		Expect(string(change.Action)).To(Equal("deleted"))
+		Expect(filepath.Join(tempDir, ".cursor")).NotTo(BeAnExistingFile())
+	})
+
+	It("should install and remove skills without touching other skills", func() {
+		teamSkill := filepath.Join(tempDir, ".github", "skills", "team", "SKILL.md")
+		Expect(os.MkdirAll(filepath.Dir(teamSkill), 0o755)).To(Succeed())
+		Expect(os.WriteFile(teamSkill, []byte("team"), 0o644)).To(Succeed())
+
+		_ = runCLI(tempDir, "skills", "install", "pattern-author", "--skills", "github")
+		Expect(skillFile(".github", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(tempDir, ".claude")).NotTo(BeAnExistingFile())
+
+		session := runCLIWithExitCode(tempDir, 1, "skills", "remove", "team")
+		Expect(string(session.Err.Contents())).To(ContainSubstring(`skill "team" is not installed by patternizer`))
+
+		rep := runCLIJSON(tempDir, "skills", "remove")
+		Expect(rep.Success).To(BeTrue())
+		Expect(filepath.Join(tempDir, ".github", "skills", "pattern-author")).NotTo(BeAnExistingFile())
+		Expect(teamSkill).To(BeAnExistingFile())
+
+		session = runCLI(tempDir, "skills", "list")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("pattern-author: embedded sha256:"))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("removed; init and upgrade leave it out until patternizer skills install pattern-author"))
+	})
+
+	It("should not install removed skills again on upgrade", func() {
+		_ = runCLI(tempDir, "init")
+		_ = runCLI(tempDir, "skills", "remove", "pattern-author")
+
+		rep := runCLIJSON(tempDir, "upgrade")
+		Expect(rep.Skills).To(BeEmpty())
+		Expect(filepath.Join(tempDir, ".claude", "skills", "pattern-author")).NotTo(BeAnExistingFile())
+
+		_ = runCLI(tempDir, "skills", "install", "pattern-author")
+		Expect(skillFile(".claude", "SKILL.md")).To(BeAnExistingFile())
+		data, err := os.ReadFile(filepath.Join(tempDir, ".patternizer", "metadata.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("removedSkills"))
+	})
+
+	It("should keep local changes to a skill unless --force is given", func() {
+		_ = runCLI(tempDir, "init")
+		Expect(os.WriteFile(skillFile(".cursor", "SKILL.md"), []byte("changed"), 0o644)).To(Succeed())
+		warnings := func(rep report.Report) []string {
+			var messages []string
+			for _, warning := range rep.Warnings {
+				messages = append(messages, warning.Message)
+			}
+			return messages
+		}
+		kept := "leaving skill pattern-author alone because of local changes to .cursor/skills/pattern-author/SKILL.md; pass --force to overwrite them"
+
+		rep := runCLIJSON(tempDir, "upgrade")
+		Expect(warnings(rep)).To(ContainElement(kept))
+		Expect(rep.Skills).To(BeEmpty())
+		for _, args := range [][]string{{"skills", "update"}, {"skills", "install", "pattern-author"}, {"skills", "remove", "pattern-author"}} {
+			rep = runCLIJSON(tempDir, args...)
+			Expect(warnings(rep)).To(ContainElement(kept), strings.Join(args, " "))
+			Expect(rep.Files).To(BeEmpty(), strings.Join(args, " "))
+		}
+		data, err := os.ReadFile(skillFile(".cursor", "SKILL.md"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("changed"))
+
+		rep = runCLIJSON(tempDir, "upgrade", "--force")
+		Expect(warnings(rep)).To(ContainElement("overwriting local changes to .cursor/skills/pattern-author/SKILL.md"))
+		Expect(rep.Skills).To(ConsistOf("pattern-author"))
+		verifySkillsInstalled(tempDir)
+	})
+
+	It("should reject unknown skills", func() {
+		session := runCLIWithExitCode(tempDir, 1, "skills", "install", "vim")
+		Expect(string(session.Err.Contents())).To(ContainSubstring(`unknown skill "vim"`))
+	})
+})
+
+var _ = Describe("patternizer skill sources", func() {
+	var tempDir string
+
+	// writeSkill writes a skill with the given front matter to dir.
+	writeSkill := func(dir, frontMatter string) {
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\n"+frontMatter+"---\n# Org conventions\n"), 0o644)).To(Succeed())
+	}
+
+	writeConfig := func(content string) {
+		Expect(os.MkdirAll(filepath.Join(tempDir, ".patternizer"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, ".patternizer", "config.yaml"), []byte(content), 0o644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		writeSkill(filepath.Join(tempDir, "org-skills", "org-conventions"), "name: org-conventions\ndescription: Our pattern conventions.\nversion: 1.0.0\nallowed-tools: Read\n")
+	})
+
+	It("should install the skills of a directory alongside the embedded ones", func() {
+		writeConfig("skillSources: [org-skills]\n")
+		rep := runCLIJSON(tempDir, "init")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Skills).To(ConsistOf("pattern-author", "org-conventions"))
+		Expect(rep.Messages).To(ContainElement("Installed skill 'org-conventions' from org-skills for claude, cursor"))
+		_, ok := findFileChange(rep, ".cursor/skills/org-conventions/SKILL.md")
+		Expect(ok).To(BeTrue())
+
+		session := runCLI(tempDir, "skills", "list")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("org-conventions: source org-skills 1.0.0, installed 1.0.0 for claude, cursor (up to date)"))
+
+		writeSkill(filepath.Join(tempDir, "org-skills", "org-conventions"), "name: org-conventions\ndescription: Our pattern conventions.\nversion: 1.1.0\n")
+		session = runCLI(tempDir, "skills", "update", "org-conventions")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Updated skill 'org-conventions' from 1.0.0 to 1.1.0 for claude, cursor"))
+
+		writeConfig("")
+		rep = runCLIJSON(tempDir, "upgrade", "--allow-dirty")
+		Expect(rep.Success).To(BeTrue())
+		Expect(rep.Skills).To(Equal([]string{"pattern-author"}))
+		Expect(filepath.Join(tempDir, ".claude", "skills", "org-conventions")).NotTo(BeAnExistingFile())
+		change, ok := findFileChange(rep, ".claude/skills/org-conventions/SKILL.md")
+		Expect(ok).To(BeTrue())
+		Expect(string(change.Action)).To(Equal("deleted"))
+	})
+
+	It("should install the skills of a tarball", func() {
+		tarCmd := exec.Command("tar", "-czf", filepath.Join(tempDir, "org-skills.tgz"), "-C", tempDir, "org-skills")
+		Expect(tarCmd.Run()).To(Succeed())
+		Expect(os.RemoveAll(filepath.Join(tempDir, "org-skills"))).To(Succeed())
+		writeConfig("skillSources: org-skills.tgz\nskills: github\n")
+
+		_ = runCLI(tempDir, "init")
+		Expect(filepath.Join(tempDir, ".github", "skills", "org-conventions", "SKILL.md")).To(BeAnExistingFile())
+		data, err := os.ReadFile(filepath.Join(tempDir, ".patternizer", "metadata.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("source: org-skills.tgz"))
+	})
+
+	It("should reject invalid skills before changing any file", func() {
+		writeSkill(filepath.Join(tempDir, "org-skills", "org-release"), "name: org-release\n")
+		writeConfig("skillSources: [org-skills]\n")
+		session := runCLIWithExitCode(tempDir, 1, "init")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("invalid skill source org-skills: invalid SKILL.md of org-release: description is required"))
+		Expect(filepath.Join(tempDir, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+
+	It("should reject skills that patternizer already ships", func() {
+		writeSkill(filepath.Join(tempDir, "org-skills", "pattern-author"), "name: pattern-author\ndescription: Ours.\n")
+		writeConfig("skillSources: [org-skills]\n")
+		session := runCLIWithExitCode(tempDir, 1, "skills", "install")
+		Expect(string(session.Err.Contents())).To(ContainSubstring("skill pattern-author of org-skills is already provided by patternizer"))
+	})
+})
@@ -1,75 +1,182 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 
-	"github.com/validatedpatterns/patternizer/internal/embedded"
 	"github.com/validatedpatterns/patternizer/internal/fileutils"
 	"github.com/validatedpatterns/patternizer/internal/pattern"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/resources"
 )
 
+// upgradeOptions holds the flags of the upgrade command.
+type upgradeOptions struct {
+	replaceMakefile bool
+	allowDowngrade  bool
+	toVersion       string
+	resourcesDir    string
+	runtime         string
+	skills          string
+	// force overwrites the local changes to installed skills.
+	force bool
+	changeOptions
+	// edits are applied before the legacy common/ directory is removed;
+	// migrate uses them to rewrite the references to it.
+	edits []fileutils.Edit
+}
+
 // runUpgrade handles the upgrade logic for the upgrade command.
-func runUpgrade(replaceMakefile bool) error {
-	_, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
+func runUpgrade(opts upgradeOptions, rep *report.Report) (err error) {
+	patternName, repoRoot, err := pattern.GetPatternNameAndRepoRoot()
 	if err != nil {
-		return fmt.Errorf("error getting pattern information: %w", err)
+		return report.Errorf(report.CodeRepository, "error getting pattern information: %w", err)
+	}
+	rep.RepoRoot = repoRoot
+
+	if err := checkGeneratorVersion(repoRoot, opts.allowDowngrade, rep); err != nil {
+		return err
+	}
+
+	catalog := resources.Embedded()
+	target := opts.toVersion
+	if target == "" {
+		target = catalog.Current()
+	}
+	baseSet, resourceVersion, err := catalog.Set(target)
+	if err != nil {
+		return report.Errorf(report.CodeResourceVersion, "error selecting resource version: %w", err)
+	}
+	if err := checkResourceVersion(repoRoot, catalog, resourceVersion, opts.allowDowngrade, rep); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+	skillTargets, err := resolveSkillTargets(opts.skills, cfg)
+	if err != nil {
+		return err
+	}
+	skillBundles, closeSkillSources, err := loadSkillBundles(repoRoot, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSkillSources()
+	overriddenSet, cleanup, err := applyResourceOverrides(repoRoot, baseSet, opts.resourcesDir, cfg, rep)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	data, err := templateData(repoRoot, patternName, opts.runtime, cfg)
+	if err != nil {
+		return err
+	}
+	resourceSet, err := renderResources(overriddenSet, data)
+	if err != nil {
+		return err
+	}
+
+	paths, err := managedPaths(repoRoot)
+	if err != nil {
+		return report.Errorf(report.CodeInternal, "error snapshotting pattern files: %w", err)
+	}
+	paths = append(paths, skillSourcePaths(skillBundles)...)
+	for _, edit := range opts.edits {
+		paths = append(paths, displayPath(repoRoot, edit.Path))
+	}
+	finish, err := beginChanges(repoRoot, paths, &opts.changeOptions, rep)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if err := fileutils.ApplyEdits(opts.edits); err != nil {
+		return report.Errorf(report.CodeMigration, "error rewriting legacy references: %w", err)
 	}
 
 	commonDirPath := filepath.Join(repoRoot, "common")
 	patternShPath := filepath.Join(repoRoot, "pattern.sh")
 
+	if info, statErr := os.Lstat(commonDirPath); statErr == nil && info.IsDir() {
+		upstream, err := pattern.EmbeddedUpstreamCommon()
+		if err != nil {
+			return report.Errorf(report.CodeInternal, "error reading upstream common releases: %w", err)
+		}
+		preserved, err := pattern.PreserveCommonFiles(repoRoot, upstream)
+		if err != nil {
+			return report.Errorf(report.CodeLegacyCommon, "error preserving custom files of common/: %w", err)
+		}
+		for _, file := range preserved {
+			rep.Warnf(report.CodeLegacyCommon, "moved common/%s, which is not part of a known upstream common/ release, to %s", file, filepath.ToSlash(filepath.Join(pattern.PreservedCommonDir, file)))
+		}
+		// The deleted files are listed in the report's file changes.
+		removed, err := report.Snapshot(repoRoot, []string{"common"})
+		if err != nil {
+			return report.Errorf(report.CodeInternal, "error listing common directory: %w", err)
+		}
+		if len(removed) > 0 {
+			rep.Warnf(report.CodeLegacyCommon, "removing legacy common/ directory with %d upstream files", len(removed))
+		}
+	}
 	if err := fileutils.RemovePathIfExists(commonDirPath); err != nil {
-		return fmt.Errorf("error removing common directory: %w", err)
+		return report.Errorf(report.CodeResourceCopy, "error removing common directory: %w", err)
 	}
 
+	if info, statErr := os.Lstat(patternShPath); statErr == nil && info.Mode()&os.ModeSymlink != 0 {
+		rep.Warnf(report.CodeLegacySymlink, "replacing pattern.sh symlink with a regular file")
+	}
 	if err := fileutils.RemovePathIfExists(patternShPath); err != nil {
-		return fmt.Errorf("error removing pattern.sh: %w", err)
+		return report.Errorf(report.CodeResourceCopy, "error removing pattern.sh: %w", err)
 	}
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/pattern.sh", patternShPath, 0o755); err != nil {
-		return fmt.Errorf("error copying pattern.sh: %w", err)
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/pattern.sh", patternShPath, 0o755); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying pattern.sh: %w", err)
 	}
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/Makefile-common", filepath.Join(repoRoot, "Makefile-common"), 0o644); err != nil {
-		return fmt.Errorf("error copying Makefile-common: %w", err)
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/Makefile-common", filepath.Join(repoRoot, "Makefile-common"), 0o644); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying Makefile-common: %w", err)
 	}
 
-	if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/ansible.cfg", filepath.Join(repoRoot, "ansible.cfg"), 0o644); err != nil {
-		return fmt.Errorf("error copying ansible.cfg: %w", err)
+	if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/ansible.cfg", filepath.Join(repoRoot, "ansible.cfg"), 0o644); err != nil {
+		return report.Errorf(report.CodeResourceCopy, "error copying ansible.cfg: %w", err)
 	}
 
 	makefileDst := filepath.Join(repoRoot, "Makefile")
 
-	if replaceMakefile {
-		if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/Makefile", makefileDst, 0o644); err != nil {
-			return fmt.Errorf("error replacing Makefile: %w", err)
+	if opts.replaceMakefile {
+		if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/Makefile", makefileDst, 0o644); err != nil {
+			return report.Errorf(report.CodeMakefile, "error replacing Makefile: %w", err)
 		}
 	} else {
 		if _, err := os.Stat(makefileDst); os.IsNotExist(err) {
-			if err := fileutils.WriteEmbeddedFile(embedded.Resources, "resources/Makefile", makefileDst, 0o644); err != nil {
-				return fmt.Errorf("error copying Makefile: %w", err)
+			if err := fileutils.WriteEmbeddedFile(resourceSet, "resources/Makefile", makefileDst, 0o644); err != nil {
+				return report.Errorf(report.CodeMakefile, "error copying Makefile: %w", err)
 			}
 		} else if err == nil {
-			hasInclude, err := fileutils.FileContainsIncludeMakefileCommon(makefileDst)
+			if err := addMakefileInclude(repoRoot, rep); err != nil {
+				return err
+			}
+			collisions, err := makefileCollisions(repoRoot)
 			if err != nil {
-				return fmt.Errorf("error checking Makefile for include: %w", err)
+				return err
 			}
-			if !hasInclude {
-				if err := fileutils.PrependLineToFile(makefileDst, "include Makefile-common"); err != nil {
-					return fmt.Errorf("error updating Makefile: %w", err)
-				}
+			for _, collision := range collisions {
+				rep.Warnf(report.CodeMakefileTarget, "%s", collision)
 			}
 		} else {
-			return fmt.Errorf("error accessing Makefile: %w", err)
+			return report.Errorf(report.CodeMakefile, "error accessing Makefile: %w", err)
 		}
 	}
 
-	if err := fileutils.InstallSkills(repoRoot); err != nil {
-		return fmt.Errorf("error installing skills: %w", err)
+	if err := installSkills(repoRoot, skillTargets, skillBundles, opts.force, rep); err != nil {
+		return err
+	}
+
+	if err := stampMetadata(repoRoot, resourceVersion); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully upgraded pattern repository in %s\n", repoRoot)
+	rep.Infof("Successfully upgraded pattern repository in %s to resource version %s", repoRoot, resourceVersion)
 	return nil
 }
@@ -10,6 +10,9 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
+
+	"github.com/validatedpatterns/patternizer/internal/makefile"
+	"github.com/validatedpatterns/patternizer/internal/report"
 )
 
 func cloneMCGWithCommon(dir string) {
@@ -64,9 +67,11 @@ var _ = Describe("patternizer upgrade", func() {
 		It("should inject the include for Makefile-common into the existing Makefile", func() {
 			f, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
 			Expect(err).NotTo(HaveOccurred())
-			actualMakefile := string(f)
-			Expect(strings.Contains(actualMakefile, oldMakefile)).To(BeTrue(), "Could not find contents of existing Makefile in updated Makefile")
-			Expect(strings.Contains(actualMakefile, "include Makefile-common\n")).To(BeTrue(), "Could not find include for Makefile-common in updated Makefile")
+			// The include is inserted before the first rule, which need not
+			// be at the top, and every existing line is kept.
+			expected, _ := makefile.InsertInclude([]byte(oldMakefile), "Makefile-common")
+			Expect(string(f)).To(Equal(string(expected)))
+			Expect(string(f)).To(ContainSubstring("include Makefile-common\n"))
 		})
 
 		It("should remove the old common directory", func() {
@@ -85,7 +90,8 @@ var _ = Describe("patternizer upgrade", func() {
 			f, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
 			Expect(err).NotTo(HaveOccurred())
 			expectedMakefile = string(f)
-			_ = runCLI(tempDir, "upgrade")
+			// The first upgrade left uncommitted changes.
+			_ = runCLI(tempDir, "upgrade", "--allow-dirty")
 		})
 
 		It("should not update Makefiles that already include Makefile-common", func() {
@@ -135,3 +141,77 @@ var _ = Describe("patternizer upgrade --replace-makefile", func() {
 		})
 	})
 })
+
+var _ = Describe("patternizer upgrade of a custom Makefile", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = createTestDir()
+		_ = runCLI(tempDir, "init")
+	})
+
+	It("should insert the include after the variables and before the first rule", func() {
+		custom := "# My pattern\nTARGET_ORIGIN ?= upstream\n\n# Builds the docs\ndocs:\n\t@echo docs\n\ninstall:\n\t@echo custom install\nshow:\n\t@echo mine\n"
+		Expect(os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte(custom), 0o644)).To(Succeed())
+
+		session := runCLI(tempDir, "upgrade")
+		output := string(session.Out.Contents())
+		Expect(output).To(ContainSubstring("added 'include Makefile-common' to the existing Makefile at line 4"))
+		Expect(output).To(ContainSubstring("make without a target now runs 'help' from Makefile-common instead of 'docs'"))
+		Expect(output).To(ContainSubstring("the Makefile target 'install' (line 10) also runs the prerequisites Makefile-common gives it"))
+		Expect(output).To(ContainSubstring("the Makefile target 'show' (line 12) overrides the one of Makefile-common"))
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("# My pattern\nTARGET_ORIGIN ?= upstream\n\ninclude Makefile-common\n\n" + strings.TrimPrefix(custom, "# My pattern\nTARGET_ORIGIN ?= upstream\n\n")))
+	})
+
+	It("should warn about targets that Makefile-common replaces", func() {
+		custom := "show:\n\t@echo mine\n\ninclude Makefile-common\n"
+		Expect(os.WriteFile(filepath.Join(tempDir, "Makefile"), []byte(custom), 0o644)).To(Succeed())
+
+		session := runCLI(tempDir, "upgrade")
+		Expect(string(session.Out.Contents())).To(ContainSubstring("the Makefile target 'show' (line 1) comes before 'include Makefile-common', whose recipe replaces it"))
+		data, err := os.ReadFile(filepath.Join(tempDir, "Makefile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(custom))
+	})
+})
+
+var _ = Describe("patternizer upgrade of a common/ directory with custom files", func() {
+	It("should preserve the files that are not part of a known upstream release", func() {
+		tempDir := createTestDir()
+		_ = runCLI(tempDir, "init")
+		for name, content := range map[string]string{
+			"common/Makefile":                "operator-deploy:\n",
+			"common/scripts/pattern-util.sh": "#!/bin/sh\n",
+			"common/scripts/our-backup.sh":   "#!/bin/sh\necho backup\n",
+		} {
+			path := filepath.Join(tempDir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		}
+
+		rep := runCLIJSON(tempDir, "upgrade")
+		var warnings []string
+		for _, warning := range rep.Warnings {
+			warnings = append(warnings, warning.Message)
+		}
+		Expect(warnings).To(ContainElements(
+			"moved common/scripts/our-backup.sh, which is not part of a known upstream common/ release, to .patternizer/preserved/common/scripts/our-backup.sh",
+			"removing legacy common/ directory with 2 upstream files",
+		))
+		change, ok := findFileChange(rep, "common/Makefile")
+		Expect(ok).To(BeTrue())
+		Expect(change.Action).To(Equal(report.ActionDeleted))
+		change, ok = findFileChange(rep, ".patternizer/preserved/common/scripts/our-backup.sh")
+		Expect(ok).To(BeTrue())
+		Expect(change.Action).To(Equal(report.ActionCreated))
+
+		data, err := os.ReadFile(filepath.Join(tempDir, ".patternizer", "preserved", "common", "scripts", "our-backup.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("#!/bin/sh\necho backup\n"))
+		_, err = os.Stat(filepath.Join(tempDir, "common"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+	})
+})
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+// runVersion prints the version of the binary and of every embedded resource and skill.
+func runVersion(out io.Writer, format string) error {
+	info, err := version.GetWithAssets()
+	if err != nil {
+		return fmt.Errorf("error collecting version information: %w", err)
+	}
+
+	if format == report.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Version:\t%s\n", info.Version)
+	fmt.Fprintf(w, "Commit:\t%s\n", info.Commit)
+	fmt.Fprintf(w, "Build date:\t%s\n", info.BuildDate)
+	fmt.Fprintf(w, "Go version:\t%s\n", info.GoVersion)
+	fmt.Fprintf(w, "Platform:\t%s\n", info.Platform)
+	fmt.Fprintln(w, "\nEmbedded resources:")
+	for _, asset := range info.Resources {
+		fmt.Fprintf(w, "  %s\t%s\n", asset.Name, asset.SHA256)
+	}
+	fmt.Fprintln(w, "\nEmbedded skills:")
+	for _, asset := range info.Skills {
+		name := asset.Name
+		if asset.Version != "" {
+			name += " (" + asset.Version + ")"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", name, asset.SHA256)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,32 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+var _ = Describe("patternizer version", func() {
+	It("should report build metadata and embedded asset hashes as JSON", func() {
+		session := runCLI(createTestDir(), "version", "--output", "json")
+
+		var info version.Info
+		Expect(json.Unmarshal(session.Out.Contents(), &info)).To(Succeed())
+		Expect(info.Version).NotTo(BeEmpty())
+		Expect(info.Commit).NotTo(BeEmpty())
+		Expect(info.Resources).NotTo(BeEmpty())
+		Expect(info.Skills).NotTo(BeEmpty())
+		for _, asset := range append(info.Resources, info.Skills...) {
+			Expect(asset.SHA256).To(HaveLen(64))
+		}
+	})
+
+	It("should use the version injected via ldflags", func() {
+		session := runBinaryWithExitCode(buildVersionedBinary("v9.8.7"), createTestDir(), 0, "--version")
+		Expect(strings.TrimSpace(string(session.Out.Contents()))).To(HavePrefix("patternizer version v9.8.7 (commit cafe,"))
+	})
+})
@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+)
+
+// Dir is the directory, relative to the repository root, that holds the backups.
+var Dir = filepath.Join(metadata.Dir, "backups")
+
+// manifestName is the name of the manifest inside a backup directory; the
+// backed up files are kept next to it under filesDir.
+const (
+	manifestName = "backup.yaml"
+	filesDir     = "files"
+)
+
+// idFormat is the layout of the timestamp that names a backup.
+const idFormat = "20060102T150405Z"
+
+// File is a file saved in a backup.
+type File struct {
+	// Path is the slash-separated path relative to the repository root.
+	Path string      `yaml:"path"`
+	Mode fs.FileMode `yaml:"mode,omitempty"`
+	// Link is the target of a symlink.
+	Link string `yaml:"link,omitempty"`
+}
+
+// Backup is a snapshot of the files a command was about to change.
+type Backup struct {
+	ID      string    `yaml:"-"`
+	Command string    `yaml:"command"`
+	Time    time.Time `yaml:"time"`
+	Files   []File    `yaml:"files"`
+	// Created lists the files the command created, which a restore deletes.
+	Created []string `yaml:"created,omitempty"`
+
+	dir string
+}
+
+// Create copies the files under paths, which are relative to repoRoot, into
+// a new backup named after now.
+func Create(repoRoot, command string, paths []string, now time.Time) (*Backup, error) {
+	now = now.UTC().Truncate(time.Second)
+	b := &Backup{Command: command, Time: now}
+	root := filepath.Join(repoRoot, Dir)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", root, err)
+	}
+	// Backups are local to the working tree and should not be committed.
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Join(root, ".gitignore"), err)
+	}
+	// Backups made within the same second get a numeric suffix.
+	for i := 0; ; i++ {
+		b.ID = now.Format(idFormat)
+		if i > 0 {
+			b.ID = fmt.Sprintf("%s-%d", b.ID, i)
+		}
+		b.dir = filepath.Join(root, b.ID)
+		err := os.Mkdir(b.dir, 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create %s: %w", b.dir, err)
+		}
+	}
+
+	for _, p := range paths {
+		start := filepath.Join(repoRoot, p)
+		if _, err := os.Lstat(start); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(repoRoot, path)
+			if err != nil {
+				return err
+			}
+			entry := File{Path: filepath.ToSlash(rel)}
+			if d.Type()&fs.ModeSymlink != 0 {
+				if entry.Link, err = os.Readlink(path); err != nil {
+					return err
+				}
+				b.Files = append(b.Files, entry)
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			entry.Mode = info.Mode().Perm()
+			dst := filepath.Join(b.dir, filesDir, rel)
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return err
+			}
+			if err := fileutils.CopyFile(path, dst); err != nil {
+				return err
+			}
+			b.Files = append(b.Files, entry)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", p, err)
+		}
+	}
+	if err := b.save(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// RecordCreated records the files, relative to the repository root, that the
+// command created after the backup was made.
+func (b *Backup) RecordCreated(created []string) error {
+	b.Created = append(b.Created, created...)
+	return b.save()
+}
+
+// Paths returns the paths, relative to the repository root, that a restore
+// of the backup changes.
+func (b *Backup) Paths() []string {
+	paths := make([]string, 0, len(b.Files)+len(b.Created))
+	for _, entry := range b.Files {
+		paths = append(paths, filepath.FromSlash(entry.Path))
+	}
+	for _, created := range b.Created {
+		paths = append(paths, filepath.FromSlash(created))
+	}
+	return paths
+}
+
+func (b *Backup) save() error {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, manifestName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// List returns the backups of repoRoot, oldest first.
+func List(repoRoot string) ([]*Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(repoRoot, Dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var backups []*Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		b, err := Load(repoRoot, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.Before(backups[j].Time)
+		}
+		return backups[i].ID < backups[j].ID
+	})
+	return backups, nil
+}
+
+// Load reads the backup of repoRoot with the given ID.
+func Load(repoRoot, id string) (*Backup, error) {
+	if id == "" || filepath.Base(id) != id || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid backup ID %q", id)
+	}
+	dir := filepath.Join(repoRoot, Dir, id)
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", id, err)
+	}
+	b := &Backup{}
+	if err := yaml.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("failed to parse backup %s: %w", id, err)
+	}
+	b.ID = id
+	b.dir = dir
+	return b, nil
+}
+
+// Restore puts the files of the backup back into repoRoot and deletes the
+// files the command created. Regular files are restored all at once with
+// fileutils.ApplyEdits; symlinks are recreated afterwards.
+func (b *Backup) Restore(repoRoot string) error {
+	var edits []fileutils.Edit
+	for _, entry := range b.Files {
+		if entry.Link != "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(b.dir, filesDir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s from backup %s: %w", entry.Path, b.ID, err)
+		}
+		edits = append(edits, fileutils.Edit{Path: filepath.Join(repoRoot, filepath.FromSlash(entry.Path)), Content: content, Mode: entry.Mode})
+	}
+	for _, created := range b.Created {
+		edits = append(edits, fileutils.Edit{Path: filepath.Join(repoRoot, filepath.FromSlash(created)), Delete: true})
+	}
+	if err := fileutils.ApplyEdits(edits); err != nil {
+		return err
+	}
+
+	for _, entry := range b.Files {
+		if entry.Link == "" {
+			continue
+		}
+		path := filepath.Join(repoRoot, filepath.FromSlash(entry.Path))
+		if err := fileutils.RemovePathIfExists(path); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.Symlink(entry.Link, path); err != nil {
+			return fmt.Errorf("failed to restore symlink %s: %w", entry.Path, err)
+		}
+	}
+	return nil
+}
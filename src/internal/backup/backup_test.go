@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backup", func() {
+	var repoRoot string
+	now := time.Date(2026, 10, 18, 20, 48, 54, 0, time.UTC)
+
+	write := func(name, content string) {
+		path := filepath.Join(repoRoot, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(repoRoot, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		write("Makefile", "all:\n")
+		write("common/scripts/test.sh", "#!/bin/sh\n")
+		Expect(os.Symlink("common/scripts/pattern-util.sh", filepath.Join(repoRoot, "pattern.sh"))).To(Succeed())
+	})
+
+	It("should restore changed, deleted and created files", func() {
+		b, err := Create(repoRoot, "upgrade", []string{"Makefile", "common", "pattern.sh", "values-global.yaml"}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.ID).To(Equal("20261018T204854Z"))
+		Expect(b.Files).To(ConsistOf(
+			File{Path: "Makefile", Mode: 0o644},
+			File{Path: "common/scripts/test.sh", Mode: 0o644},
+			File{Path: "pattern.sh", Link: "common/scripts/pattern-util.sh"},
+		))
+
+		write("Makefile", "include Makefile-common\nall:\n")
+		Expect(os.RemoveAll(filepath.Join(repoRoot, "common"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(repoRoot, "pattern.sh"))).To(Succeed())
+		write("pattern.sh", "#!/bin/sh\n")
+		write("values-global.yaml", "global: {}\n")
+		Expect(b.RecordCreated([]string{"values-global.yaml"})).To(Succeed())
+
+		loaded, err := Load(repoRoot, b.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Command).To(Equal("upgrade"))
+		Expect(loaded.Time).To(BeTemporally("==", now))
+		Expect(loaded.Created).To(Equal([]string{"values-global.yaml"}))
+		Expect(loaded.Restore(repoRoot)).To(Succeed())
+
+		Expect(read("Makefile")).To(Equal("all:\n"))
+		Expect(read("common/scripts/test.sh")).To(Equal("#!/bin/sh\n"))
+		target, err := os.Readlink(filepath.Join(repoRoot, "pattern.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal("common/scripts/pattern-util.sh"))
+		Expect(filepath.Join(repoRoot, "values-global.yaml")).NotTo(BeAnExistingFile())
+	})
+
+	It("should list the backups oldest first and keep them out of git", func() {
+		second, err := Create(repoRoot, "upgrade", []string{"Makefile"}, now)
+		Expect(err).NotTo(HaveOccurred())
+		third, err := Create(repoRoot, "upgrade", []string{"Makefile"}, now)
+		Expect(err).NotTo(HaveOccurred())
+		first, err := Create(repoRoot, "init", []string{"Makefile"}, now.Add(-time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third.ID).To(Equal("20261018T204854Z-1"))
+
+		backups, err := List(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		var ids []string
+		for _, b := range backups {
+			ids = append(ids, b.ID)
+		}
+		Expect(ids).To(Equal([]string{first.ID, second.ID, third.ID}))
+		Expect(read(filepath.Join(Dir, ".gitignore"))).To(Equal("*\n"))
+	})
+
+	It("should reject unknown and invalid backup IDs", func() {
+		_, err := Load(repoRoot, "20200101T000000Z")
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		_, err = Load(repoRoot, "../..")
+		Expect(err).To(MatchError(ContainSubstring("invalid backup ID")))
+	})
+
+	It("should report no backups before the first one", func() {
+		backups, err := List(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backups).To(BeEmpty())
+	})
+})
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/resources"
+)
+
+// FileName is the name of the repository configuration file inside metadata.Dir.
+const FileName = "config.yaml"
+
+// RelPath is the path of the configuration file relative to the repository root.
+var RelPath = filepath.Join(metadata.Dir, FileName)
+
+// Config holds per-repository patternizer settings. Unlike the metadata file,
+// it is written by users and never modified by patternizer.
+type Config struct {
+	// ResourcesDir is a directory or tarball whose files override the embedded
+	// resources. Relative paths are resolved against the repository root.
+	ResourcesDir string `yaml:"resourcesDir,omitempty"`
+	// UtilityContainer is the utility container image that the generated
+	// pattern.sh runs unless PATTERN_UTILITY_CONTAINER is set.
+	UtilityContainer string `yaml:"utilityContainer,omitempty"`
+	// DisconnectedHome is the registry that the generated pattern.sh uses
+	// unless PATTERN_DISCONNECTED_HOME is set.
+	DisconnectedHome string `yaml:"disconnectedHome,omitempty"`
+	// ContainerRuntime selects the container runtime of the generated
+	// pattern.sh and of patternizer run: podman, docker or auto.
+	ContainerRuntime string `yaml:"containerRuntime,omitempty"`
+	// Skills selects the AI tools that init and upgrade install skills for:
+	// none, all or a list of fileutils.SkillTargets names. Unset means
+	// fileutils.DefaultSkillTargets; an empty list means none.
+	Skills StringList `yaml:"skills,omitempty"`
+	// SkillSources are directories or tarballs with additional skills that
+	// are installed alongside the embedded ones. Relative paths are resolved
+	// against the repository root.
+	SkillSources StringList `yaml:"skillSources,omitempty"`
+}
+
+// StringList is a list of strings that can also be written as a single
+// scalar.
+type StringList []string
+
+// UnmarshalYAML accepts a sequence or a scalar.
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	items := []string{}
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+// imageRefPattern matches the characters allowed in image references and
+// registry paths. Values are embedded in generated shell scripts, so anything
+// that could need quoting is rejected.
+var imageRefPattern = regexp.MustCompile(`^[A-Za-z0-9._:/@-]*$`)
+
+// Path returns the absolute path of the configuration file for repoRoot.
+func Path(repoRoot string) string {
+	return filepath.Join(repoRoot, RelPath)
+}
+
+// Load reads the configuration file of repoRoot. It returns an empty Config if
+// the file does not exist.
+func Load(repoRoot string) (*Config, error) {
+	configPath := Path(repoRoot)
+	c := &Config{}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	// Reject unknown keys so that typos do not silently disable a setting.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configPath, err)
+	}
+	return c, nil
+}
+
+// validate checks the settings that are rendered into generated files.
+func (c *Config) validate() error {
+	if !imageRefPattern.MatchString(c.UtilityContainer) {
+		return fmt.Errorf("utilityContainer %q is not a valid image reference", c.UtilityContainer)
+	}
+	if !imageRefPattern.MatchString(c.DisconnectedHome) {
+		return fmt.Errorf("disconnectedHome %q is not a valid registry path", c.DisconnectedHome)
+	}
+	if c.ContainerRuntime != "" && !slices.Contains(resources.ContainerRuntimes, c.ContainerRuntime) {
+		return fmt.Errorf("containerRuntime %q is not one of: %s", c.ContainerRuntime, strings.Join(resources.ContainerRuntimes, ", "))
+	}
+	if len(c.Skills) > 0 {
+		if _, err := fileutils.ParseSkillTargets(c.Skills); err != nil {
+			return fmt.Errorf("skills: %w", err)
+		}
+	}
+	for _, source := range c.SkillSources {
+		if strings.TrimSpace(source) == "" {
+			return errors.New("skillSources: empty path")
+		}
+	}
+	return nil
+}
+
+// SkillTargets returns the skill targets the configuration selects.
+func (c *Config) SkillTargets() []string {
+	if c.Skills == nil {
+		return fileutils.DefaultSkillTargets
+	}
+	// An empty list selects no target, like none.
+	if len(c.Skills) == 0 {
+		return []string{}
+	}
+	// Validated by Load.
+	targets, _ := fileutils.ParseSkillTargets(c.Skills)
+	return targets
+}
+
+// ResolvePath resolves a path from the configuration file relative to repoRoot.
+func ResolvePath(repoRoot, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(repoRoot, p)
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeConfig(repoRoot, content string) {
+	Expect(os.MkdirAll(filepath.Dir(Path(repoRoot)), 0o755)).To(Succeed())
+	Expect(os.WriteFile(Path(repoRoot), []byte(content), 0o644)).To(Succeed())
+}
+
+var _ = Describe("Load", func() {
+	It("should return an empty configuration when the file does not exist", func() {
+		c, err := Load(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*c).To(Equal(Config{}))
+	})
+
+	It("should accept an empty file", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "")
+
+		c, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*c).To(Equal(Config{}))
+	})
+
+	It("should read the settings", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "resourcesDir: ../org-resources\n")
+
+		c, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.ResourcesDir).To(Equal("../org-resources"))
+	})
+
+	It("should reject unknown settings", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "resourceDir: typo\n")
+
+		_, err := Load(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("resourceDir")))
+	})
+})
+
+var _ = Describe("ResolvePath", func() {
+	It("should resolve relative paths against the repository root", func() {
+		Expect(ResolvePath("/repo", "org")).To(Equal(filepath.Join("/repo", "org")))
+		Expect(ResolvePath("/repo", "/abs/org")).To(Equal("/abs/org"))
+		Expect(ResolvePath("/repo", "")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Load template settings", func() {
+	It("should read the utility container and disconnected home", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "utilityContainer: registry.example.com/utility-container:v1\ndisconnectedHome: registry.example.com/mirror\n")
+
+		c, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.UtilityContainer).To(Equal("registry.example.com/utility-container:v1"))
+		Expect(c.DisconnectedHome).To(Equal("registry.example.com/mirror"))
+	})
+
+	It("should reject values that are unsafe in a shell script", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "utilityContainer: 'quay.io/x\"; rm -rf ~'\n")
+
+		_, err := Load(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("not a valid image reference")))
+	})
+
+	It("should validate the container runtime", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "containerRuntime: docker\n")
+		c, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.ContainerRuntime).To(Equal("docker"))
+
+		writeConfig(repoRoot, "containerRuntime: lxc\n")
+		_, err = Load(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("containerRuntime \"lxc\" is not one of")))
+	})
+})
+
+var _ = Describe("Load skills", func() {
+	DescribeTable("should select the skill targets",
+		func(content string, expected []string) {
+			repoRoot := GinkgoT().TempDir()
+			writeConfig(repoRoot, content)
+			c, err := Load(repoRoot)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.SkillTargets()).To(Equal(expected))
+		},
+		Entry("by default", "", []string{"claude", "cursor"}),
+		Entry("as a list", "skills: [github, agents]\n", []string{"agents", "github"}),
+		Entry("as a comma-separated scalar", "skills: windsurf,claude\n", []string{"claude", "windsurf"}),
+		Entry("none", "skills: none\n", []string{}),
+		Entry("an empty list", "skills: []\n", []string{}),
+		Entry("all", "skills: all\n", []string{"agents", "claude", "cursor", "github", "windsurf"}),
+	)
+
+	It("should reject unknown targets", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "skills: [claude, vim]\n")
+		_, err := Load(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("unknown skill target \"vim\"")))
+	})
+
+	It("should read the skill sources as a list or a single path", func() {
+		repoRoot := GinkgoT().TempDir()
+		writeConfig(repoRoot, "skillSources: [skills/org, /opt/skills.tgz]\n")
+		c, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.SkillSources).To(Equal(StringList{"skills/org", "/opt/skills.tgz"}))
+
+		writeConfig(repoRoot, "skillSources: skills/org\n")
+		c, err = Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.SkillSources).To(Equal(StringList{"skills/org"}))
+
+		writeConfig(repoRoot, "skillSources: ['']\n")
+		_, err = Load(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("skillSources: empty path")))
+	})
+})
@@ -2,12 +2,27 @@ package embedded
 
 import "embed"
 
+// ResourceVersion identifies the set of files in Resources. Bump it whenever a
+// file in resources/ changes, after copying the previous set into history/.
+const ResourceVersion = "v2.3.0"
+
 // Resources holds the embedded resources directory tree (pattern.sh, Makefile, etc.).
 //
 //go:embed resources/*
 var Resources embed.FS
 
+// History holds previously released resource sets under history/<version>/resources/.
+//
+//go:embed all:history
+var History embed.FS
+
 // Skills holds the embedded skills directory tree for IDE integrations.
 //
 //go:embed all:skills
 var Skills embed.FS
+
+// Common holds the file digests of the upstream common repository releases
+// under common/<release>.sha256.
+//
+//go:embed common
+var Common embed.FS
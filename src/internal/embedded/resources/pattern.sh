@@ -23,8 +23,13 @@ if is_container; then
 fi
 
 if [ -z "${PATTERN_UTILITY_CONTAINER:-}" ]; then
-	PATTERN_UTILITY_CONTAINER="quay.io/validatedpatterns/utility-container"
+	PATTERN_UTILITY_CONTAINER="{{ .UtilityContainer }}"
 fi
+{{- if .DisconnectedHome }}
+if [ -z "${PATTERN_DISCONNECTED_HOME:-}" ]; then
+    PATTERN_DISCONNECTED_HOME="{{ .DisconnectedHome }}"
+fi
+{{- end }}
 # If PATTERN_DISCONNECTED_HOME is set it will be used to populate both PATTERN_UTILITY_CONTAINER
 # and PATTERN_INSTALL_CHART automatically
 if [ -n "${PATTERN_DISCONNECTED_HOME:-}" ]; then
@@ -36,34 +41,96 @@ if [ -n "${PATTERN_DISCONNECTED_HOME:-}" ]; then
     echo "  PATTERN_INSTALL_CHART: ${PATTERN_INSTALL_CHART}"
 fi
 
-readonly commands=(podman)
-for cmd in "${commands[@]}"; do is_available "$cmd"; done
-
-UNSUPPORTED_PODMAN_VERSIONS="1.6 1.5"
-PODMAN_VERSION_STR=$(podman --version) || { echo "Failed to get podman version"; exit 1; }
-for i in ${UNSUPPORTED_PODMAN_VERSIONS}; do
-	# We add a space
-	if echo "${PODMAN_VERSION_STR}" | grep -q -E "\b${i}"; then
-		echo "Unsupported podman version. We recommend > 4.3.0"
-		podman --version
-		exit 1
-	fi
-done
-
-# podman --version outputs:
-# podman version 4.8.2
-PODMAN_VERSION=$(echo "${PODMAN_VERSION_STR}" | awk '{ print $NF }')
-
-# podman < 4.3.0 do not support keep-id:uid=...
-PODMAN_ARGS=()
-if [ "$(version "${PODMAN_VERSION}")" -lt "$(version "4.3.0")" ]; then
-    PODMAN_ARGS=(-v "${HOME}:/root")
+{{ if eq .ContainerRuntime "auto" -}}
+# Prefer podman and fall back to docker. PATTERN_CONTAINER_RUNTIME selects one explicitly.
+CONTAINER_RUNTIME="${PATTERN_CONTAINER_RUNTIME:-}"
+if [ -z "${CONTAINER_RUNTIME}" ]; then
+    if command -v podman >/dev/null 2>&1; then
+        CONTAINER_RUNTIME=podman
+    elif command -v docker >/dev/null 2>&1; then
+        CONTAINER_RUNTIME=docker
+    else
+        echo >&2 "podman or docker is required but neither is installed. Aborting."
+        exit 1
+    fi
+fi
+{{ else -}}
+CONTAINER_RUNTIME={{ .ContainerRuntime }}
+{{ end -}}
+is_available "${CONTAINER_RUNTIME}"
+
+# Environment variables passed through to the container. Entries ending in *
+# pass every variable with that prefix.
+PASSTHROUGH_ENV=(
+{{- range .EnvPassthrough }}
+    '{{ . }}'
+{{- end }}
+)
+
+# We do not rely on bash's $UID and $GID because on MacOSX $GID is not set
+MYNAME=$(id -n -u)
+MYUID=$(id -u)
+MYGID=$(id -g)
+
+RUNTIME_ARGS=()
+ENV_ARGS=()
+REMOTE_RUNTIME=0
+if [ "${CONTAINER_RUNTIME}" = "podman" ]; then
+    UNSUPPORTED_PODMAN_VERSIONS="1.6 1.5"
+    PODMAN_VERSION_STR=$(podman --version) || { echo "Failed to get podman version"; exit 1; }
+    for i in ${UNSUPPORTED_PODMAN_VERSIONS}; do
+        # We add a space
+        if echo "${PODMAN_VERSION_STR}" | grep -q -E "\b${i}"; then
+            echo "Unsupported podman version. We recommend > 4.3.0"
+            podman --version
+            exit 1
+        fi
+    done
+
+    # podman --version outputs:
+    # podman version 4.8.2
+    PODMAN_VERSION=$(echo "${PODMAN_VERSION_STR}" | awk '{ print $NF }')
+
+    # podman < 4.3.0 do not support keep-id:uid=...
+    if [ "$(version "${PODMAN_VERSION}")" -lt "$(version "4.3.0")" ]; then
+        RUNTIME_ARGS=(-v "${HOME}:/root")
+    else
+        RUNTIME_ARGS=(--passwd-entry "${MYNAME}:x:${MYUID}:${MYGID}::/pattern-home:/bin/bash" --user "${MYUID}:${MYGID}" --userns "keep-id:uid=${MYUID},gid=${MYGID}")
+    fi
+
+    # podman expands the wildcards itself
+    for name in "${PASSTHROUGH_ENV[@]}"; do
+        ENV_ARGS+=(-e "${name}")
+    done
+    PULL_POLICY=newer
+
+    # Detect if we use podman machine, in which case the host ssl folders are not visible
+    REMOTE_RUNTIME=$(podman system connection list | tail -n +2 | wc -l) || REMOTE_RUNTIME=0
 else
-    # We do not rely on bash's $UID and $GID because on MacOSX $GID is not set
-    MYNAME=$(id -n -u)
-    MYUID=$(id -u)
-    MYGID=$(id -g)
-    PODMAN_ARGS=(--passwd-entry "${MYNAME}:x:${MYUID}:${MYGID}::/pattern-home:/bin/bash" --user "${MYUID}:${MYGID}" --userns "keep-id:uid=${MYUID},gid=${MYGID}")
+    # docker has no keep-id user namespace: run as the host user with HOME
+    # pointing at the bind-mounted home folder
+    RUNTIME_ARGS=(--user "${MYUID}:${MYGID}" -e HOME=/pattern-home)
+
+    # docker does not support wildcards in -e, so pass every matching variable by name
+    for name in "${PASSTHROUGH_ENV[@]}"; do
+        if [[ "${name}" != *"*" ]]; then
+            ENV_ARGS+=(-e "${name}")
+            continue
+        fi
+        for var in $(compgen -e); do
+            if [[ "${var}" == "${name%"*"}"* ]]; then
+                ENV_ARGS+=(-e "${var}")
+            fi
+        done
+    done
+
+    # docker has no "newer" pull policy, "always" only downloads changed layers
+    PULL_POLICY=always
+
+    # A non-local DOCKER_HOST (e.g. a remote engine) cannot see the host ssl folders
+    if [ -n "${DOCKER_HOST:-}" ] && [[ "${DOCKER_HOST}" != unix://* ]]; then
+        REMOTE_RUNTIME=1
+    fi
 fi
 
 if [ -n "${KUBECONFIG:-}" ]; then
@@ -75,11 +142,9 @@ if [ -n "${KUBECONFIG:-}" ]; then
     fi
 fi
 
-# Detect if we use podman machine. If we do not then we bind mount local host ssl folders
-# if we are using podman machine then we do not bind mount anything (for now!)
-REMOTE_PODMAN=$(podman system connection list | tail -n +2 | wc -l) || REMOTE_PODMAN=0
+# If the container runtime is local we bind mount the host ssl folders
 PKI_HOST_MOUNT_ARGS=()
-if [ "${REMOTE_PODMAN}" -eq 0 ]; then # If we are not using podman machine we check the hosts folders
+if [ "${REMOTE_RUNTIME}" -eq 0 ]; then
     # We check /etc/pki/tls because on ubuntu /etc/pki/fwupd sometimes
     # exists but not /etc/pki/tls and we do not want to bind mount in such a case
     # as it would find no certificates at all.
@@ -99,41 +164,17 @@ if [ -n "${EXTRA_ARGS:-}" ]; then
     EXTRA_ARGS_ARRAY=(${EXTRA_ARGS})
 fi
 
-# Copy Kubeconfig from current environment. The utilities will pick up ~/.kube/config if set so it's not mandatory
 # $HOME is mounted as itself for any files that are referenced with absolute paths
-# $HOME is mounted to /root because the UID in the container is 0 and that's where SSH looks for credentials
+# $HOME is mounted to /pattern-home, which is the home folder of the user in the container
 
-podman run -it --rm --pull=newer \
+"${CONTAINER_RUNTIME}" run -it --rm --pull="${PULL_POLICY}" \
     --security-opt label=disable \
-    -e ANSIBLE_STDOUT_CALLBACK \
-    -e DISABLE_VALIDATE_ORIGIN \
-    -e EXTRA_HELM_OPTS \
-    -e EXTRA_PLAYBOOK_OPTS \
-    -e K8S_AUTH_HOST \
-    -e K8S_AUTH_PASSWORD \
-    -e K8S_AUTH_SSL_CA_CERT \
-    -e K8S_AUTH_TOKEN \
-    -e K8S_AUTH_USERNAME \
-    -e K8S_AUTH_VERIFY_SSL \
-    -e KUBECONFIG \
-    -e PATTERN_DIR \
-    -e PATTERN_DISCONNECTED_HOME \
-    -e PATTERN_INSTALL_CHART \
-    -e PATTERN_NAME \
-    -e TARGET_BRANCH \
-    -e TARGET_CLUSTERGROUP \
-    -e TARGET_VARIANT \
-    -e TARGET_ORIGIN \
-    -e TOKEN_NAMESPACE \
-    -e TOKEN_SECRET \
-    -e UUID_FILE \
-    -e VALUES_SECRET \
-    -e 'VP_*' \
+    "${ENV_ARGS[@]}" \
     "${PKI_HOST_MOUNT_ARGS[@]}" \
     -v "$(pwd -P)":"$(pwd -P)" \
     -v "${HOME}":"${HOME}" \
     -v "${HOME}":/pattern-home \
-    "${PODMAN_ARGS[@]}" \
+    "${RUNTIME_ARGS[@]}" \
     "${EXTRA_ARGS_ARRAY[@]}" \
     -w "$(pwd -P)" \
     "$PATTERN_UTILITY_CONTAINER" \
@@ -0,0 +1,111 @@
+package fileutils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OpenDirOrTarball returns a filesystem for a local directory or a .tar, .tar.gz
+// or .tgz archive. Archives are extracted to a temporary directory which is
+// removed by the returned cleanup function. If an archive contains a single
+// top-level directory, the returned filesystem is rooted at that directory.
+func OpenDirOrTarball(path string) (fsys fs.FS, cleanup func(), err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() {}, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "patternizer-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+	cleanup = func() { _ = os.RemoveAll(tmpDir) }
+
+	if err := extractTarball(path, tmpDir); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	root := tmpDir
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("read %s: %w", tmpDir, err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(tmpDir, entries[0].Name())
+	}
+	return os.DirFS(root), cleanup, nil
+}
+
+// extractTarball extracts the regular files and directories of a possibly
+// gzip-compressed tar archive into dst, rejecting entries that escape dst.
+func extractTarball(archivePath, dst string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(archivePath, ".tar.gz"), strings.HasSuffix(archivePath, ".tgz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("open gzip stream %s: %w", archivePath, err)
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(archivePath, ".tar"):
+	default:
+		return fmt.Errorf("%s is neither a directory nor a .tar, .tar.gz or .tgz archive", archivePath)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", archivePath, err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive %s contains unsafe path %s", archivePath, header.Name)
+		}
+		target := filepath.Join(dst, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("create %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("create %s: %w", filepath.Dir(target), err)
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return fmt.Errorf("create %s: %w", target, err)
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return fmt.Errorf("extract %s: %w", header.Name, err)
+			}
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("close %s: %w", target, err)
+			}
+		}
+	}
+}
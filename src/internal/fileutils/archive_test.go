@@ -0,0 +1,80 @@
+package fileutils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeTarball(path string, files map[string]string) {
+	f, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for name, content := range files {
+		Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
+
+var _ = Describe("OpenDirOrTarball", func() {
+	It("should open a directory in place", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644)).To(Succeed())
+
+		fsys, cleanup, err := OpenDirOrTarball(dir)
+		Expect(err).NotTo(HaveOccurred())
+		defer cleanup()
+
+		data, err := fs.ReadFile(fsys, "a.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("a"))
+	})
+
+	It("should extract a gzipped tarball and descend into a single top-level directory", func() {
+		archive := filepath.Join(GinkgoT().TempDir(), "resources.tar.gz")
+		writeTarball(archive, map[string]string{"org/pattern.sh": "#!/bin/bash\n", "org/sub/b.txt": "b"})
+
+		fsys, cleanup, err := OpenDirOrTarball(archive)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := fs.ReadFile(fsys, "pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("#!/bin/bash\n"))
+
+		data, err = fs.ReadFile(fsys, "sub/b.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("b"))
+
+		cleanup()
+		_, err = fs.ReadFile(fsys, "pattern.sh")
+		Expect(err).To(HaveOccurred(), "cleanup should remove the extracted files")
+	})
+
+	It("should reject archives with paths outside the extraction directory", func() {
+		archive := filepath.Join(GinkgoT().TempDir(), "evil.tgz")
+		writeTarball(archive, map[string]string{"../evil.sh": "x"})
+
+		_, _, err := OpenDirOrTarball(archive)
+		Expect(err).To(MatchError(ContainSubstring("unsafe path")))
+	})
+
+	It("should reject files that are not archives", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "resources.zip")
+		Expect(os.WriteFile(file, []byte("x"), 0o644)).To(Succeed())
+
+		_, _, err := OpenDirOrTarball(file)
+		Expect(err).To(MatchError(ContainSubstring("neither a directory nor")))
+	})
+})
@@ -0,0 +1,128 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Edit is a change to a single file, applied together with other edits by ApplyEdits.
+type Edit struct {
+	// Path is the file to write or delete.
+	Path string
+	// Content is the new content of the file.
+	Content []byte
+	// Mode is the permission of a new file. Existing files keep their permissions.
+	Mode os.FileMode
+	// Delete removes the file instead of writing it.
+	Delete bool
+}
+
+// renameFile moves staged files into place. Tests replace it to simulate failures.
+var renameFile = os.Rename
+
+// original is the state of a file before ApplyEdits changed it.
+type original struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+	existed bool
+}
+
+// ApplyEdits applies all edits or none of them. New contents are staged in
+// temporary files next to their targets before any file is changed, and if
+// committing an edit fails, the files changed so far are restored.
+func ApplyEdits(edits []Edit) (err error) {
+	originals := make([]original, 0, len(edits))
+	staged := make([]string, len(edits))
+	defer func() {
+		for _, tmp := range staged {
+			if tmp != "" {
+				_ = os.Remove(tmp)
+			}
+		}
+	}()
+
+	for i, edit := range edits {
+		orig := original{path: edit.Path, mode: edit.Mode}
+		if info, statErr := os.Stat(edit.Path); statErr == nil {
+			orig.existed = true
+			orig.mode = info.Mode().Perm()
+			if orig.content, err = os.ReadFile(edit.Path); err != nil {
+				return fmt.Errorf("read %s: %w", edit.Path, err)
+			}
+		} else if !os.IsNotExist(statErr) {
+			return fmt.Errorf("stat %s: %w", edit.Path, statErr)
+		}
+		originals = append(originals, orig)
+
+		if edit.Delete {
+			continue
+		}
+		mode := orig.mode
+		if mode == 0 {
+			mode = 0o644
+		}
+		if staged[i], err = stageFile(edit.Path, edit.Content, mode); err != nil {
+			return err
+		}
+	}
+
+	for i, edit := range edits {
+		var commitErr error
+		if edit.Delete {
+			commitErr = RemovePathIfExists(edit.Path)
+		} else {
+			if commitErr = renameFile(staged[i], edit.Path); commitErr == nil {
+				staged[i] = ""
+			}
+		}
+		if commitErr != nil {
+			return errors.Join(fmt.Errorf("apply change to %s: %w", edit.Path, commitErr), restore(originals[:i+1]))
+		}
+	}
+	return nil
+}
+
+// stageFile writes content to a temporary file in the directory of path.
+func stageFile(path string, content []byte, mode os.FileMode) (string, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("create temporary file for %s: %w", path, err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("write temporary file for %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("close temporary file for %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("chmod temporary file for %s: %w", path, err)
+	}
+	return tmp.Name(), nil
+}
+
+// restore puts the given files back into their original state.
+func restore(originals []original) error {
+	var errs []error
+	for i := len(originals) - 1; i >= 0; i-- {
+		orig := originals[i]
+		if !orig.existed {
+			errs = append(errs, RemovePathIfExists(orig.path))
+			continue
+		}
+		if err := os.WriteFile(orig.path, orig.content, orig.mode); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s: %w", orig.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyEdits", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should write, create and delete files", func() {
+		existing := filepath.Join(dir, "existing.yaml")
+		writeFileWithMode(existing, "old\n", 0o600)
+		removed := filepath.Join(dir, "removed.yaml")
+		writeFileWithMode(removed, "gone\n", 0o644)
+		created := filepath.Join(dir, "sub", "created.yaml")
+
+		Expect(ApplyEdits([]Edit{
+			{Path: existing, Content: []byte("new\n")},
+			{Path: created, Content: []byte("created\n"), Mode: 0o640},
+			{Path: removed, Delete: true},
+		})).To(Succeed())
+
+		Expect(os.ReadFile(existing)).To(Equal([]byte("new\n")))
+		info, err := os.Stat(existing)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+		Expect(os.ReadFile(created)).To(Equal([]byte("created\n")))
+		info, err = os.Stat(created)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+
+		Expect(removed).NotTo(BeAnExistingFile())
+		entries, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should restore every file when a change cannot be applied", func() {
+		first := filepath.Join(dir, "first.yaml")
+		writeFileWithMode(first, "first\n", 0o644)
+		removed := filepath.Join(dir, "removed.yaml")
+		writeFileWithMode(removed, "removed\n", 0o600)
+		created := filepath.Join(dir, "created.yaml")
+		last := filepath.Join(dir, "last.yaml")
+		writeFileWithMode(last, "last\n", 0o644)
+
+		original := renameFile
+		DeferCleanup(func() { renameFile = original })
+		renameFile = func(from, to string) error {
+			if to == last {
+				return errors.New("disk full")
+			}
+			return original(from, to)
+		}
+
+		err := ApplyEdits([]Edit{
+			{Path: first, Content: []byte("changed\n")},
+			{Path: created, Content: []byte("created\n")},
+			{Path: removed, Delete: true},
+			{Path: last, Content: []byte("changed\n")},
+		})
+		Expect(err).To(MatchError(ContainSubstring("disk full")))
+
+		Expect(os.ReadFile(first)).To(Equal([]byte("first\n")))
+		Expect(os.ReadFile(removed)).To(Equal([]byte("removed\n")))
+		info, err := os.Stat(removed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+		Expect(created).NotTo(BeAnExistingFile())
+		Expect(os.ReadFile(last)).To(Equal([]byte("last\n")))
+
+		entries, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should not change anything when a file cannot be staged", func() {
+		first := filepath.Join(dir, "first.yaml")
+		writeFileWithMode(first, "first\n", 0o644)
+		blocker := filepath.Join(dir, "blocker")
+		writeFileWithMode(blocker, "", 0o644)
+
+		err := ApplyEdits([]Edit{
+			{Path: first, Content: []byte("changed\n")},
+			{Path: filepath.Join(blocker, "nested.yaml"), Content: []byte("nested\n")},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(os.ReadFile(first)).To(Equal([]byte("first\n")))
+	})
+})
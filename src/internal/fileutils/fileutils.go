@@ -6,9 +6,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/makefile"
 )
 
 // CopyFile copies a file from src to dst. If dst already exists, it will be overwritten.
@@ -74,6 +75,8 @@ func WriteEmbeddedFile(fsys fs.FS, srcPath, dstPath string, mode os.FileMode) er
 }
 
 // WriteEmbeddedDir recursively copies an embedded directory tree to disk.
+// Files that are executable in fsys, such as scripts of a skill read from
+// disk, stay executable.
 func WriteEmbeddedDir(fsys fs.FS, srcDir, dstDir string) error {
 	return fs.WalkDir(fsys, srcDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -88,7 +91,11 @@ func WriteEmbeddedDir(fsys fs.FS, srcDir, dstDir string) error {
 		if d.IsDir() {
 			return os.MkdirAll(target, 0o755)
 		}
-		return WriteEmbeddedFile(fsys, path, target, 0o644)
+		mode := os.FileMode(0o644)
+		if info, err := d.Info(); err == nil && info.Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+		return WriteEmbeddedFile(fsys, path, target, mode)
 	})
 }
 
@@ -152,40 +159,14 @@ func RemovePathIfExists(targetPath string) error {
 	return os.Remove(targetPath)
 }
 
-// FileContainsIncludeMakefileCommon checks if a Makefile already contains an include Makefile-common line.
+// FileContainsIncludeMakefileCommon checks if a Makefile has an include,
+// -include or sinclude directive for Makefile-common.
 func FileContainsIncludeMakefileCommon(makefilePath string) (bool, error) {
 	data, err := os.ReadFile(makefilePath)
 	if err != nil {
 		return false, fmt.Errorf("read %s: %w", makefilePath, err)
 	}
-	contents := string(data)
-	// We keep this simple to avoid regex: look for lines with 'include' and 'Makefile-common'
-	for _, line := range strings.Split(contents, "\n") {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-		if strings.Contains(trimmed, "include") && strings.Contains(trimmed, "Makefile-common") {
-			return true, nil
-		}
-	}
-	return false, nil
-}
-
-// PrependLineToFile prepends a line to a file, preserving existing permissions.
-func PrependLineToFile(filePath, line string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", filePath, err)
-	}
-
-	mode := os.FileMode(0o644)
-	if info, statErr := os.Stat(filePath); statErr == nil {
-		mode = info.Mode()
-	}
-
-	newContents := []byte(line + "\n" + string(data))
-	return os.WriteFile(filePath, newContents, mode)
+	return makefile.Parse(data).Includes("Makefile-common"), nil
 }
 
 // WriteYAMLWithIndent marshals the given data structure to YAML and writes it to a file
@@ -217,30 +217,12 @@ var _ = Describe("FileContainsIncludeMakefileCommon", func() {
 		Entry("no include in multi-target file", "foo:\n\t@echo foo\n# comment\nbar:\n\t@echo bar\n", false),
 		Entry("include in the middle of the file",
 			strings.Join([]string{"foo:", "\t@echo foo", "include Makefile-common", "bar:", "\t@echo bar", ""}, "\n"), true),
+		Entry("optional include", "-include Makefile-common\n", true),
+		Entry("Makefile-common in a comment of another include", "-include foo # Makefile-common\n", false),
+		Entry("include in a recipe", "all:\n\tinclude Makefile-common\n", false),
 	)
 })
 
-var _ = Describe("PrependLineToFile", func() {
-	It("should prepend a line and preserve file mode", func() {
-		dir := GinkgoT().TempDir()
-		p := filepath.Join(dir, "Makefile")
-		original := "all:\n\t@echo hi\n"
-		mode := os.FileMode(0o600)
-		writeFileWithMode(p, original, mode)
-
-		line := "include Makefile-common"
-		Expect(PrependLineToFile(p, line)).To(Succeed())
-
-		data, err := os.ReadFile(p)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(string(data)).To(Equal(line + "\n" + original))
-
-		info, err := os.Stat(p)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(info.Mode().Perm()).To(Equal(mode.Perm()))
-	})
-})
-
 var _ = Describe("WriteYAMLWithIndent", func() {
 	It("should use 2-space indentation", func() {
 		dir := GinkgoT().TempDir()
@@ -0,0 +1,231 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+// skillNamePattern matches the skill names allowed by the Agent Skills
+// standard: lowercase letters, digits and single hyphens.
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Limits of the Agent Skills standard.
+const (
+	maxSkillNameLength        = 64
+	maxSkillDescriptionLength = 1024
+)
+
+// SkillBundle is a set of skills that can be installed: the embedded skills
+// or those of a skill source configured for the repository.
+type SkillBundle struct {
+	// Source is the directory or tarball the skills come from, as
+	// configured, or empty for the embedded skills.
+	Source string
+	fsys   fs.FS
+	// dirs maps the names of the skills to their directory in fsys.
+	dirs map[string]string
+}
+
+// EmbeddedSkillBundle returns the skills embedded in patternizer.
+func EmbeddedSkillBundle() *SkillBundle {
+	bundle := &SkillBundle{fsys: embedded.Skills, dirs: make(map[string]string)}
+	entries, _ := fs.ReadDir(embedded.Skills, "skills")
+	for _, entry := range entries {
+		if entry.IsDir() {
+			bundle.dirs[entry.Name()] = path.Join("skills", entry.Name())
+		}
+	}
+	return bundle
+}
+
+// LoadSkillBundle reads the skills of source, opened as fsys. Either the
+// root of fsys is a single skill, or every directory in it is one. The
+// SKILL.md front matter of every skill is validated.
+func LoadSkillBundle(fsys fs.FS, source string) (*SkillBundle, error) {
+	bundle := &SkillBundle{Source: source, fsys: fsys, dirs: make(map[string]string)}
+
+	if _, err := fs.Stat(fsys, "SKILL.md"); err == nil {
+		name, err := validateSkill(fsys, ".", "")
+		if err != nil {
+			return nil, err
+		}
+		bundle.dirs[name] = "."
+		return bundle, nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", source, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name, err := validateSkill(fsys, entry.Name(), entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		bundle.dirs[name] = entry.Name()
+	}
+	if len(bundle.dirs) == 0 {
+		return nil, fmt.Errorf("%s contains no skills; expected a SKILL.md or directories with one", source)
+	}
+	return bundle, nil
+}
+
+// Names returns the names of the skills in b, sorted.
+func (b *SkillBundle) Names() []string {
+	return slices.Sorted(maps.Keys(b.dirs))
+}
+
+// Has reports whether b contains the skill name.
+func (b *SkillBundle) Has(name string) bool {
+	_, ok := b.dirs[name]
+	return ok
+}
+
+// Label describes where the skills of b come from, for messages.
+func (b *SkillBundle) Label() string {
+	if b.Source == "" {
+		return "patternizer"
+	}
+	return b.Source
+}
+
+// Paths returns the directories, relative to the repository root, that the
+// skills of b are installed to for any of the targets.
+func (b *SkillBundle) Paths() []string {
+	var paths []string
+	for _, target := range SkillTargetNames() {
+		for _, name := range b.Names() {
+			paths = append(paths, SkillDir(target, name))
+		}
+	}
+	return paths
+}
+
+// Skill returns the manifest of the skill name in b, without targets.
+func (b *SkillBundle) Skill(name string) (skillbundle.InstalledSkill, error) {
+	skillDir, ok := b.dirs[name]
+	if !ok {
+		return skillbundle.InstalledSkill{}, fmt.Errorf("unknown skill %q", name)
+	}
+	digest, err := skillbundle.DirDigest(b.fsys, skillDir)
+	if err != nil {
+		return skillbundle.InstalledSkill{}, err
+	}
+	files := make(map[string]string)
+	err = fs.WalkDir(b.fsys, skillDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(b.fsys, p)
+		if err != nil {
+			return err
+		}
+		relPath := p
+		if skillDir != "." {
+			relPath = strings.TrimPrefix(p, skillDir+"/")
+		}
+		files[relPath] = report.HashBytes(data)
+		return nil
+	})
+	if err != nil {
+		return skillbundle.InstalledSkill{}, fmt.Errorf("error reading skill %s: %w", name, err)
+	}
+	return skillbundle.InstalledSkill{
+		Version: skillbundle.Version(b.fsys, skillDir),
+		Source:  b.Source,
+		SHA256:  digest,
+		Files:   files,
+	}, nil
+}
+
+// FindSkillBundle returns the bundle that contains the skill name, or nil.
+func FindSkillBundle(bundles []*SkillBundle, name string) *SkillBundle {
+	for _, bundle := range bundles {
+		if bundle.Has(name) {
+			return bundle
+		}
+	}
+	return nil
+}
+
+// validateSkill checks the SKILL.md front matter of the skill in dir of fsys
+// and returns the name of the skill. If dirName is set, the name must match it.
+func validateSkill(fsys fs.FS, dir, dirName string) (string, error) {
+	label := dirName
+	if label == "" {
+		label = "the skill"
+	}
+	data, err := fs.ReadFile(fsys, path.Join(dir, "SKILL.md"))
+	if err != nil {
+		return "", fmt.Errorf("%s has no SKILL.md", label)
+	}
+	name, err := ValidateSkillFrontMatter(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid SKILL.md of %s: %w", label, err)
+	}
+	if dirName != "" && name != dirName {
+		return "", fmt.Errorf("invalid SKILL.md of %s: name %q does not match the directory name", label, name)
+	}
+	return name, nil
+}
+
+// ValidateSkillFrontMatter checks the front matter of a SKILL.md against the
+// Agent Skills standard and returns the name of the skill. The name and the
+// description are required; allowed-tools, if present, must be a string or
+// a list of strings.
+func ValidateSkillFrontMatter(data []byte) (string, error) {
+	frontMatter, err := skillbundle.FrontMatter(data)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(frontMatter, &fields); err != nil {
+		return "", fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	name, _ := fields["name"].(string)
+	switch {
+	case name == "":
+		return "", errors.New("name is required")
+	case len(name) > maxSkillNameLength:
+		return "", fmt.Errorf("name %q is longer than %d characters", name, maxSkillNameLength)
+	case !skillNamePattern.MatchString(name):
+		return "", fmt.Errorf("name %q may only contain lowercase letters, digits and single hyphens", name)
+	}
+
+	description, _ := fields["description"].(string)
+	switch {
+	case strings.TrimSpace(description) == "":
+		return "", errors.New("description is required")
+	case len(description) > maxSkillDescriptionLength:
+		return "", fmt.Errorf("description is longer than %d characters", maxSkillDescriptionLength)
+	}
+
+	switch tools := fields["allowed-tools"].(type) {
+	case nil, string:
+	case []interface{}:
+		for _, tool := range tools {
+			if s, ok := tool.(string); !ok || s == "" {
+				return "", fmt.Errorf("allowed-tools must list tool names, got %v", tool)
+			}
+		}
+	default:
+		return "", fmt.Errorf("allowed-tools must be a string or a list of strings, got %v", tools)
+	}
+	return name, nil
+}
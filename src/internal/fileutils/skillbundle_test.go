@@ -0,0 +1,143 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+// skillMD returns a SKILL.md with the given front matter.
+func skillMD(frontMatter string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte("---\n" + frontMatter + "---\n# Skill\n")}
+}
+
+var _ = DescribeTable("ValidateSkillFrontMatter",
+	func(content string, expectedErr string) {
+		name, err := ValidateSkillFrontMatter([]byte(content))
+		if expectedErr != "" {
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("org-conventions"))
+	},
+	Entry("a minimal skill", "---\nname: org-conventions\ndescription: Our conventions.\n---\n", ""),
+	Entry("allowed tools as a string", "---\nname: org-conventions\ndescription: d\nallowed-tools: Read Bash(make *)\n---\n", ""),
+	Entry("allowed tools as a list", "---\nname: org-conventions\ndescription: d\nallowed-tools: [Read, Grep]\n---\n", ""),
+	Entry("CRLF line endings", "\ufeff---\r\nname: org-conventions\r\ndescription: d\r\n---\r\n# Conventions\r\n", ""),
+	Entry("no front matter", "# Conventions\n", "missing front matter"),
+	Entry("unterminated front matter", "---\nname: org-conventions\n", "unterminated front matter"),
+	Entry("invalid YAML", "---\nname: [\n---\n", "invalid front matter"),
+	Entry("no name", "---\ndescription: d\n---\n", "name is required"),
+	Entry("an uppercase name", "---\nname: Org\ndescription: d\n---\n", "may only contain lowercase letters"),
+	Entry("a long name", "---\nname: "+strings.Repeat("a", 65)+"\ndescription: d\n---\n", "longer than 64 characters"),
+	Entry("no description", "---\nname: org-conventions\ndescription: ' '\n---\n", "description is required"),
+	Entry("allowed tools as a map", "---\nname: org-conventions\ndescription: d\nallowed-tools: {Read: true}\n---\n", "must be a string or a list of strings"),
+	Entry("an empty allowed tool", "---\nname: org-conventions\ndescription: d\nallowed-tools: [Read, '']\n---\n", "must list tool names"),
+)
+
+var _ = Describe("SkillBundle", func() {
+	It("should accept the embedded skills", func() {
+		embedded := EmbeddedSkillBundle()
+		Expect(embedded.Names()).To(ContainElement("pattern-author"))
+		for _, name := range embedded.Names() {
+			_, err := validateSkill(embedded.fsys, embedded.dirs[name], name)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should load every skill directory of a source", func() {
+		bundle, err := LoadSkillBundle(fstest.MapFS{
+			"README.md":                      &fstest.MapFile{Data: []byte("org skills")},
+			"org-conventions/SKILL.md":       skillMD("name: org-conventions\ndescription: d\nversion: 1.2.0\n"),
+			"org-conventions/scripts/lint":   &fstest.MapFile{Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+			"org-release/SKILL.md":           skillMD("name: org-release\ndescription: d\n"),
+			".git/HEAD":                      &fstest.MapFile{Data: []byte("ref: refs/heads/main\n")},
+			"org-release/templates/notes.md": &fstest.MapFile{Data: []byte("notes")},
+		}, "skills/org")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bundle.Names()).To(Equal([]string{"org-conventions", "org-release"}))
+
+		skill, err := bundle.Skill("org-conventions")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skill.Version).To(Equal("1.2.0"))
+		Expect(skill.Source).To(Equal("skills/org"))
+		Expect(skill.Files).To(HaveKey("scripts/lint"))
+	})
+
+	It("should load a source that is a single skill", func() {
+		bundle, err := LoadSkillBundle(fstest.MapFS{
+			"SKILL.md":     skillMD("name: org-conventions\ndescription: d\n"),
+			"reference.md": &fstest.MapFile{Data: []byte("ref")},
+		}, "conventions.tgz")
+		Expect(err).NotTo(HaveOccurred())
+		skill, err := bundle.Skill("org-conventions")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skill.Files).To(HaveLen(2))
+		Expect(skill.Files).To(HaveKey("reference.md"))
+	})
+
+	DescribeTable("should reject invalid sources",
+		func(fsys fstest.MapFS, expectedErr string) {
+			_, err := LoadSkillBundle(fsys, "skills/org")
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("without skills", fstest.MapFS{"README.md": &fstest.MapFile{}}, "skills/org contains no skills"),
+		Entry("with a directory without SKILL.md", fstest.MapFS{"docs/index.md": &fstest.MapFile{}}, "docs has no SKILL.md"),
+		Entry("with a name that does not match the directory", fstest.MapFS{"org/SKILL.md": skillMD("name: other\ndescription: d\n")}, "does not match the directory name"),
+		Entry("with an invalid front matter", fstest.MapFS{"org/SKILL.md": skillMD("name: org\n")}, "invalid SKILL.md of org: description is required"),
+	)
+
+	Describe("installing", func() {
+		var (
+			repoRoot string
+			bundle   *SkillBundle
+		)
+
+		BeforeEach(func() {
+			repoRoot = GinkgoT().TempDir()
+			var err error
+			bundle, err = LoadSkillBundle(fstest.MapFS{
+				"org-conventions/SKILL.md":     skillMD("name: org-conventions\ndescription: d\n"),
+				"org-conventions/scripts/lint": &fstest.MapFile{Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+			}, "skills/org")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should install and track the skills of a source like the embedded ones", func() {
+			manifest := map[string]skillbundle.InstalledSkill{}
+			skills, err := InstallSkills(repoRoot, []string{"claude", "github"}, []*SkillBundle{EmbeddedSkillBundle(), bundle}, manifest, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skills).To(Equal([]string{"pattern-author", "org-conventions"}))
+			Expect(manifest["org-conventions"].Source).To(Equal("skills/org"))
+
+			info, err := os.Stat(filepath.Join(repoRoot, ".github", "skills", "org-conventions", "scripts", "lint"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(fs.FileMode(0o755)))
+
+			// Without the source, its skills are removed.
+			_, err = InstallSkills(repoRoot, []string{"claude", "github"}, []*SkillBundle{EmbeddedSkillBundle()}, manifest, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).NotTo(HaveKey("org-conventions"))
+			Expect(filepath.Join(repoRoot, ".github", "skills", "org-conventions")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(repoRoot, ".github", "skills", "pattern-author")).To(BeADirectory())
+		})
+
+		It("should not overwrite a skill of the same name that it did not install", func() {
+			own := filepath.Join(repoRoot, ".claude", "skills", "org-conventions", "SKILL.md")
+			Expect(os.MkdirAll(filepath.Dir(own), 0o755)).To(Succeed())
+			Expect(os.WriteFile(own, []byte("mine"), 0o644)).To(Succeed())
+
+			_, err := InstallSkill(repoRoot, bundle, "org-conventions", []string{"claude"}, nil)
+			Expect(err).To(MatchError(ContainSubstring(".claude/skills/org-conventions already exists and was not installed by patternizer")))
+			Expect(os.ReadFile(own)).To(Equal([]byte("mine")))
+		})
+	})
+})
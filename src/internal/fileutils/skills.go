@@ -2,37 +2,273 @@ package fileutils
 
 import (
 	"fmt"
-	"io/fs"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
-	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
 )
 
-var skillTargets = []string{".claude", ".cursor"}
+// SkillTargets maps the names of the AI tools that skills can be installed
+// for to the directory, relative to the repository root, whose skills/
+// subdirectory the tool reads.
+var SkillTargets = map[string]string{
+	"agents":   ".agents",
+	"claude":   ".claude",
+	"cursor":   ".cursor",
+	"github":   ".github",
+	"windsurf": ".windsurf",
+}
 
-// InstallSkills copies all embedded skill directories into the .claude and .cursor skill directories under the given repository root.
-func InstallSkills(repoRoot string) error {
-	entries, err := fs.ReadDir(embedded.Skills, "skills")
-	if err != nil {
-		return fmt.Errorf("error reading embedded skills: %w", err)
+// DefaultSkillTargets are the targets skills are installed for unless the
+// repository configuration or the --skills flag chooses others.
+var DefaultSkillTargets = []string{"claude", "cursor"}
+
+// SkillTargetNames returns the names of all skill targets, sorted.
+func SkillTargetNames() []string {
+	names := make([]string, 0, len(SkillTargets))
+	for name := range SkillTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSkillTargets resolves a selection of skill targets: "none", "all" or
+// a list of target names, each of which may also be comma-separated.
+func ParseSkillTargets(values []string) ([]string, error) {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 1 && names[0] == "none" {
+		return []string{}, nil
+	}
+	if len(names) == 1 && names[0] == "all" {
+		return SkillTargetNames(), nil
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	var targets []string
+	for _, name := range names {
+		if _, ok := SkillTargets[name]; !ok {
+			return nil, fmt.Errorf("unknown skill target %q; use none, all or a list of: %s", name, strings.Join(SkillTargetNames(), ", "))
+		}
+		if !slices.Contains(targets, name) {
+			targets = append(targets, name)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no skill targets given; use none, all or a list of: %s", strings.Join(SkillTargetNames(), ", "))
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// SkillPaths returns the directories, relative to the repository root, that
+// the embedded skills are installed to for any of the targets. Other skills
+// in the same directories are left alone.
+func SkillPaths() []string {
+	return EmbeddedSkillBundle().Paths()
+}
+
+// SkillDir returns the directory, relative to the repository root, that
+// skill is installed to for target.
+func SkillDir(target, skill string) string {
+	return filepath.Join(SkillTargets[target], "skills", skill)
+}
+
+// FindSkill returns the targets for which a SKILL.md of skill exists under
+// repoRoot, whether patternizer installed it or not.
+func FindSkill(repoRoot, skill string) []string {
+	var targets []string
+	for _, name := range SkillTargetNames() {
+		if _, err := os.Stat(filepath.Join(repoRoot, SkillDir(name, skill), "SKILL.md")); err == nil {
+			targets = append(targets, name)
+		}
+	}
+	return targets
+}
+
+// ModifiedSkillFiles returns the paths, relative to the repository root, of
+// the files of an installed skill that were changed or deleted since they
+// were installed.
+func ModifiedSkillFiles(repoRoot, skill string, installed skillbundle.InstalledSkill) []string {
+	var modified []string
+	for _, target := range installed.Targets {
+		if _, ok := SkillTargets[target]; !ok {
 			continue
 		}
+		for _, file := range slices.Sorted(maps.Keys(installed.Files)) {
+			relPath := filepath.Join(SkillDir(target, skill), filepath.FromSlash(file))
+			data, err := os.ReadFile(filepath.Join(repoRoot, relPath))
+			if err != nil || report.HashBytes(data) != installed.Files[file] {
+				modified = append(modified, filepath.ToSlash(relPath))
+			}
+		}
+	}
+	return modified
+}
 
-		skillName := entry.Name()
+// InstallSkills installs the skills of all bundles for targets under
+// repoRoot, or removes them if targets is empty, and updates manifest
+// accordingly. Skills in the manifest that no bundle contains any more are
+// removed, and the skills listed in skip are left alone. It returns the
+// names of the installed skills.
+func InstallSkills(repoRoot string, targets []string, bundles []*SkillBundle, manifest map[string]skillbundle.InstalledSkill, skip []string) ([]string, error) {
+	for _, name := range slices.Sorted(maps.Keys(manifest)) {
+		if FindSkillBundle(bundles, name) != nil || slices.Contains(skip, name) {
+			continue
+		}
+		previous := manifest[name]
+		if err := RemoveSkill(repoRoot, name, &previous); err != nil {
+			return nil, err
+		}
+		delete(manifest, name)
+	}
 
-		for _, target := range skillTargets {
-			skillDst := filepath.Join(repoRoot, target, "skills", skillName)
-			if err := WriteEmbeddedDir(embedded.Skills, "skills/"+skillName, skillDst); err != nil {
-				return fmt.Errorf("error installing skill %s to %s: %w", skillName, target, err)
+	var installed []string
+	for _, bundle := range bundles {
+		for _, name := range bundle.Names() {
+			if slices.Contains(skip, name) {
+				continue
+			}
+			previous, ok := manifest[name]
+			if len(targets) == 0 {
+				if err := RemoveSkill(repoRoot, name, manifestEntry(previous, ok)); err != nil {
+					return nil, err
+				}
+				delete(manifest, name)
+				continue
 			}
+			skill, err := InstallSkill(repoRoot, bundle, name, targets, manifestEntry(previous, ok))
+			if err != nil {
+				return nil, err
+			}
+			manifest[name] = skill
+			installed = append(installed, name)
 		}
+	}
+	return installed, nil
+}
 
-		fmt.Printf("Installed skill '%s'\n", skillName)
+// InstallSkill copies the skill name of bundle into the skill directories of
+// targets and removes it from the other targets. The files of previous, the
+// manifest of the installed copy if there is one, that the skill no longer
+// has are pruned. It returns the new manifest of the skill.
+//
+// A skill of a configured source is not installed over a skill directory
+// that patternizer did not install, which likely holds a skill of the same
+// name written by hand.
+func InstallSkill(repoRoot string, bundle *SkillBundle, name string, targets []string, previous *skillbundle.InstalledSkill) (skillbundle.InstalledSkill, error) {
+	skill, err := bundle.Skill(name)
+	if err != nil {
+		return skillbundle.InstalledSkill{}, err
 	}
+	if bundle.Source != "" {
+		for _, target := range targets {
+			if previous != nil && slices.Contains(previous.Targets, target) {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(repoRoot, SkillDir(target, name))); err == nil {
+				return skillbundle.InstalledSkill{}, fmt.Errorf("%s already exists and was not installed by patternizer; remove or rename it to install skill %s from %s",
+					filepath.ToSlash(SkillDir(target, name)), name, bundle.Source)
+			}
+		}
+	}
+
+	var stale []string
+	if previous != nil {
+		for file := range previous.Files {
+			if _, ok := skill.Files[file]; !ok {
+				stale = append(stale, file)
+			}
+		}
+	}
+	for _, target := range SkillTargetNames() {
+		if slices.Contains(targets, target) {
+			if err := removeSkillFiles(repoRoot, target, name, stale); err != nil {
+				return skillbundle.InstalledSkill{}, err
+			}
+			continue
+		}
+		if err := removeSkillFiles(repoRoot, target, name, knownSkillFiles(skill.Files, previous)); err != nil {
+			return skillbundle.InstalledSkill{}, err
+		}
+	}
+
+	for _, target := range targets {
+		if err := WriteEmbeddedDir(bundle.fsys, bundle.dirs[name], filepath.Join(repoRoot, SkillDir(target, name))); err != nil {
+			return skillbundle.InstalledSkill{}, fmt.Errorf("error installing skill %s to %s: %w", name, SkillTargets[target], err)
+		}
+	}
+	skill.Targets = slices.Clone(targets)
+	return skill, nil
+}
+
+// RemoveSkill removes skill name from all targets under repoRoot. Only the
+// files patternizer installed, as recorded in previous or, without a
+// manifest, as embedded, are removed, along with the directories this
+// leaves empty; files added to the skill directory by hand are kept.
+func RemoveSkill(repoRoot, name string, previous *skillbundle.InstalledSkill) error {
+	var files map[string]string
+	if embedded := EmbeddedSkillBundle(); embedded.Has(name) {
+		if skill, err := embedded.Skill(name); err == nil {
+			files = skill.Files
+		}
+	}
+	known := knownSkillFiles(files, previous)
+	for _, target := range SkillTargetNames() {
+		if err := removeSkillFiles(repoRoot, target, name, known); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// removeSkillFiles removes files, relative to the directory of skill for
+// target, along with the directories this leaves empty.
+func removeSkillFiles(repoRoot, target, skill string, files []string) error {
+	for _, file := range files {
+		filePath := filepath.Join(repoRoot, SkillDir(target, skill), filepath.FromSlash(file))
+		if err := RemovePathIfExists(filePath); err != nil {
+			return fmt.Errorf("error removing %s of skill %s from %s: %w", file, skill, SkillTargets[target], err)
+		}
+		// Fails, as intended, at the first directory that is not empty.
+		for dir := filepath.Dir(filePath); dir != repoRoot && len(dir) > len(repoRoot); dir = filepath.Dir(dir) {
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
 	return nil
 }
+
+// knownSkillFiles returns the files of a skill that patternizer wrote: those
+// of the embedded skill and those recorded in previous.
+func knownSkillFiles(files map[string]string, previous *skillbundle.InstalledSkill) []string {
+	known := slices.Sorted(maps.Keys(files))
+	if previous != nil {
+		for _, file := range slices.Sorted(maps.Keys(previous.Files)) {
+			if !slices.Contains(known, file) {
+				known = append(known, file)
+			}
+		}
+	}
+	return known
+}
+
+// manifestEntry returns a pointer to entry if ok, else nil.
+func manifestEntry(entry skillbundle.InstalledSkill, ok bool) *skillbundle.InstalledSkill {
+	if !ok {
+		return nil
+	}
+	return &entry
+}
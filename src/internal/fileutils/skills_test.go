@@ -0,0 +1,163 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+var _ = DescribeTable("ParseSkillTargets",
+	func(values []string, expected []string, expectedErr string) {
+		targets, err := ParseSkillTargets(values)
+		if expectedErr != "" {
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targets).To(Equal(expected))
+	},
+	Entry("a list", []string{"cursor, github", "cursor"}, []string{"cursor", "github"}, ""),
+	Entry("none", []string{"none"}, []string{}, ""),
+	Entry("all", []string{"all"}, SkillTargetNames(), ""),
+	Entry("none among others", []string{"claude,none"}, nil, "unknown skill target \"none\""),
+	Entry("an unknown target", []string{"emacs"}, nil, "unknown skill target \"emacs\""),
+	Entry("nothing", []string{" , "}, nil, "no skill targets given"),
+)
+
+var _ = Describe("InstallSkills", func() {
+	var repoRoot string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+	})
+
+	It("should install the skills for the given targets only", func() {
+		skills, err := InstallSkills(repoRoot, []string{"github", "agents"}, []*SkillBundle{EmbeddedSkillBundle()}, map[string]skillbundle.InstalledSkill{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skills).To(ContainElement("pattern-author"))
+		Expect(filepath.Join(repoRoot, ".github", "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, ".agents", "skills", "pattern-author", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, ".claude")).NotTo(BeAnExistingFile())
+	})
+
+	It("should remove its skills from the targets no longer selected", func() {
+		manifest := map[string]skillbundle.InstalledSkill{}
+		_, err := InstallSkills(repoRoot, DefaultSkillTargets, []*SkillBundle{EmbeddedSkillBundle()}, manifest, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).To(HaveKey("pattern-author"))
+		ownSkill := filepath.Join(repoRoot, ".claude", "skills", "team-skill", "SKILL.md")
+		Expect(os.MkdirAll(filepath.Dir(ownSkill), 0o755)).To(Succeed())
+		Expect(os.WriteFile(ownSkill, []byte("ours"), 0o644)).To(Succeed())
+
+		skills, err := InstallSkills(repoRoot, []string{}, []*SkillBundle{EmbeddedSkillBundle()}, manifest, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skills).To(BeEmpty())
+		Expect(manifest).To(BeEmpty())
+		Expect(filepath.Join(repoRoot, ".claude", "skills", "pattern-author")).NotTo(BeAnExistingFile())
+		Expect(ownSkill).To(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, ".cursor")).NotTo(BeAnExistingFile())
+	})
+
+	It("should leave the skipped skills alone", func() {
+		manifest := map[string]skillbundle.InstalledSkill{}
+		skills, err := InstallSkills(repoRoot, DefaultSkillTargets, []*SkillBundle{EmbeddedSkillBundle()}, manifest, []string{"pattern-author"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skills).NotTo(ContainElement("pattern-author"))
+		Expect(manifest).NotTo(HaveKey("pattern-author"))
+		Expect(filepath.Join(repoRoot, ".claude", "skills", "pattern-author")).NotTo(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("Skill manifest", func() {
+	var repoRoot string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+	})
+
+	skillFile := func(target, file string) string {
+		return filepath.Join(repoRoot, SkillDir(target, "pattern-author"), file)
+	}
+
+	It("should record the version, targets and files of an installed skill", func() {
+		installed, err := InstallSkill(repoRoot, EmbeddedSkillBundle(), "pattern-author", []string{"claude"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed.Targets).To(Equal([]string{"claude"}))
+		Expect(installed.Files).To(HaveKey("SKILL.md"))
+		Expect(installed.SHA256).To(HaveLen(64))
+		Expect(installed.DisplayVersion()).NotTo(BeEmpty())
+		Expect(ModifiedSkillFiles(repoRoot, "pattern-author", installed)).To(BeEmpty())
+
+		Expect(os.WriteFile(skillFile("claude", "SKILL.md"), []byte("changed"), 0o644)).To(Succeed())
+		Expect(os.Remove(skillFile("claude", "reference.md"))).To(Succeed())
+		Expect(ModifiedSkillFiles(repoRoot, "pattern-author", installed)).To(ConsistOf(
+			".claude/skills/pattern-author/SKILL.md", ".claude/skills/pattern-author/reference.md"))
+	})
+
+	It("should prune the files the embedded skill no longer has", func() {
+		previous, err := InstallSkill(repoRoot, EmbeddedSkillBundle(), "pattern-author", []string{"claude", "cursor"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, target := range []string{"claude", "cursor"} {
+			Expect(os.MkdirAll(filepath.Dir(skillFile(target, "examples/old.md")), 0o755)).To(Succeed())
+			Expect(os.WriteFile(skillFile(target, "examples/old.md"), []byte("old"), 0o644)).To(Succeed())
+		}
+		Expect(os.WriteFile(skillFile("claude", "notes.md"), []byte("mine"), 0o644)).To(Succeed())
+		previous.Files["examples/old.md"] = "0000"
+
+		installed, err := InstallSkill(repoRoot, EmbeddedSkillBundle(), "pattern-author", []string{"claude"}, &previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed.Files).NotTo(HaveKey("examples/old.md"))
+		Expect(filepath.Join(repoRoot, ".claude", "skills", "pattern-author", "examples")).NotTo(BeAnExistingFile())
+		Expect(skillFile("claude", "notes.md")).To(BeAnExistingFile())
+		Expect(skillFile("claude", "SKILL.md")).To(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, ".cursor")).NotTo(BeAnExistingFile())
+	})
+
+	It("should remove only the files it installed", func() {
+		previous, err := InstallSkill(repoRoot, EmbeddedSkillBundle(), "pattern-author", []string{"github"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(skillFile("github", "notes.md"), []byte("mine"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, ".github", "CODEOWNERS"), []byte("* @team"), 0o644)).To(Succeed())
+
+		Expect(RemoveSkill(repoRoot, "pattern-author", &previous)).To(Succeed())
+		Expect(skillFile("github", "SKILL.md")).NotTo(BeAnExistingFile())
+		Expect(skillFile("github", "notes.md")).To(BeAnExistingFile())
+
+		Expect(os.Remove(skillFile("github", "notes.md"))).To(Succeed())
+		Expect(RemoveSkill(repoRoot, "pattern-author", &previous)).To(Succeed())
+		Expect(filepath.Join(repoRoot, ".github", "skills")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, ".github", "CODEOWNERS")).To(BeAnExistingFile())
+	})
+
+	It("should remove installed skills that are no longer embedded", func() {
+		retired := filepath.Join(repoRoot, ".claude", "skills", "retired", "SKILL.md")
+		Expect(os.MkdirAll(filepath.Dir(retired), 0o755)).To(Succeed())
+		Expect(os.WriteFile(retired, []byte("retired"), 0o644)).To(Succeed())
+		manifest := map[string]skillbundle.InstalledSkill{
+			"retired": {Targets: []string{"claude"}, Files: map[string]string{"SKILL.md": "0000"}},
+		}
+
+		_, err := InstallSkills(repoRoot, []string{"claude"}, []*SkillBundle{EmbeddedSkillBundle()}, manifest, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).NotTo(HaveKey("retired"))
+		Expect(filepath.Dir(retired)).NotTo(BeAnExistingFile())
+	})
+
+	It("should keep skipped skills that are no longer embedded", func() {
+		retired := filepath.Join(repoRoot, ".claude", "skills", "retired", "SKILL.md")
+		Expect(os.MkdirAll(filepath.Dir(retired), 0o755)).To(Succeed())
+		Expect(os.WriteFile(retired, []byte("changed"), 0o644)).To(Succeed())
+		manifest := map[string]skillbundle.InstalledSkill{
+			"retired": {Targets: []string{"claude"}, Files: map[string]string{"SKILL.md": "0000"}},
+		}
+
+		_, err := InstallSkills(repoRoot, []string{"claude"}, []*SkillBundle{EmbeddedSkillBundle()}, manifest, []string{"retired"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest).To(HaveKey("retired"))
+		Expect(retired).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,639 @@
+package gitutil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNothingToCommit is returned by Commit when the files are already
+// committed as they are.
+var ErrNothingToCommit = errors.New("nothing to commit")
+
+// Signature identifies the author or committer of a commit.
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s> %s", s.Name, s.Email, gitDate(s.When))
+}
+
+// Identity returns the author or committer, as role says, that Git would
+// record: the GIT_AUTHOR_* or GIT_COMMITTER_* environment variables, or else
+// user.name and user.email of the repository or global configuration.
+func (r *Repo) Identity(role string, when time.Time) (Signature, error) {
+	prefix := "GIT_" + strings.ToUpper(role) + "_"
+	sig := Signature{Name: os.Getenv(prefix + "NAME"), Email: os.Getenv(prefix + "EMAIL"), When: when}
+	if sig.Name != "" && sig.Email != "" {
+		return sig, nil
+	}
+
+	for _, config := range r.configs() {
+		if name, ok := config.Get("user", "", "name"); ok && sig.Name == "" {
+			sig.Name = name
+		}
+		if email, ok := config.Get("user", "", "email"); ok && sig.Email == "" {
+			sig.Email = email
+		}
+	}
+	if sig.Email == "" {
+		sig.Email = os.Getenv("EMAIL")
+	}
+	if sig.Name == "" || sig.Email == "" {
+		return Signature{}, fmt.Errorf("%s identity unknown; set user.name and user.email with git config, or the %sNAME and %sEMAIL environment variables", role, prefix, prefix)
+	}
+	return sig, nil
+}
+
+// configs returns the repository and global configurations that can be read,
+// highest precedence first.
+func (r *Repo) configs() []Config {
+	var configs []Config
+	if config, err := r.Config(); err == nil {
+		configs = append(configs, config)
+	}
+	for _, path := range globalConfigPaths() {
+		if data, err := os.ReadFile(path); err == nil {
+			if config, err := ParseConfig(data); err == nil {
+				configs = append(configs, config)
+			}
+		}
+	}
+	return configs
+}
+
+// globalConfigPaths returns the global configuration files, highest
+// precedence first.
+func globalConfigPaths() []string {
+	if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+		return []string{path}
+	}
+	var paths []string
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "git", "config"))
+	} else if err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+	}
+	return paths
+}
+
+// branchNamePattern matches the characters and sequences that Git forbids
+// in ref names.
+var branchNamePattern = regexp.MustCompile(`[\x00-\x20\x7f~^:?*\[\\]|\.\.|@\{|//|/\.|\.lock(/|$)|^[-/.]|[/.]$|^@$`)
+
+// ValidateBranchName checks name against the rules of git check-ref-format.
+func ValidateBranchName(name string) error {
+	if name == "" || branchNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid branch name", name)
+	}
+	return nil
+}
+
+// HasBranch reports whether a branch exists.
+func (r *Repo) HasBranch(name string) (bool, error) {
+	id, err := r.readRef("refs/heads/" + name)
+	return id != "", err
+}
+
+// CreateBranch creates a branch at the commit HEAD points at and checks it
+// out. The index and working tree are left alone, since they match the new
+// branch as well as the old one.
+//
+// If git is installed, it creates the branch with git switch -c. Otherwise
+// the ref and HEAD are written directly.
+func (r *Repo) CreateBranch(name string, sig Signature) error {
+	if err := ValidateBranchName(name); err != nil {
+		return err
+	}
+	exists, err := r.HasBranch(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("branch %s already exists", name)
+	}
+	if git, err := lookGit(); err == nil {
+		env := []string{"GIT_COMMITTER_NAME=" + sig.Name, "GIT_COMMITTER_EMAIL=" + sig.Email, "GIT_COMMITTER_DATE=" + gitDate(sig.When)}
+		_, err := r.runGit(git, env, "", "switch", "-q", "-c", name)
+		return err
+	}
+	ref := "refs/heads/" + name
+	head, err := r.resolveHead()
+	if err != nil {
+		return err
+	}
+	// On a repository without commits, the branch is created by the first one.
+	if head != "" {
+		if err := r.updateRef(ref, "", head, sig, "branch: Created from HEAD"); err != nil {
+			return err
+		}
+	}
+	return r.writeLocked(filepath.Join(r.GitDir, "HEAD"), "ref: "+ref+"\n")
+}
+
+// lookGit finds the git executable; tests replace it.
+var lookGit = func() (string, error) { return exec.LookPath("git") }
+
+// commitHooks are the hooks git commit runs.
+var commitHooks = []string{"pre-commit", "prepare-commit-msg", "commit-msg", "post-commit"}
+
+// Commit records the working tree copy of the files under paths, which are
+// absolute, in a new commit on top of HEAD with the given message, and
+// stages them. Deleted files are removed. Other changes, including those
+// already staged, are left out of the commit and stay as they are, like
+// with git commit --only.
+//
+// If git is installed, it makes the commit, so that commit signing and hooks
+// apply. Otherwise the commit is written directly, which CheckCommit refuses
+// when the configuration asks for either.
+func (r *Repo) Commit(paths []string, message string, author, committer Signature) (string, error) {
+	rels := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rel, err := r.relPath(p)
+		if err != nil {
+			return "", err
+		}
+		rels = append(rels, rel)
+	}
+	if git, err := lookGit(); err == nil {
+		return r.commitWithGit(git, rels, message, author, committer)
+	}
+	if err := r.checkDirectCommit(); err != nil {
+		return "", err
+	}
+	return r.commitDirect(paths, rels, message, author, committer)
+}
+
+// CheckCommit reports an error if Commit cannot honor the commit
+// configuration: without git, signed commits and commit hooks are refused.
+func (r *Repo) CheckCommit() error {
+	if _, err := lookGit(); err == nil {
+		return nil
+	}
+	return r.checkDirectCommit()
+}
+
+// checkDirectCommit refuses to commit without git when commit.gpgsign, a
+// hooks directory or a commit hook is configured.
+func (r *Repo) checkDirectCommit() error {
+	configs := r.configs()
+	if sign, ok := configValue(configs, "commit", "gpgsign"); ok && isTrue(sign) {
+		return errors.New("commit.gpgsign is set, and signing commits needs git; install git or commit the changes yourself")
+	}
+	if hooksPath, ok := configValue(configs, "core", "hooksPath"); ok && hooksPath != "" {
+		return errors.New("core.hooksPath is set, and running commit hooks needs git; install git or commit the changes yourself")
+	}
+	for _, hook := range commitHooks {
+		if info, err := os.Stat(filepath.Join(r.CommonDir, "hooks", hook)); err == nil && info.Mode()&0o111 != 0 {
+			return fmt.Errorf("the %s hook is installed, and running it needs git; install git or commit the changes yourself", hook)
+		}
+	}
+	return nil
+}
+
+// configValue returns the value of key in the first of configs that sets it.
+func configValue(configs []Config, section, key string) (string, bool) {
+	for _, config := range configs {
+		if value, ok := config.Get(section, "", key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// isTrue reports whether a Git configuration value is a true boolean.
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// relPath returns the slash-separated path of p, which is absolute, relative
+// to the root of the repository.
+func (r *Repo) relPath(p string) (string, error) {
+	rel, err := filepath.Rel(r.Root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the repository at %s", p, r.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// commitWithGit makes the commit of Commit with git add and git commit
+// --only. Deleted files that git does not track are left out.
+func (r *Repo) commitWithGit(git string, rels []string, message string, author, committer Signature) (string, error) {
+	env := []string{
+		"GIT_LITERAL_PATHSPECS=1",
+		"GIT_AUTHOR_NAME=" + author.Name, "GIT_AUTHOR_EMAIL=" + author.Email, "GIT_AUTHOR_DATE=" + gitDate(author.When),
+		"GIT_COMMITTER_NAME=" + committer.Name, "GIT_COMMITTER_EMAIL=" + committer.Email, "GIT_COMMITTER_DATE=" + gitDate(committer.When),
+	}
+	run := func(stdin string, args ...string) (string, error) {
+		return r.runGit(git, env, stdin, args...)
+	}
+
+	tracked, err := run("", append([]string{"ls-files", "-z", "--cached", "--"}, rels...)...)
+	if err != nil {
+		return "", err
+	}
+	known := map[string]bool{}
+	for _, rel := range strings.Split(tracked, "\x00") {
+		known[rel] = true
+	}
+	var pathspecs []string
+	for _, rel := range rels {
+		if _, err := os.Lstat(filepath.Join(r.Root, filepath.FromSlash(rel))); err == nil || known[rel] {
+			pathspecs = append(pathspecs, rel)
+		}
+	}
+	if len(pathspecs) == 0 {
+		return "", ErrNothingToCommit
+	}
+
+	// The files are staged with -f, like Commit does without git, so that
+	// ignored files are committed as well.
+	if _, err := run("", append([]string{"add", "-A", "-f", "--"}, pathspecs...)...); err != nil {
+		return "", err
+	}
+	changes, err := run("", append([]string{"status", "--porcelain", "--"}, pathspecs...)...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(changes) == "" {
+		return "", ErrNothingToCommit
+	}
+	if _, err := run(message, append([]string{"commit", "-q", "--only", "-F", "-", "--"}, pathspecs...)...); err != nil {
+		return "", err
+	}
+	id, err := run("", "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(id), nil
+}
+
+// runGit runs git in the working tree with env added to the environment and
+// stdin as its input, and returns what it writes to standard output.
+func (r *Repo) runGit(git string, env []string, stdin string, args ...string) (string, error) {
+	cmd := exec.Command(git, args...)
+	cmd.Dir = r.Root
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// gitDate formats t in the raw date format of Git.
+func gitDate(t time.Time) string {
+	return fmt.Sprintf("%d %s", t.Unix(), t.Format("-0700"))
+}
+
+// commitDirect makes the commit of Commit without git.
+func (r *Repo) commitDirect(paths, rels []string, message string, author, committer Signature) (string, error) {
+	newHash, err := r.newHash()
+	if err != nil {
+		return "", err
+	}
+	idSize := newHash().Size()
+	config, err := r.Config()
+	if err != nil {
+		return "", err
+	}
+	fileMode, _ := config.Get("core", "", "filemode")
+
+	indexPath := filepath.Join(r.GitDir, "index")
+	lock, err := lockFile(indexPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(lock.Name())
+	defer lock.Close()
+
+	entries, err := r.readIndex(idSize)
+	if err != nil {
+		return "", err
+	}
+	index := map[string][]indexEntry{}
+	for _, e := range entries {
+		if e.mode == modeTree {
+			return "", errors.New("sparse indexes are not supported")
+		}
+		index[e.path] = append(index[e.path], e)
+	}
+	parent, parentTree, err := r.headTree(idSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	files, err := r.headFiles([]string{""}, idSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	for i, p := range paths {
+		rel := rels[i]
+		e, err := r.stageFile(p, rel, index[rel], fileMode != "false", newHash)
+		if err != nil {
+			return "", err
+		}
+		if e == nil {
+			delete(files, rel)
+			delete(index, rel)
+			continue
+		}
+		files[rel] = treeEntry{mode: e.mode, id: e.id}
+		index[rel] = []indexEntry{*e}
+	}
+
+	tree, err := r.writeTree(files, "", newHash)
+	if err != nil {
+		return "", err
+	}
+	if tree == parentTree {
+		return "", ErrNothingToCommit
+	}
+	var commit bytes.Buffer
+	fmt.Fprintf(&commit, "tree %s\n", tree)
+	if parent != "" {
+		fmt.Fprintf(&commit, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&commit, "author %s\ncommitter %s\n\n%s\n", author, committer, strings.TrimRight(message, "\n"))
+	id, err := r.writeObject("commit", commit.Bytes(), newHash)
+	if err != nil {
+		return "", err
+	}
+
+	entries = entries[:0]
+	for _, staged := range index {
+		entries = append(entries, staged...)
+	}
+	data, err := encodeIndex(entries, newHash)
+	if err != nil {
+		return "", err
+	}
+	if _, err := lock.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write the index: %w", err)
+	}
+	if err := lock.Close(); err != nil {
+		return "", fmt.Errorf("failed to write the index: %w", err)
+	}
+
+	subject, _, _ := strings.Cut(message, "\n")
+	reflogMessage := "commit: " + subject
+	if parent == "" {
+		reflogMessage = "commit (initial): " + subject
+	}
+	head, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	ref, onBranch := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !onBranch {
+		ref = "HEAD"
+	}
+	if err := r.updateRef(ref, parent, id, committer, reflogMessage); err != nil {
+		return "", err
+	}
+	if onBranch {
+		r.appendReflog("HEAD", parent, id, committer, reflogMessage)
+	}
+	// The index is replaced only once the commit is recorded.
+	if err := os.Rename(lock.Name(), indexPath); err != nil {
+		return "", fmt.Errorf("failed to write the index: %w", err)
+	}
+	return id, nil
+}
+
+// stageFile writes the blob of the working tree copy of a file and returns
+// its index entry, or nil if the file was deleted.
+func (r *Repo) stageFile(path, rel string, staged []indexEntry, checkMode bool, newHash func() hash.Hash) (*indexEntry, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) || isNotDir(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	var mode uint32
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		data, mode = []byte(filepath.ToSlash(target)), modeSymlink
+	case info.Mode().IsRegular():
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		mode = 0o100644
+		if info.Mode()&0o111 != 0 {
+			mode = 0o100755
+		}
+		// Without core.filemode, the executable bit is kept from the index.
+		if !checkMode && len(staged) > 0 && staged[0].mode&0o170000 == 0o100000 {
+			mode = staged[0].mode
+		}
+	default:
+		return nil, fmt.Errorf("cannot commit %s: not a regular file", rel)
+	}
+
+	id, err := r.writeObject("blob", data, newHash)
+	if err != nil {
+		return nil, err
+	}
+	e := newIndexEntry(rel, mode, id, info)
+	return &e, nil
+}
+
+// writeTree writes the tree of the files below prefix and returns its ID.
+func (r *Repo) writeTree(files map[string]treeEntry, prefix string, newHash func() hash.Hash) (string, error) {
+	type item struct {
+		name string
+		treeEntry
+	}
+	var items []item
+	dirs := map[string]bool{}
+	for path, file := range files {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok {
+			continue
+		}
+		if dir, _, ok := strings.Cut(rest, "/"); ok {
+			dirs[dir] = true
+			continue
+		}
+		items = append(items, item{rest, file})
+	}
+	for dir := range dirs {
+		id, err := r.writeTree(files, prefix+dir+"/", newHash)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item{dir, treeEntry{mode: modeTree, id: id}})
+	}
+	// Git sorts trees as if the names of subtrees ended with a slash.
+	key := func(i item) string {
+		if i.mode == modeTree {
+			return i.name + "/"
+		}
+		return i.name
+	}
+	sort.Slice(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+
+	var data bytes.Buffer
+	for _, i := range items {
+		id, err := hex.DecodeString(i.id)
+		if err != nil {
+			return "", fmt.Errorf("invalid object ID %q", i.id)
+		}
+		fmt.Fprintf(&data, "%o %s\x00", i.mode, i.name)
+		data.Write(id)
+	}
+	return r.writeObject("tree", data.Bytes(), newHash)
+}
+
+// writeObject stores an object as a loose object and returns its ID.
+func (r *Repo) writeObject(objType string, data []byte, newHash func() hash.Hash) (string, error) {
+	id := hashObject(newHash, objType, data)
+	dir := filepath.Join(r.CommonDir, "objects", id[:2])
+	path := filepath.Join(dir, id[2:])
+	if _, err := os.Stat(path); err == nil {
+		return id, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "%s %d\x00", objType, len(data))
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to write object %s: %w", id, err)
+	}
+	tmp, err := os.CreateTemp(dir, "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("failed to write object %s: %w", id, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o444)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write object %s: %w", id, err)
+	}
+	return id, nil
+}
+
+// updateRef points ref at newID if it still points at oldID, "" meaning that
+// it must not exist yet, and records the update in its reflog.
+func (r *Repo) updateRef(ref, oldID, newID string, sig Signature, message string) error {
+	current, err := r.readRef(ref)
+	if err != nil {
+		return err
+	}
+	if current != oldID {
+		return fmt.Errorf("%s was updated by another process", ref)
+	}
+	if err := r.writeLocked(r.refPath(ref), newID+"\n"); err != nil {
+		return err
+	}
+	r.appendReflog(ref, oldID, newID, sig, message)
+	return nil
+}
+
+// refPath returns the file of a loose ref; HEAD is specific to the worktree.
+func (r *Repo) refPath(ref string) string {
+	if ref == "HEAD" {
+		return filepath.Join(r.GitDir, ref)
+	}
+	return filepath.Join(r.CommonDir, filepath.FromSlash(ref))
+}
+
+// appendReflog records a ref update. Failing to do so does not fail the
+// update, like in Git.
+func (r *Repo) appendReflog(ref, oldID, newID string, sig Signature, message string) {
+	if oldID == "" {
+		oldID = strings.Repeat("0", len(newID))
+	}
+	dir := r.CommonDir
+	if ref == "HEAD" {
+		dir = r.GitDir
+	}
+	path := filepath.Join(dir, "logs", filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s %s %s\t%s\n", oldID, newID, sig, strings.ReplaceAll(message, "\n", " "))
+}
+
+// writeLocked replaces a file through a lock file, like Git does.
+func (r *Repo) writeLocked(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	lock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(lock.Name())
+	_, err = lock.WriteString(content)
+	if closeErr := lock.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(lock.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// lockFile creates the lock file Git uses to guard path.
+func lockFile(path string) (*os.File, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("%s.lock exists; another git process seems to be running", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,251 @@
+package gitutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Commit", func() {
+	var (
+		root string
+		sig  = Signature{Name: "Pat Ternizer", Email: "pat@example.com", When: time.Date(2026, 10, 18, 12, 0, 0, 0, time.FixedZone("", 2*3600))}
+	)
+
+	commit := func(root string, message string, paths ...string) (string, error) {
+		repo, err := Open(root)
+		Expect(err).NotTo(HaveOccurred())
+		var abs []string
+		for _, p := range paths {
+			abs = append(abs, filepath.Join(root, filepath.FromSlash(p)))
+		}
+		return repo.Commit(abs, message, sig, sig)
+	}
+
+	BeforeEach(func() {
+		root = newGitRepo(map[string]string{
+			"Makefile":           "all:\n",
+			"values-global.yaml": "global: {}\n",
+			"values-hub.yaml":    "clusterGroup: {}\n",
+			"charts/app/a.yaml":  "a\n",
+		})
+	})
+
+	// withoutGit makes Commit behave as if git was not installed.
+	withoutGit := func() {
+		lookGit = func() (string, error) { return "", exec.ErrNotFound }
+		DeferCleanup(func() { lookGit = func() (string, error) { return exec.LookPath("git") } })
+	}
+
+	for _, withGit := range []bool{true, false} {
+		Context(fmt.Sprintf("with git installed: %t", withGit), func() {
+			BeforeEach(func() {
+				GinkgoT().Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+				GinkgoT().Setenv("GIT_CONFIG_NOSYSTEM", "1")
+				if !withGit {
+					withoutGit()
+				}
+			})
+
+			It("should commit exactly the given files and keep other changes", func() {
+				writeFile(root, "Makefile", "include Makefile-common\n")
+				writeFile(root, "Makefile-common", "install:\n")
+				writeFile(root, "scripts/run.sh", "#!/bin/sh\n")
+				Expect(os.Chmod(filepath.Join(root, "scripts", "run.sh"), 0o755)).To(Succeed())
+				Expect(os.Symlink("Makefile-common", filepath.Join(root, "common.mk"))).To(Succeed())
+				Expect(os.Remove(filepath.Join(root, "values-hub.yaml"))).To(Succeed())
+				writeFile(root, "values-global.yaml", "global: {pattern: demo}\n")
+				runGit(root, "add", "values-global.yaml")
+				writeFile(root, "charts/app/a.yaml", "b\n")
+
+				id, err := commit(root, "Update the pattern\n\nWith a body.", "Makefile", "Makefile-common", "scripts/run.sh", "common.mk", "values-hub.yaml")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(strings.TrimSpace(runGit(root, "rev-parse", "HEAD"))).To(Equal(id))
+				Expect(runGit(root, "show", "--name-status", "--format=%an <%ae> %ad%n%B", "--date=iso", "HEAD")).To(Equal(
+					"Pat Ternizer <pat@example.com> 2026-10-18 12:00:00 +0200\nUpdate the pattern\n\nWith a body.\n\n\n" +
+						"M\tMakefile\nA\tMakefile-common\nA\tcommon.mk\nA\tscripts/run.sh\nD\tvalues-hub.yaml\n"))
+				Expect(runGit(root, "ls-tree", "HEAD", "scripts/run.sh", "common.mk")).To(And(ContainSubstring("100755 blob"), ContainSubstring("120000 blob")))
+				Expect(runGit(root, "status", "--porcelain")).To(Equal(" M charts/app/a.yaml\nM  values-global.yaml\n"))
+				Expect(runGit(root, "reflog", "-1", "--format=%gs")).To(Equal("commit: Update the pattern\n"))
+				runGit(root, "fsck", "--strict")
+				Expect(status(root, "Makefile", "Makefile-common", "scripts", "common.mk", "values-hub.yaml")).To(BeEmpty())
+			})
+
+			It("should create a branch and commit on it", func() {
+				main := strings.TrimSpace(runGit(root, "rev-parse", "HEAD"))
+				repo, err := Open(root)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(repo.CreateBranch("patternizer/init", sig)).To(Succeed())
+				Expect(repo.CreateBranch("patternizer/init", sig)).To(MatchError("branch patternizer/init already exists"))
+				// git switch records the checkout in the reflog of HEAD.
+				if withGit {
+					Expect(runGit(root, "reflog", "-1", "--format=%gs", "HEAD")).To(Equal("checkout: moving from main to patternizer/init\n"))
+				} else {
+					Expect(runGit(root, "reflog", "-1", "--format=%gs", "HEAD")).NotTo(ContainSubstring("checkout:"))
+				}
+				Expect(runGit(root, "reflog", "-1", "--format=%gs %gn", "patternizer/init")).To(Equal("branch: Created from HEAD Pat Ternizer\n"))
+
+				writeFile(root, "Makefile", "include Makefile-common\n")
+				_, err = commit(root, "Initialize", "Makefile")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(runGit(root, "rev-parse", "--abbrev-ref", "HEAD")).To(Equal("patternizer/init\n"))
+				Expect(strings.TrimSpace(runGit(root, "rev-parse", "HEAD~1"))).To(Equal(main))
+				Expect(strings.TrimSpace(runGit(root, "rev-parse", "main"))).To(Equal(main))
+				runGit(root, "fsck", "--strict")
+			})
+
+			It("should make the first commit of a repository", func() {
+				root := newGitRepo(nil)
+				repo, err := Open(root)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(repo.CreateBranch("pattern", sig)).To(Succeed())
+				writeFile(root, "values-global.yaml", "global: {}\n")
+				writeFile(root, "notes.txt", "not committed\n")
+
+				_, err = commit(root, "Initialize", "values-global.yaml")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(runGit(root, "log", "--format=%s %D")).To(Equal("Initialize HEAD -> pattern\n"))
+				Expect(runGit(root, "status", "--porcelain")).To(Equal("?? notes.txt\n"))
+			})
+
+			It("should commit in SHA-256 repositories", func() {
+				root := newGitRepo(map[string]string{"charts/app/a.yaml": "a\n"}, "--object-format=sha256")
+				writeFile(root, "Makefile", "all:\n")
+				_, err := commit(root, "Add Makefile", "Makefile")
+				Expect(err).NotTo(HaveOccurred())
+				runGit(root, "fsck", "--strict")
+				Expect(runGit(root, "status", "--porcelain")).To(BeEmpty())
+			})
+
+			It("should refuse to make an empty commit", func() {
+				_, err := commit(root, "Nothing", "Makefile", "missing.yaml")
+				Expect(err).To(MatchError(ErrNothingToCommit))
+				Expect(filepath.Join(root, ".git", "index.lock")).NotTo(BeAnExistingFile())
+			})
+
+			It("should refuse to run while git holds the index lock", func() {
+				writeFile(root, ".git/index.lock", "")
+				writeFile(root, "Makefile", "changed\n")
+				_, err := commit(root, "Update", "Makefile")
+				Expect(err).To(MatchError(MatchRegexp("(?i)another git process seems to be running")))
+			})
+		})
+	}
+
+	It("should run the commit hooks with git", func() {
+		writeFile(root, ".git/hooks/commit-msg", "#!/bin/sh\necho 'Signed-off-by: Hook' >> \"$1\"\n")
+		Expect(os.Chmod(filepath.Join(root, ".git", "hooks", "commit-msg"), 0o755)).To(Succeed())
+		writeFile(root, "Makefile", "changed\n")
+
+		_, err := commit(root, "Update", "Makefile")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runGit(root, "log", "-1", "--format=%B")).To(Equal("Update\nSigned-off-by: Hook\n\n"))
+	})
+
+	It("should report a failing hook", func() {
+		writeFile(root, ".git/hooks/pre-commit", "#!/bin/sh\necho 'lint failed' >&2\nexit 1\n")
+		Expect(os.Chmod(filepath.Join(root, ".git", "hooks", "pre-commit"), 0o755)).To(Succeed())
+		writeFile(root, "Makefile", "changed\n")
+
+		_, err := commit(root, "Update", "Makefile")
+		Expect(err).To(MatchError(ContainSubstring("lint failed")))
+	})
+
+	Context("without git", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+			withoutGit()
+			writeFile(root, "Makefile", "changed\n")
+		})
+
+		It("should refuse to commit when commits are signed", func() {
+			runGit(root, "config", "commit.gpgsign", "true")
+			repo, err := Open(root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.CheckCommit()).To(MatchError(ContainSubstring("commit.gpgsign is set")))
+			_, err = commit(root, "Update", "Makefile")
+			Expect(err).To(MatchError(ContainSubstring("commit.gpgsign is set")))
+			Expect(runGit(root, "log", "--format=%s")).To(Equal("initial\n"))
+		})
+
+		It("should refuse to commit when hooks are configured", func() {
+			runGit(root, "config", "core.hooksPath", ".githooks")
+			_, err := commit(root, "Update", "Makefile")
+			Expect(err).To(MatchError(ContainSubstring("core.hooksPath is set")))
+
+			runGit(root, "config", "--unset", "core.hooksPath")
+			writeFile(root, ".git/hooks/pre-commit", "#!/bin/sh\n")
+			Expect(os.Chmod(filepath.Join(root, ".git", "hooks", "pre-commit"), 0o755)).To(Succeed())
+			_, err = commit(root, "Update", "Makefile")
+			Expect(err).To(MatchError(ContainSubstring("the pre-commit hook is installed")))
+		})
+
+		It("should commit when signing is disabled in the repository", func() {
+			writeFile(root, "global.gitconfig", "[commit]\n\tgpgsign = true\n")
+			GinkgoT().Setenv("GIT_CONFIG_GLOBAL", filepath.Join(root, "global.gitconfig"))
+			runGit(root, "config", "commit.gpgsign", "false")
+			writeFile(root, ".git/hooks/pre-commit.sample", "#!/bin/sh\n")
+			_, err := commit(root, "Update", "Makefile")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Identity", func() {
+	It("should prefer the environment over the configuration", func() {
+		root := newGitRepo(nil)
+		runGit(root, "config", "user.name", "Config Name")
+		runGit(root, "config", "user.email", "config@example.com")
+		repo, err := Open(root)
+		Expect(err).NotTo(HaveOccurred())
+
+		GinkgoT().Setenv("GIT_AUTHOR_NAME", "")
+		GinkgoT().Setenv("GIT_AUTHOR_EMAIL", "")
+		Expect(repo.Identity("author", time.Now())).To(And(HaveField("Name", "Config Name"), HaveField("Email", "config@example.com")))
+
+		GinkgoT().Setenv("GIT_AUTHOR_NAME", "Env Name")
+		GinkgoT().Setenv("GIT_AUTHOR_EMAIL", "env@example.com")
+		Expect(repo.Identity("author", time.Now())).To(HaveField("Name", "Env Name"))
+	})
+
+	It("should fail without a name and email", func() {
+		root := newGitRepo(nil)
+		repo, err := Open(root)
+		Expect(err).NotTo(HaveOccurred())
+		GinkgoT().Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+		GinkgoT().Setenv("GIT_COMMITTER_NAME", "")
+		GinkgoT().Setenv("GIT_COMMITTER_EMAIL", "")
+		GinkgoT().Setenv("EMAIL", "")
+		_, err = repo.Identity("committer", time.Now())
+		Expect(err).To(MatchError(ContainSubstring("committer identity unknown")))
+	})
+})
+
+var _ = DescribeTable("ValidateBranchName",
+	func(name string, valid bool) {
+		if valid {
+			Expect(ValidateBranchName(name)).To(Succeed())
+		} else {
+			Expect(ValidateBranchName(name)).NotTo(Succeed())
+		}
+	},
+	Entry("simple", "patternizer-upgrade", true),
+	Entry("with slashes", "feature/patternizer", true),
+	Entry("empty", "", false),
+	Entry("with a space", "my branch", false),
+	Entry("with two dots", "a..b", false),
+	Entry("starting with a dash", "-b", false),
+	Entry("ending with .lock", "topic.lock", false),
+	Entry("with a component starting with a dot", "a/.b", false),
+	Entry("ending with a slash", "a/", false),
+	Entry("with @{", "a@{1}", false),
+	Entry("@ alone", "@", false),
+)
@@ -0,0 +1,275 @@
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNotRepository is returned when a directory is not inside a Git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Repo is a Git repository on disk. It is read directly from the .git
+// directory, so that patternizer does not need the git binary, which the
+// container image does not ship.
+type Repo struct {
+	// Root is the directory of the working tree.
+	Root string
+	// GitDir is the directory holding HEAD and the index. It differs from
+	// CommonDir in linked worktrees.
+	GitDir string
+	// CommonDir is the directory holding the configuration, refs and objects.
+	CommonDir string
+}
+
+// Open finds the repository containing dir, looking at dir and its parents.
+// Both .git directories and the .git files of worktrees and submodules are
+// supported.
+func Open(dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		info, err := os.Stat(gitPath)
+		if err == nil {
+			repo := &Repo{Root: dir, GitDir: gitPath}
+			if !info.IsDir() {
+				if repo.GitDir, err = readGitFile(gitPath); err != nil {
+					return nil, err
+				}
+			}
+			repo.CommonDir = repo.GitDir
+			if data, err := os.ReadFile(filepath.Join(repo.GitDir, "commondir")); err == nil {
+				repo.CommonDir = resolvePath(repo.GitDir, strings.TrimSpace(string(data)))
+			}
+			return repo, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to stat %s: %w", gitPath, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+// readGitFile returns the Git directory a .git file points at.
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s does not point at a git directory", path)
+	}
+	return resolvePath(filepath.Dir(path), strings.TrimSpace(target)), nil
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// Head returns the branch HEAD points at. It fails when HEAD is detached.
+func (r *Repo) Head() (string, error) {
+	data, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !ok {
+		return "", fmt.Errorf("HEAD is detached; check out a branch")
+	}
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return "", fmt.Errorf("HEAD points at %s, which is not a branch", ref)
+	}
+	return branch, nil
+}
+
+// Config returns the repository configuration.
+func (r *Repo) Config() (Config, error) {
+	path := filepath.Join(r.CommonDir, "config")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Config maps "section.key" and "section.subsection.key" names to their
+// values in the order they are set. Section and key names are lower case;
+// subsection names keep their case, like git config does.
+type Config map[string][]string
+
+// Get returns the last value of a setting, which is the one Git uses.
+func (c Config) Get(section, subsection, key string) (string, bool) {
+	values := c[configName(section, subsection, key)]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+func configName(section, subsection, key string) string {
+	if subsection == "" {
+		return strings.ToLower(section) + "." + strings.ToLower(key)
+	}
+	return strings.ToLower(section) + "." + subsection + "." + strings.ToLower(key)
+}
+
+var (
+	sectionPattern = regexp.MustCompile(`^\[\s*([A-Za-z0-9.-]+)\s*(?:"((?:[^"\\]|\\.)*)")?\s*\]`)
+	keyPattern     = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*)\s*(?:=\s*(.*))?$`)
+)
+
+// ParseConfig parses a Git configuration file. Include directives are not
+// followed.
+func ParseConfig(data []byte) (Config, error) {
+	config := Config{}
+	var section, subsection string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			match := sectionPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid section header", lineNumber)
+			}
+			section, subsection = match[1], unescape(match[2])
+			// The deprecated [section.subsection] syntax.
+			if match[2] == "" {
+				if name, sub, ok := strings.Cut(section, "."); ok {
+					section, subsection = name, strings.ToLower(sub)
+				}
+			}
+			line = strings.TrimSpace(line[len(match[0]):])
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: setting outside of a section", lineNumber)
+		}
+		match := keyPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: invalid setting", lineNumber)
+		}
+		value := "true"
+		if strings.Contains(line, "=") {
+			value = parseValue(match[2])
+		}
+		name := configName(section, subsection, match[1])
+		config[name] = append(config[name], value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parseValue strips comments and quotes from a value and unescapes it.
+func parseValue(raw string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && i+1 < len(raw):
+			i++
+			b.WriteString(unescape(raw[i-1 : i+1]))
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\b`, "\b", `\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// Upstream returns the remote and remote branch a local branch tracks. It
+// fails when the branch has no upstream configured.
+func (c Config) Upstream(branch string) (remote, remoteBranch string, err error) {
+	remote, hasRemote := c.Get("branch", branch, "remote")
+	merge, hasMerge := c.Get("branch", branch, "merge")
+	if !hasRemote || !hasMerge || remote == "" || remote == "." {
+		return "", "", fmt.Errorf("branch %s has no upstream configured; push it with 'git push -u <remote> %s'", branch, branch)
+	}
+	return remote, strings.TrimPrefix(merge, "refs/heads/"), nil
+}
+
+// RemoteURL returns the fetch URL of a remote, after applying the url.<base>.insteadOf
+// rewrites of the configuration.
+func (c Config) RemoteURL(remote string) (string, error) {
+	url, ok := c.Get("remote", remote, "url")
+	if !ok || url == "" {
+		return "", fmt.Errorf("remote %s has no URL configured", remote)
+	}
+	// The longest matching insteadOf prefix wins.
+	var base, prefix string
+	for name, values := range c {
+		rest, ok := strings.CutPrefix(name, "url.")
+		if !ok || !strings.HasSuffix(rest, ".insteadof") {
+			continue
+		}
+		for _, value := range values {
+			if strings.HasPrefix(url, value) && len(value) > len(prefix) {
+				base, prefix = strings.TrimSuffix(rest, ".insteadof"), value
+			}
+		}
+	}
+	if prefix != "" {
+		url = base + strings.TrimPrefix(url, prefix)
+	}
+	return url, nil
+}
+
+// scpURLPattern matches scp-like SSH URLs such as git@github.com:org/repo.git.
+var scpURLPattern = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// HTTPSURL converts SSH remote URLs to the HTTPS URL of the same repository,
+// like the pattern Makefile does, since the cluster fetches the pattern over
+// HTTPS. Other URLs are returned unchanged.
+func HTTPSURL(url string) string {
+	if rest, ok := strings.CutPrefix(url, "ssh://"); ok {
+		host, path, _ := strings.Cut(rest, "/")
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		// Drop the port, which is the SSH port.
+		host, _, _ = strings.Cut(host, ":")
+		return "https://" + host + "/" + path
+	}
+	if strings.Contains(url, "://") {
+		return url
+	}
+	if match := scpURLPattern.FindStringSubmatch(url); match != nil {
+		return "https://" + match[1] + "/" + strings.TrimPrefix(match[2], "/")
+	}
+	return url
+}
@@ -0,0 +1,142 @@
+package gitutil
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeGitDir creates a .git directory with a HEAD and a config file.
+func writeGitDir(gitDir, head, config string) {
+	Expect(os.MkdirAll(gitDir, 0o755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(head), 0o644)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(gitDir, "config"), []byte(config), 0o644)).To(Succeed())
+}
+
+var _ = Describe("Open", func() {
+	It("should find the repository from a subdirectory", func() {
+		root := GinkgoT().TempDir()
+		writeGitDir(filepath.Join(root, ".git"), "ref: refs/heads/main\n", "")
+		sub := filepath.Join(root, "charts", "app")
+		Expect(os.MkdirAll(sub, 0o755)).To(Succeed())
+
+		repo, err := Open(sub)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Root).To(Equal(root))
+		Expect(repo.CommonDir).To(Equal(filepath.Join(root, ".git")))
+
+		branch, err := repo.Head()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("main"))
+	})
+
+	It("should follow the .git file of a linked worktree", func() {
+		main := GinkgoT().TempDir()
+		worktreeGitDir := filepath.Join(main, ".git", "worktrees", "feature")
+		writeGitDir(filepath.Join(main, ".git"), "ref: refs/heads/main\n", "[core]\n")
+		writeGitDir(worktreeGitDir, "ref: refs/heads/feature\n", "")
+		Expect(os.WriteFile(filepath.Join(worktreeGitDir, "commondir"), []byte("../..\n"), 0o644)).To(Succeed())
+
+		worktree := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+worktreeGitDir+"\n"), 0o644)).To(Succeed())
+
+		repo, err := Open(worktree)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.GitDir).To(Equal(worktreeGitDir))
+		Expect(repo.CommonDir).To(Equal(filepath.Join(main, ".git")))
+		Expect(repo.Head()).To(Equal("feature"))
+	})
+
+	It("should fail outside of a repository", func() {
+		_, err := Open(GinkgoT().TempDir())
+		Expect(err).To(MatchError(ErrNotRepository))
+	})
+
+	It("should fail on a detached HEAD", func() {
+		root := GinkgoT().TempDir()
+		writeGitDir(filepath.Join(root, ".git"), "0123456789abcdef0123456789abcdef01234567\n", "")
+		repo, err := Open(root)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = repo.Head()
+		Expect(err).To(MatchError(ContainSubstring("HEAD is detached")))
+	})
+})
+
+var _ = Describe("Config", func() {
+	const data = `# comment
+[core]
+	bare = false
+	filemode
+[remote "origin"]
+	url = git@github.com:org/repo.git ; trailing comment
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[branch "Feature"]
+	remote = origin
+	merge = refs/heads/feature
+[branch "local"]
+	remote = .
+	merge = refs/heads/main
+[url "https://mirror.example.com/"]
+	insteadOf = https://github.com/
+[remote "mirrored"]
+	url = "https://github.com/org/repo.git"
+`
+
+	var config Config
+
+	BeforeEach(func() {
+		var err error
+		config, err = ParseConfig([]byte(data))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should parse sections, subsections and values", func() {
+		get := func(section, subsection, key string) string {
+			value, ok := config.Get(section, subsection, key)
+			Expect(ok).To(BeTrue())
+			return value
+		}
+		Expect(get("core", "", "bare")).To(Equal("false"))
+		Expect(get("CORE", "", "FileMode")).To(Equal("true"))
+		Expect(get("remote", "origin", "url")).To(Equal("git@github.com:org/repo.git"))
+		_, ok := config.Get("branch", "feature", "remote")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should return the upstream of a branch", func() {
+		remote, branch, err := config.Upstream("Feature")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote).To(Equal("origin"))
+		Expect(branch).To(Equal("feature"))
+
+		_, _, err = config.Upstream("local")
+		Expect(err).To(MatchError(ContainSubstring("branch local has no upstream configured")))
+		_, _, err = config.Upstream("main")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should apply insteadOf rewrites to remote URLs", func() {
+		Expect(config.RemoteURL("mirrored")).To(Equal("https://mirror.example.com/org/repo.git"))
+		_, err := config.RemoteURL("missing")
+		Expect(err).To(MatchError(ContainSubstring("remote missing has no URL configured")))
+	})
+
+	It("should reject invalid files", func() {
+		_, err := ParseConfig([]byte("url = x\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 1: setting outside of a section")))
+		_, err = ParseConfig([]byte("[core\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 1: invalid section header")))
+	})
+})
+
+var _ = DescribeTable("HTTPSURL",
+	func(url, expected string) {
+		Expect(HTTPSURL(url)).To(Equal(expected))
+	},
+	Entry("scp-like SSH", "git@github.com:org/repo.git", "https://github.com/org/repo.git"),
+	Entry("SSH URL with port", "ssh://git@gitlab.example.com:2222/org/repo.git", "https://gitlab.example.com/org/repo.git"),
+	Entry("HTTPS", "https://github.com/org/repo.git", "https://github.com/org/repo.git"),
+	Entry("local path", "/srv/git/repo.git", "/srv/git/repo.git"),
+)
@@ -0,0 +1,194 @@
+package gitutil
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a pattern of a .gitignore file.
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer tells which paths of a working tree Git ignores, reading the
+// .gitignore files of the directories it is asked about as it goes.
+type ignorer struct {
+	root   string
+	rules  []ignoreRule
+	loaded map[string]bool
+}
+
+// newIgnorer loads the excludes file and info/exclude of the repository; the
+// .gitignore files are loaded on demand.
+func (r *Repo) newIgnorer() *ignorer {
+	i := &ignorer{root: r.Root, loaded: map[string]bool{}}
+	excludesFile := ""
+	if config, err := r.Config(); err == nil {
+		excludesFile, _ = config.Get("core", "", "excludesfile")
+	}
+	if excludesFile == "" {
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+			excludesFile = filepath.Join(dir, "git", "ignore")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			excludesFile = filepath.Join(home, ".config", "git", "ignore")
+		}
+	} else if rest, ok := strings.CutPrefix(excludesFile, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			excludesFile = filepath.Join(home, rest)
+		}
+	}
+	if excludesFile != "" {
+		i.addFile(excludesFile, "")
+	}
+	i.addFile(filepath.Join(r.CommonDir, "info", "exclude"), "")
+	return i
+}
+
+// addFile adds the rules of an ignore file whose patterns are relative to
+// base, a slash-separated directory ending in "/" or "" for the root.
+func (i *ignorer) addFile(path, base string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rule, ok := compileIgnoreRule(strings.TrimSuffix(line, "\r"), base); ok {
+			i.rules = append(i.rules, rule)
+		}
+	}
+}
+
+// load adds the rules of the .gitignore file of dir, a slash-separated path
+// relative to the root, and of its parents.
+func (i *ignorer) load(dir string) {
+	if i.loaded[dir] {
+		return
+	}
+	i.loaded[dir] = true
+	base := ""
+	if dir != "" {
+		parent, _ := splitPath(dir)
+		i.load(parent)
+		base = dir + "/"
+	}
+	i.addFile(filepath.Join(i.root, filepath.FromSlash(dir), ".gitignore"), base)
+}
+
+// ignored reports whether path, slash-separated and relative to the root, is
+// ignored, either itself or through one of its parent directories.
+func (i *ignorer) ignored(path string, isDir bool) bool {
+	parent, _ := splitPath(path)
+	if parent != "" && i.ignored(parent, true) {
+		return true
+	}
+	i.load(parent)
+	ignored := false
+	for _, rule := range i.rules {
+		if (!rule.dirOnly || isDir) && rule.pattern.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// splitPath splits a slash-separated path into its directory, "" at the
+// root, and its name.
+func splitPath(path string) (string, string) {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// compileIgnoreRule translates a line of an ignore file to a regular
+// expression matching the paths it ignores.
+func compileIgnoreRule(line, base string) (ignoreRule, bool) {
+	// Trailing spaces are dropped unless escaped.
+	if trimmed := strings.TrimRight(line, " "); strings.HasSuffix(trimmed, `\`) && trimmed != line {
+		line = trimmed + " "
+	} else {
+		line = trimmed
+	}
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	}
+	if trimmed, ok := strings.CutSuffix(line, "/"); ok {
+		rule.dirOnly = true
+		line = trimmed
+	}
+	// Patterns with a slash other than a trailing one are relative to the
+	// directory of the ignore file; the others match at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	var b strings.Builder
+	b.WriteString("^" + regexp.QuoteMeta(base))
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '*':
+			doubleStar := i+1 < len(line) && line[i+1] == '*' && (i == 0 || line[i-1] == '/') && (i+2 == len(line) || line[i+2] == '/')
+			switch {
+			case doubleStar && i+2 == len(line):
+				b.WriteString(".*")
+				i++
+			case doubleStar:
+				b.WriteString("(?:.*/)?")
+				i += 2
+			default:
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			// A "]" right after the opening bracket is part of the set.
+			start := i + 1
+			if start < len(line) && line[start] == '!' {
+				start++
+			}
+			if start < len(line) && line[start] == ']' {
+				start++
+			}
+			end := strings.IndexByte(line[start:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			end += start
+			class := line[i+1 : end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			b.WriteString("[" + class + "]")
+			i = end
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				b.WriteString(regexp.QuoteMeta(line[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
@@ -0,0 +1,232 @@
+package gitutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// indexEntry is a file staged in the index, with the stat data Git uses to
+// tell whether the working tree copy changed.
+type indexEntry struct {
+	path  string
+	mode  uint32
+	id    string
+	size  uint32
+	mtime [2]uint32
+	stage int
+	// skipWorktree is set for files outside a sparse checkout, whose working
+	// tree copy is not expected to exist.
+	skipWorktree bool
+	// intentToAdd is set for files added with git add -N.
+	intentToAdd bool
+	// stat holds the ctime, mtime, device, inode, mode, uid, gid and size
+	// fields as stored, and flags and extended the flags, so that entries
+	// can be written back unchanged.
+	stat     []byte
+	flags    uint16
+	extended uint16
+}
+
+// Index entry flags.
+const (
+	indexExtended     = 0x4000
+	indexStageMask    = 0x3000
+	indexSkipWorktree = 0x4000
+	indexIntentToAdd  = 0x2000
+)
+
+// readIndex parses the index, in any of the versions 2 to 4. It returns no
+// entries when the repository has no index yet.
+func (r *Repo) readIndex(idSize int) ([]indexEntry, error) {
+	path := filepath.Join(r.GitDir, "index")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the index: %w", err)
+	}
+	entries, err := parseIndex(data, idSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the index: %w", err)
+	}
+	return entries, nil
+}
+
+func parseIndex(data []byte, idSize int) ([]indexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("bad signature")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	// The index ends with a checksum.
+	end := len(data) - idSize
+	pos := 12
+	truncated := fmt.Errorf("truncated entry")
+
+	entries := make([]indexEntry, 0, count)
+	var previous string
+	for range count {
+		start := pos
+		fixed := 40 + idSize + 2
+		if pos+fixed > end {
+			return nil, truncated
+		}
+		e := indexEntry{
+			mtime: [2]uint32{binary.BigEndian.Uint32(data[pos+8:]), binary.BigEndian.Uint32(data[pos+12:])},
+			mode:  binary.BigEndian.Uint32(data[pos+24:]),
+			size:  binary.BigEndian.Uint32(data[pos+36:]),
+			id:    hex.EncodeToString(data[pos+40 : pos+40+idSize]),
+			stat:  data[pos : pos+40],
+			flags: binary.BigEndian.Uint16(data[pos+40+idSize:]),
+		}
+		e.stage = int(e.flags&indexStageMask) >> 12
+		pos += fixed
+		if version >= 3 && e.flags&indexExtended != 0 {
+			if pos+2 > end {
+				return nil, truncated
+			}
+			e.extended = binary.BigEndian.Uint16(data[pos:])
+			e.skipWorktree = e.extended&indexSkipWorktree != 0
+			e.intentToAdd = e.extended&indexIntentToAdd != 0
+			pos += 2
+		}
+
+		if version == 4 {
+			// Paths are prefix-compressed against the previous entry.
+			strip, n := readOffset(data[pos:end])
+			if n == 0 || strip > len(previous) {
+				return nil, truncated
+			}
+			pos += n
+			name, _, ok := bytes.Cut(data[pos:end], []byte{0})
+			if !ok {
+				return nil, truncated
+			}
+			e.path = previous[:len(previous)-strip] + string(name)
+			pos += len(name) + 1
+		} else {
+			name, _, ok := bytes.Cut(data[pos:end], []byte{0})
+			if !ok {
+				return nil, truncated
+			}
+			e.path = string(name)
+			// Entries are padded with 1 to 8 NULs to a multiple of 8 bytes.
+			pos = start + (pos-start+len(name)+8)&^7
+		}
+		previous = e.path
+		entries = append(entries, e)
+	}
+
+	// A split index keeps most entries in a shared index file, which is not
+	// read here.
+	for pos+8 <= end {
+		signature := string(data[pos : pos+4])
+		size := int(binary.BigEndian.Uint32(data[pos+4:]))
+		if signature == "link" {
+			return nil, fmt.Errorf("split indexes are not supported")
+		}
+		pos += 8 + size
+	}
+	return entries, nil
+}
+
+// readOffset decodes the variable-length integers of pack offsets and index
+// version 4 paths, returning the value and the number of bytes read.
+func readOffset(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	c := data[0]
+	value := int(c & 0x7f)
+	n := 1
+	for c&0x80 != 0 {
+		if n >= len(data) {
+			return 0, 0
+		}
+		c = data[n]
+		n++
+		value = (value+1)<<7 | int(c&0x7f)
+	}
+	return value, n
+}
+
+// newIndexEntry returns the entry of a file of the working tree. The
+// creation time, device, inode and owner are left out, which only makes Git
+// hash the file again the next time it looks at it.
+func newIndexEntry(path string, mode uint32, id string, info fs.FileInfo) indexEntry {
+	mtime := info.ModTime()
+	e := indexEntry{
+		path:  path,
+		mode:  mode,
+		id:    id,
+		size:  uint32(info.Size()),
+		mtime: [2]uint32{uint32(mtime.Unix()), uint32(mtime.Nanosecond())},
+		stat:  make([]byte, 40),
+	}
+	binary.BigEndian.PutUint32(e.stat[0:], e.mtime[0])
+	binary.BigEndian.PutUint32(e.stat[4:], e.mtime[1])
+	binary.BigEndian.PutUint32(e.stat[8:], e.mtime[0])
+	binary.BigEndian.PutUint32(e.stat[12:], e.mtime[1])
+	binary.BigEndian.PutUint32(e.stat[24:], mode)
+	binary.BigEndian.PutUint32(e.stat[36:], e.size)
+	return e
+}
+
+// encodeIndex serializes entries as an index of version 2, or 3 when an
+// entry has extended flags. Extensions such as the cached trees are dropped;
+// Git rebuilds them when needed.
+func encodeIndex(entries []indexEntry, newHash func() hash.Hash) ([]byte, error) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].path != entries[j].path {
+			return entries[i].path < entries[j].path
+		}
+		return entries[i].stage < entries[j].stage
+	})
+	version := uint32(2)
+	for _, e := range entries {
+		if e.extended != 0 {
+			version = 3
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	buf.Write(binary.BigEndian.AppendUint32(nil, version))
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(entries))))
+	for _, e := range entries {
+		start := buf.Len()
+		id, err := hex.DecodeString(e.id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object ID %q", e.id)
+		}
+		buf.Write(e.stat)
+		buf.Write(id)
+		flags := e.flags&^(indexExtended|0xfff) | uint16(min(len(e.path), 0xfff))
+		if e.extended != 0 {
+			flags |= indexExtended
+		}
+		buf.Write(binary.BigEndian.AppendUint16(nil, flags))
+		if e.extended != 0 {
+			buf.Write(binary.BigEndian.AppendUint16(nil, e.extended))
+		}
+		buf.WriteString(e.path)
+		// Pad with 1 to 8 NULs to a multiple of 8 bytes.
+		length := buf.Len() - start
+		buf.Write(make([]byte, (length+8)&^7-length))
+	}
+	h := newHash()
+	h.Write(buf.Bytes())
+	buf.Write(h.Sum(nil))
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,460 @@
+package gitutil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Object types, as numbered in pack files.
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+var objectTypes = map[string]int{"commit": objCommit, "tree": objTree, "blob": objBlob, "tag": objTag}
+
+// errObjectNotFound is returned when an object is neither loose nor packed.
+var errObjectNotFound = errors.New("object not found")
+
+// newHash returns the hash function of the repository's object format.
+func (r *Repo) newHash() (func() hash.Hash, error) {
+	config, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	switch format, _ := config.Get("extensions", "", "objectformat"); strings.ToLower(format) {
+	case "", "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported object format %s", format)
+	}
+}
+
+// hashObject returns the ID Git gives an object of the given type and content.
+func hashObject(newHash func() hash.Hash, objType string, data []byte) string {
+	h := newHash()
+	fmt.Fprintf(h, "%s %d\x00", objType, len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// resolveHead returns the commit HEAD points at, or "" on a branch without commits.
+func (r *Repo) resolveHead() (string, error) {
+	data, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		return head, nil
+	}
+	for range 10 {
+		id, err := r.readRef(ref)
+		if err != nil || id == "" {
+			return "", err
+		}
+		if ref, ok = strings.CutPrefix(id, "ref: "); !ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("too many levels of symbolic references from HEAD")
+}
+
+// readRef returns the value of a loose or packed ref, or "" if it does not exist.
+func (r *Repo) readRef(ref string) (string, error) {
+	for _, dir := range []string{r.GitDir, r.CommonDir} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read %s: %w", ref, err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(r.CommonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read packed-refs: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if id, name, ok := strings.Cut(line, " "); ok && name == ref {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// readObject returns the type and content of an object.
+func (r *Repo) readObject(id string) (int, []byte, error) {
+	objType, data, err := r.readLooseObject(id)
+	if !errors.Is(err, errObjectNotFound) {
+		return objType, data, err
+	}
+	packs, err := filepath.Glob(filepath.Join(r.CommonDir, "objects", "pack", "*.idx"))
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.Strings(packs)
+	raw, err := hex.DecodeString(id)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid object ID %q", id)
+	}
+	for _, idx := range packs {
+		offset, found, err := findInPackIndex(idx, raw)
+		if err != nil {
+			return 0, nil, err
+		}
+		if found {
+			return r.readPackedObject(strings.TrimSuffix(idx, ".idx")+".pack", offset, len(raw))
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: %s", errObjectNotFound, id)
+}
+
+func (r *Repo) readLooseObject(id string) (int, []byte, error) {
+	if len(id) < 3 {
+		return 0, nil, fmt.Errorf("invalid object ID %q", id)
+	}
+	f, err := os.Open(filepath.Join(r.CommonDir, "objects", id[:2], id[2:]))
+	if os.IsNotExist(err) {
+		return 0, nil, errObjectNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read object %s: %w", id, err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read object %s: %w", id, err)
+	}
+	header, content, ok := bytes.Cut(data, []byte{0})
+	typeName, size, _ := strings.Cut(string(header), " ")
+	objType, known := objectTypes[typeName]
+	if !ok || !known || size != strconv.Itoa(len(content)) {
+		return 0, nil, fmt.Errorf("object %s is corrupt", id)
+	}
+	return objType, content, nil
+}
+
+// findInPackIndex looks an object up in a version 2 pack index and returns
+// its offset in the pack.
+func findInPackIndex(path string, id []byte) (int64, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	const fanoutStart, fanoutSize = 8, 256 * 4
+	if len(data) < fanoutStart+fanoutSize || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return 0, false, fmt.Errorf("%s is not a version 2 pack index", path)
+	}
+	fanout := func(i int) int {
+		return int(binary.BigEndian.Uint32(data[fanoutStart+4*i:]))
+	}
+	count := fanout(255)
+	namesStart := fanoutStart + fanoutSize
+	offsetsStart := namesStart + count*len(id) + count*4
+	if len(data) < offsetsStart+count*4 {
+		return 0, false, fmt.Errorf("%s is truncated", path)
+	}
+
+	lo := 0
+	if id[0] > 0 {
+		lo = fanout(int(id[0]) - 1)
+	}
+	hi := fanout(int(id[0]))
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		start := namesStart + (lo+i)*len(id)
+		return bytes.Compare(data[start:start+len(id)], id) >= 0
+	})
+	if i >= hi || !bytes.Equal(data[namesStart+i*len(id):namesStart+(i+1)*len(id)], id) {
+		return 0, false, nil
+	}
+
+	offset := binary.BigEndian.Uint32(data[offsetsStart+4*i:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true, nil
+	}
+	// Offsets beyond 2 GiB are kept in a table of 64-bit offsets.
+	large := offsetsStart + count*4 + int(offset&0x7fffffff)*8
+	if len(data) < large+8 {
+		return 0, false, fmt.Errorf("%s is truncated", path)
+	}
+	return int64(binary.BigEndian.Uint64(data[large:])), true, nil
+}
+
+// readPackedObject reads the object at offset in a pack, applying deltas.
+func (r *Repo) readPackedObject(path string, offset int64, idSize int) (int, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	return r.readPackEntry(f, path, offset, idSize, 0)
+}
+
+func (r *Repo) readPackEntry(f *os.File, path string, offset int64, idSize, depth int) (int, []byte, error) {
+	if depth > 64 {
+		return 0, nil, fmt.Errorf("delta chain too long in %s", path)
+	}
+	header := make([]byte, 32)
+	n, err := f.ReadAt(header, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, nil, err
+	}
+	header = header[:n]
+
+	pos := 0
+	next := func() (byte, error) {
+		if pos >= len(header) {
+			return 0, fmt.Errorf("corrupt object header in %s", path)
+		}
+		pos++
+		return header[pos-1], nil
+	}
+	c, err := next()
+	if err != nil {
+		return 0, nil, err
+	}
+	objType := int(c>>4) & 7
+	for c&0x80 != 0 {
+		if c, err = next(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var baseOffset int64
+	var baseID string
+	switch objType {
+	case objOfsDelta:
+		rel, n := readOffset(header[pos:])
+		if n == 0 {
+			return 0, nil, fmt.Errorf("corrupt object header in %s", path)
+		}
+		pos += n
+		baseOffset = offset - int64(rel)
+	case objRefDelta:
+		if len(header) < pos+idSize {
+			return 0, nil, fmt.Errorf("corrupt object header in %s", path)
+		}
+		baseID = hex.EncodeToString(header[pos : pos+idSize])
+		pos += idSize
+	}
+
+	zr, err := zlib.NewReader(io.NewSectionReader(f, offset+int64(pos), 1<<62))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read object in %s: %w", path, err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read object in %s: %w", path, err)
+	}
+
+	var baseType int
+	var base []byte
+	switch objType {
+	case objOfsDelta:
+		baseType, base, err = r.readPackEntry(f, path, baseOffset, idSize, depth+1)
+	case objRefDelta:
+		baseType, base, err = r.readObject(baseID)
+	default:
+		return objType, data, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := applyDelta(base, data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w in %s", err, path)
+	}
+	return baseType, result, nil
+}
+
+// applyDelta builds an object from its base and a pack delta.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	pos := 0
+	varint := func() (int, error) {
+		value, shift := 0, 0
+		for {
+			if pos >= len(delta) {
+				return 0, errors.New("corrupt delta")
+			}
+			c := delta[pos]
+			pos++
+			value |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return value, nil
+			}
+		}
+	}
+	baseSize, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	size, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != len(base) {
+		return nil, errors.New("delta does not match its base")
+	}
+
+	out := make([]byte, 0, size)
+	for pos < len(delta) {
+		op := delta[pos]
+		pos++
+		switch {
+		case op&0x80 != 0:
+			// Copy a range of the base; the bits of op tell which offset and
+			// size bytes follow.
+			var offset, length int
+			for i := 0; i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if pos >= len(delta) {
+					return nil, errors.New("corrupt delta")
+				}
+				if i < 4 {
+					offset |= int(delta[pos]) << (8 * i)
+				} else {
+					length |= int(delta[pos]) << (8 * (i - 4))
+				}
+				pos++
+			}
+			if length == 0 {
+				length = 0x10000
+			}
+			if offset+length > len(base) {
+				return nil, errors.New("corrupt delta")
+			}
+			out = append(out, base[offset:offset+length]...)
+		case op != 0:
+			if pos+int(op) > len(delta) {
+				return nil, errors.New("corrupt delta")
+			}
+			out = append(out, delta[pos:pos+int(op)]...)
+			pos += int(op)
+		default:
+			return nil, errors.New("corrupt delta")
+		}
+	}
+	if len(out) != size {
+		return nil, errors.New("delta result has the wrong size")
+	}
+	return out, nil
+}
+
+// treeEntry is a file of a tree, with its mode and object ID.
+type treeEntry struct {
+	mode uint32
+	id   string
+}
+
+// headTree returns the commit HEAD points at and its tree, or "" for both on
+// a branch without commits.
+func (r *Repo) headTree(idSize int) (string, string, error) {
+	commit, err := r.resolveHead()
+	if err != nil || commit == "" {
+		return "", "", err
+	}
+	objType, data, err := r.readObject(commit)
+	if err != nil {
+		return "", "", err
+	}
+	if objType != objCommit {
+		return "", "", fmt.Errorf("HEAD does not point at a commit")
+	}
+	tree, ok := strings.CutPrefix(string(data), "tree ")
+	if !ok || len(tree) < 2*idSize {
+		return "", "", fmt.Errorf("commit %s is corrupt", commit)
+	}
+	return commit, tree[:2*idSize], nil
+}
+
+// headFiles returns the files of the HEAD commit below the given
+// slash-separated paths, keyed by path. It is empty on a branch without commits.
+func (r *Repo) headFiles(paths []string, idSize int) (map[string]treeEntry, error) {
+	files := map[string]treeEntry{}
+	_, tree, err := r.headTree(idSize)
+	if err != nil || tree == "" {
+		return files, err
+	}
+	return files, r.readTree(tree, "", paths, idSize, files)
+}
+
+// readTree adds the files of a tree that lie below paths to files.
+func (r *Repo) readTree(id, prefix string, paths []string, idSize int, files map[string]treeEntry) error {
+	objType, data, err := r.readObject(id)
+	if err != nil {
+		return err
+	}
+	if objType != objTree {
+		return fmt.Errorf("object %s is not a tree", id)
+	}
+	for len(data) > 0 {
+		header, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok || len(rest) < idSize {
+			return fmt.Errorf("tree %s is corrupt", id)
+		}
+		modeText, name, _ := strings.Cut(string(header), " ")
+		mode, err := strconv.ParseUint(modeText, 8, 32)
+		if err != nil {
+			return fmt.Errorf("tree %s is corrupt", id)
+		}
+		entry := treeEntry{mode: uint32(mode), id: hex.EncodeToString(rest[:idSize])}
+		data = rest[idSize:]
+
+		path := prefix + name
+		switch {
+		case entry.mode == 0o40000:
+			if wanted(path, paths, true) {
+				if err := r.readTree(entry.id, path+"/", paths, idSize, files); err != nil {
+					return err
+				}
+			}
+		case wanted(path, paths, false):
+			files[path] = entry
+		}
+	}
+	return nil
+}
+
+// wanted reports whether path lies below one of paths or, for directories,
+// contains one of them. An empty path stands for the whole tree.
+func wanted(path string, paths []string, dir bool) bool {
+	for _, p := range paths {
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") || dir && strings.HasPrefix(p, path+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Statuses of a Change.
+const (
+	// StatusModified is a tracked file whose working tree copy differs from the index.
+	StatusModified = "modified"
+	// StatusDeleted is a tracked file missing from the working tree.
+	StatusDeleted = "deleted"
+	// StatusStaged is a file whose staged content differs from HEAD.
+	StatusStaged = "staged"
+	// StatusUntracked is a file that is neither tracked nor ignored.
+	StatusUntracked = "untracked"
+	// StatusUnmerged is a file with unresolved merge conflicts.
+	StatusUnmerged = "unmerged"
+)
+
+// Change is a file with uncommitted changes.
+type Change struct {
+	// Path is slash-separated and relative to the root of the working tree.
+	Path   string
+	Status string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s (%s)", c.Path, c.Status)
+}
+
+// Git file modes.
+const (
+	modeTree    = 0o40000
+	modeSymlink = 0o120000
+	modeGitlink = 0o160000
+)
+
+// Status returns the files at or below paths that have uncommitted changes,
+// sorted by path, like git status does. Files whose working tree copy differs
+// from the index take precedence over staged ones. Like Git, a file whose
+// size and modification time match the index is taken as unchanged; the
+// others are hashed, without applying the clean filters and end-of-line
+// conversions configured for the repository.
+func (r *Repo) Status(paths []string) ([]Change, error) {
+	var rels []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(r.Root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside of the repository at %s", p, r.Root)
+		}
+		if rel == "." {
+			rel = ""
+		}
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+
+	newHash, err := r.newHash()
+	if err != nil {
+		return nil, err
+	}
+	idSize := newHash().Size()
+	entries, err := r.readIndex(idSize)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.headFiles(rels, idSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	config, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	fileMode, _ := config.Get("core", "", "filemode")
+
+	changes := map[string]string{}
+	tracked := map[string]bool{}
+	for _, e := range entries {
+		if !wanted(e.path, rels, false) {
+			continue
+		}
+		tracked[e.path] = true
+		if e.stage != 0 {
+			changes[e.path] = StatusUnmerged
+			continue
+		}
+		if e.mode == modeTree {
+			// A directory collapsed by a sparse index.
+			continue
+		}
+		status := ""
+		if file, ok := head[e.path]; !ok || file.id != e.id || file.mode != e.mode || e.intentToAdd {
+			status = StatusStaged
+		}
+		if !e.skipWorktree {
+			worktree, err := r.worktreeStatus(e, newHash, fileMode != "false")
+			if err != nil {
+				return nil, err
+			}
+			if worktree != "" {
+				status = worktree
+			}
+		}
+		if status != "" {
+			changes[e.path] = status
+		}
+	}
+	for path := range head {
+		if !tracked[path] {
+			changes[path] = StatusStaged
+		}
+	}
+
+	ignorer := r.newIgnorer()
+	for _, rel := range rels {
+		if err := r.findUntracked(rel, tracked, ignorer, changes); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Change, 0, len(changes))
+	for path, status := range changes {
+		result = append(result, Change{Path: path, Status: status})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// worktreeStatus compares the working tree copy of an index entry with it.
+func (r *Repo) worktreeStatus(e indexEntry, newHash func() hash.Hash, checkMode bool) (string, error) {
+	path := filepath.Join(r.Root, filepath.FromSlash(e.path))
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) || isNotDir(err) {
+		return StatusDeleted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case e.mode == modeGitlink:
+		// Submodules have their own status.
+		return "", nil
+	case e.mode == modeSymlink:
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return StatusModified, nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if hashObject(newHash, "blob", []byte(filepath.ToSlash(target))) != e.id {
+			return StatusModified, nil
+		}
+		return "", nil
+	case !info.Mode().IsRegular():
+		return StatusModified, nil
+	}
+
+	if checkMode && (info.Mode()&0o111 != 0) != (e.mode&0o111 != 0) {
+		return StatusModified, nil
+	}
+	// The index keeps the size truncated to 32 bits.
+	if uint32(info.Size()) != e.size {
+		return StatusModified, nil
+	}
+	mtime := info.ModTime()
+	if uint32(mtime.Unix()) == e.mtime[0] && uint32(mtime.Nanosecond()) == e.mtime[1] {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if hashObject(newHash, "blob", data) != e.id {
+		return StatusModified, nil
+	}
+	return "", nil
+}
+
+// isNotDir reports whether err is the error for a path whose parent is a file.
+func isNotDir(err error) bool {
+	return errors.Is(err, syscall.ENOTDIR)
+}
+
+// findUntracked adds the files at or below rel that are neither tracked nor
+// ignored to changes.
+func (r *Repo) findUntracked(rel string, tracked map[string]bool, ignorer *ignorer, changes map[string]string) error {
+	start := filepath.Join(r.Root, filepath.FromSlash(rel))
+	if _, err := os.Lstat(start); os.IsNotExist(err) || isNotDir(err) {
+		return nil
+	}
+	return filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == r.Root {
+			return nil
+		}
+		relPath, err := filepath.Rel(r.Root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if tracked[relPath] {
+			// Tracked files were compared with the index already, and the
+			// contents of submodules belong to them.
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || ignorer.ignored(relPath, true) {
+				return filepath.SkipDir
+			}
+			// A nested repository is untracked as a whole.
+			if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+				changes[relPath+"/"] = StatusUntracked
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// A file removed from the index keeps its staged status.
+		if _, ok := changes[relPath]; !ok && !ignorer.ignored(relPath, false) {
+			changes[relPath] = StatusUntracked
+		}
+		return nil
+	})
+}
@@ -0,0 +1,184 @@
+package gitutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// runGit runs git in dir, isolated from the user's configuration.
+func runGit(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	Expect(err).NotTo(HaveOccurred(), string(out))
+	return string(out)
+}
+
+// newGitRepo creates a repository with a commit of the given files.
+func newGitRepo(files map[string]string, initArgs ...string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		Skip("git is not installed")
+	}
+	root := GinkgoT().TempDir()
+	runGit(root, append([]string{"init", "-q", "-b", "main"}, initArgs...)...)
+	for name, content := range files {
+		writeFile(root, name, content)
+	}
+	if len(files) > 0 {
+		runGit(root, "add", "-A")
+		runGit(root, "commit", "-q", "-m", "initial")
+	}
+	return root
+}
+
+func writeFile(root, name, content string) {
+	path := filepath.Join(root, filepath.FromSlash(name))
+	Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+}
+
+func status(root string, paths ...string) []string {
+	repo, err := Open(root)
+	Expect(err).NotTo(HaveOccurred())
+	var abs []string
+	for _, p := range paths {
+		abs = append(abs, filepath.Join(root, filepath.FromSlash(p)))
+	}
+	changes, err := repo.Status(abs)
+	Expect(err).NotTo(HaveOccurred())
+	result := []string{}
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	return result
+}
+
+var _ = Describe("Status", func() {
+	var root string
+
+	BeforeEach(func() {
+		root = newGitRepo(map[string]string{
+			"Makefile":                "include Makefile-common\n",
+			"values-global.yaml":      "global: {}\n",
+			"values-hub.yaml":         "clusterGroup: {}\n",
+			"charts/app/Chart.yaml":   "name: app\n",
+			"charts/app/values.yaml":  "replicas: 1\n",
+			".gitignore":              "*.log\nbuild/\n!keep.log\n",
+			"scripts/run.sh":          "#!/bin/sh\n",
+			"docs/overview/README.md": "# Overview\n",
+		})
+	})
+
+	It("should report nothing on a clean tree", func() {
+		Expect(status(root, ".")).To(BeEmpty())
+	})
+
+	It("should report the kinds of uncommitted changes like git status", func() {
+		writeFile(root, "Makefile", "include Makefile-common\nall:\n")
+		Expect(os.Remove(filepath.Join(root, "charts", "app", "values.yaml"))).To(Succeed())
+		writeFile(root, "values-prod.yaml", "clusterGroup: {}\n")
+		runGit(root, "add", "values-prod.yaml")
+		runGit(root, "rm", "-q", "--cached", "values-hub.yaml")
+		writeFile(root, "notes.txt", "todo\n")
+		writeFile(root, "debug.log", "ignored\n")
+		writeFile(root, "keep.log", "not ignored\n")
+		writeFile(root, "build/output", "ignored\n")
+		Expect(os.Chmod(filepath.Join(root, "scripts", "run.sh"), 0o755)).To(Succeed())
+
+		Expect(status(root, ".")).To(Equal([]string{
+			"Makefile (modified)",
+			"charts/app/values.yaml (deleted)",
+			"keep.log (untracked)",
+			"notes.txt (untracked)",
+			"scripts/run.sh (modified)",
+			"values-hub.yaml (staged)",
+			"values-prod.yaml (staged)",
+		}))
+	})
+
+	It("should only report changes below the given paths", func() {
+		writeFile(root, "Makefile", "changed\n")
+		writeFile(root, "charts/app/templates/deployment.yaml", "kind: Deployment\n")
+		writeFile(root, "docs/overview/notes.md", "notes\n")
+
+		Expect(status(root, "charts", "values-global.yaml", "missing.yaml")).To(Equal([]string{"charts/app/templates/deployment.yaml (untracked)"}))
+		Expect(status(filepath.Join(root, "docs"), "overview")).To(Equal([]string{"docs/overview/notes.md (untracked)"}))
+	})
+
+	It("should not report files that were only touched", func() {
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(filepath.Join(root, "Makefile"), later, later)).To(Succeed())
+		Expect(status(root, ".")).To(BeEmpty())
+	})
+
+	It("should read packed objects and refs and version 4 indexes", func() {
+		for i := range 3 {
+			writeFile(root, "values-hub.yaml", strings.Repeat("clusterGroup: {}\n", 50)+strings.Repeat("x", i)+"\n")
+			runGit(root, "commit", "-q", "-am", "update")
+		}
+		runGit(root, "gc", "-q", "--aggressive")
+		runGit(root, "update-index", "--index-version", "4")
+		Expect(filepath.Join(root, ".git", "packed-refs")).To(BeAnExistingFile())
+		Expect(status(root, ".")).To(BeEmpty())
+
+		writeFile(root, "charts/app/Chart.yaml", "name: other\n")
+		Expect(status(root, ".")).To(Equal([]string{"charts/app/Chart.yaml (modified)"}))
+
+		repo, err := Open(root)
+		Expect(err).NotTo(HaveOccurred())
+		for _, line := range strings.Split(strings.TrimSpace(runGit(root, "rev-list", "--objects", "--all")), "\n") {
+			id, _, _ := strings.Cut(line, " ")
+			objType, data, err := repo.readObject(id)
+			Expect(err).NotTo(HaveOccurred())
+			typeName := strings.TrimSpace(runGit(root, "cat-file", "-t", id))
+			Expect(objType).To(Equal(objectTypes[typeName]), id)
+			Expect(string(data)).To(Equal(runGit(root, "cat-file", typeName, id)), id)
+		}
+	})
+
+	It("should support repositories without commits", func() {
+		root := newGitRepo(nil)
+		writeFile(root, "Makefile", "all:\n")
+		writeFile(root, "values-global.yaml", "global: {}\n")
+		runGit(root, "add", "values-global.yaml")
+		Expect(status(root, ".")).To(Equal([]string{"Makefile (untracked)", "values-global.yaml (staged)"}))
+	})
+
+	It("should support SHA-256 repositories", func() {
+		root := newGitRepo(map[string]string{"Makefile": "all:\n"}, "--object-format=sha256")
+		Expect(status(root, ".")).To(BeEmpty())
+		writeFile(root, "Makefile", "all: build\n")
+		Expect(status(root, ".")).To(Equal([]string{"Makefile (modified)"}))
+	})
+})
+
+var _ = DescribeTable("compileIgnoreRule",
+	func(pattern, base, path string, isDir, matches bool) {
+		rule, ok := compileIgnoreRule(pattern, base)
+		Expect(ok).To(BeTrue())
+		Expect(rule.pattern.MatchString(path) && (!rule.dirOnly || isDir)).To(Equal(matches))
+	},
+	Entry("basename at any depth", "*.log", "", "a/b/debug.log", false, true),
+	Entry("anchored with a leading slash", "/build", "", "a/build", false, false),
+	Entry("anchored with an inner slash", "docs/*.md", "", "docs/a.md", false, true),
+	Entry("star does not cross directories", "docs/*.md", "", "docs/sub/a.md", false, false),
+	Entry("leading double star", "**/temp", "", "a/b/temp", true, true),
+	Entry("trailing double star", "out/**", "", "out/a/b", false, true),
+	Entry("inner double star", "a/**/z", "", "a/b/c/z", false, true),
+	Entry("directories only", "cache/", "", "cache", false, false),
+	Entry("character classes", "file[0-9].txt", "", "file7.txt", false, true),
+	Entry("negated character classes", "file[!0-9].txt", "", "file7.txt", false, false),
+	Entry("escaped characters", `\#notes`, "", "#notes", false, true),
+	Entry("relative to the directory of the file", "*.tmp", "charts/", "charts/app/x.tmp", false, true),
+	Entry("outside the directory of the file", "*.tmp", "charts/", "x.tmp", false, false),
+)
@@ -0,0 +1,380 @@
+package makefile
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Kind is the kind of a Makefile directive.
+type Kind int
+
+// Directive kinds.
+const (
+	// Blank is an empty line.
+	Blank Kind = iota
+	// Comment is a comment line outside of a recipe.
+	Comment
+	// Assignment is a variable assignment, including define blocks and
+	// export or override assignments.
+	Assignment
+	// Rule is a rule line together with its recipe.
+	Rule
+	// Include is an include, -include or sinclude directive.
+	Include
+	// Conditional is an ifeq, ifneq, ifdef, ifndef, else or endif line.
+	Conditional
+	// Other is any other line, such as vpath or unexport.
+	Other
+)
+
+// Directive is a logical line of a Makefile. Continued lines, define blocks
+// and the recipe of a rule belong to the directive that starts them.
+type Directive struct {
+	Kind Kind
+	// Line is the 1-based line the directive starts on.
+	Line int
+	// EndLine is the 1-based last line of the directive.
+	EndLine int
+	// Targets are the targets of a rule.
+	Targets []string
+	// HasRecipe reports whether a rule has a recipe. Rules without one only
+	// add prerequisites and do not replace the recipe of another rule.
+	HasRecipe bool
+	// Variable is the name of an assigned variable.
+	Variable string
+	// Value is the value of an assignment, after the operator.
+	Value string
+	// Files are the files of an include directive.
+	Files []string
+}
+
+// File is a parsed Makefile.
+type File struct {
+	Lines      []string
+	Directives []Directive
+}
+
+// Parse splits a Makefile into directives. It does not expand variables or
+// evaluate conditionals; both branches of a conditional are parsed.
+func Parse(data []byte) *File {
+	text := strings.TrimSuffix(string(data), "\n")
+	f := &File{}
+	if text != "" {
+		f.Lines = strings.Split(text, "\n")
+	}
+
+	inRule := false
+	for i := 0; i < len(f.Lines); i++ {
+		start := i
+		raw := f.Lines[i]
+
+		// Recipe lines start with a tab and belong to the preceding rule, like
+		// the comments and blank lines between them.
+		if inRule && strings.HasPrefix(raw, "\t") {
+			for strings.HasSuffix(f.Lines[i], `\`) && i+1 < len(f.Lines) {
+				i++
+			}
+			rule := &f.Directives[len(f.Directives)-1]
+			rule.HasRecipe = true
+			rule.EndLine = i + 1
+			continue
+		}
+
+		logical := raw
+		for strings.HasSuffix(logical, `\`) && i+1 < len(f.Lines) {
+			i++
+			// Like make, replace the backslash, newline and surrounding
+			// whitespace with a single space.
+			logical = strings.TrimRight(strings.TrimSuffix(logical, `\`), " \t") + " " + strings.TrimSpace(f.Lines[i])
+		}
+		d := Directive{Line: start + 1, EndLine: i + 1}
+		trimmed := strings.TrimSpace(logical)
+		word, rest := firstWord(trimmed)
+
+		switch {
+		case trimmed == "":
+			d.Kind = Blank
+		case strings.HasPrefix(trimmed, "#"):
+			d.Kind = Comment
+		case word == "define" || isModifier(word) && strings.HasPrefix(rest, "define "):
+			d.Kind = Assignment
+			declaration := rest
+			if word != "define" {
+				_, declaration = firstWord(rest)
+			}
+			d.Variable, _ = firstWord(declaration)
+			for i+1 < len(f.Lines) && strings.TrimSpace(f.Lines[i]) != "endef" {
+				i++
+			}
+			d.EndLine = i + 1
+		case word == "include" || word == "-include" || word == "sinclude":
+			d.Kind = Include
+			d.Files = strings.Fields(stripComment(rest))
+		case word == "ifeq" || word == "ifneq" || word == "ifdef" || word == "ifndef" || word == "else" || word == "endif":
+			d.Kind = Conditional
+		default:
+			d.Kind, d.Targets, d.Variable, d.Value = classify(stripComment(trimmed))
+			if d.Kind == Rule {
+				_, recipe, ok := strings.Cut(afterTargets(stripComment(trimmed)), ";")
+				d.HasRecipe = ok && strings.TrimSpace(recipe) != ""
+			}
+		}
+
+		switch d.Kind {
+		case Rule:
+			inRule = true
+		case Blank, Comment:
+		default:
+			inRule = false
+		}
+		// Blank lines and comments followed by more recipe lines are part of
+		// the recipe.
+		if inRule && (d.Kind == Blank || d.Kind == Comment) && nextIsRecipe(f.Lines, i+1) {
+			f.Directives[len(f.Directives)-1].EndLine = i + 1
+			continue
+		}
+		f.Directives = append(f.Directives, d)
+	}
+	return f
+}
+
+// nextIsRecipe reports whether the next non-blank, non-comment line from index
+// i is a recipe line.
+func nextIsRecipe(lines []string, i int) bool {
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(lines[i], "\t") {
+			continue
+		}
+		return strings.HasPrefix(lines[i], "\t")
+	}
+	return false
+}
+
+func firstWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// isModifier reports whether word may precede an assignment.
+func isModifier(word string) bool {
+	return word == "export" || word == "override" || word == "private"
+}
+
+// stripComment removes a trailing comment that is not escaped.
+func stripComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '#' {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}
+
+// classify tells rules from assignments by the first ':' or '=' outside of
+// variable references.
+func classify(line string) (kind Kind, targets []string, variable, value string) {
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '$' && i+1 < len(line) && (line[i+1] == '(' || line[i+1] == '{'):
+			depth++
+			i++
+		case (c == ')' || c == '}') && depth > 0:
+			depth--
+		case depth > 0:
+		case c == '=':
+			name := strings.TrimRight(line[:i], "?+!:")
+			word, rest := firstWord(name)
+			if isModifier(word) {
+				name = rest
+			}
+			return Assignment, nil, strings.TrimSpace(name), strings.TrimSpace(line[i+1:])
+		case c == ':':
+			// ::=, :::= and := are assignments.
+			j := i
+			for j < len(line) && line[j] == ':' {
+				j++
+			}
+			if j < len(line) && line[j] == '=' {
+				i = j - 1
+				continue
+			}
+			return Rule, strings.Fields(line[:i]), "", ""
+		}
+	}
+	word, _ := firstWord(line)
+	if isModifier(word) || word == "unexport" {
+		return Assignment, nil, "", ""
+	}
+	return Other, nil, "", ""
+}
+
+// afterTargets returns what follows the targets of a rule line.
+func afterTargets(line string) string {
+	_, rest, _ := strings.Cut(line, ":")
+	return strings.TrimLeft(rest, ":")
+}
+
+// Includes reports whether the Makefile includes a file with the given name.
+func (f *File) Includes(name string) bool {
+	return f.includeLine(name) > 0
+}
+
+// includeLine returns the line of the first include of name, or 0.
+func (f *File) includeLine(name string) int {
+	for _, d := range f.Directives {
+		if d.Kind != Include {
+			continue
+		}
+		for _, file := range d.Files {
+			if file == name || path.Base(file) == name {
+				return d.Line
+			}
+		}
+	}
+	return 0
+}
+
+// DefaultGoal returns the goal make runs without arguments: .DEFAULT_GOAL if
+// set, otherwise the first target that is neither special nor a pattern.
+func (f *File) DefaultGoal() string {
+	goal, defaultGoal := "", ""
+	for _, d := range f.Directives {
+		if d.Kind == Assignment && d.Variable == ".DEFAULT_GOAL" {
+			defaultGoal = d.Value
+		}
+		if d.Kind == Rule && goal == "" {
+			for _, target := range d.Targets {
+				if !strings.HasPrefix(target, ".") && !strings.Contains(target, "%") {
+					goal = target
+					break
+				}
+			}
+		}
+	}
+	if defaultGoal != "" {
+		return defaultGoal
+	}
+	return goal
+}
+
+// Assigns reports whether the Makefile assigns the variable name.
+func (f *File) Assigns(name string) bool {
+	for _, d := range f.Directives {
+		if d.Kind == Assignment && d.Variable == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RecipeTargets returns the targets that rules with a recipe define, mapped to
+// the line of their last definition, which is the one make uses.
+func (f *File) RecipeTargets() map[string]int {
+	return f.targets(true)
+}
+
+// Targets returns the targets that rules define, mapped to the line of their
+// last definition.
+func (f *File) Targets() map[string]int {
+	return f.targets(false)
+}
+
+func (f *File) targets(recipeOnly bool) map[string]int {
+	targets := map[string]int{}
+	for _, d := range f.Directives {
+		if d.Kind != Rule || recipeOnly && !d.HasRecipe {
+			continue
+		}
+		for _, target := range d.Targets {
+			if !strings.HasPrefix(target, ".") && !strings.Contains(target, "%") {
+				targets[target] = d.Line
+			}
+		}
+	}
+	return targets
+}
+
+// InsertionLine returns the 0-based index of the line before which an include
+// should be inserted: after the leading comments, blank lines and variable
+// assignments, which may set variables the included file reads, and before
+// the first rule, include or conditional together with the comment right
+// above it.
+func (f *File) InsertionLine() int {
+	index := len(f.Lines)
+	for _, d := range f.Directives {
+		if d.Kind == Blank || d.Kind == Comment || d.Kind == Assignment {
+			continue
+		}
+		index = d.Line - 1
+		break
+	}
+	for index > 0 && strings.HasPrefix(strings.TrimSpace(f.Lines[index-1]), "#") {
+		index--
+	}
+	return index
+}
+
+// InsertInclude returns data with an include of name inserted at
+// InsertionLine, and the 1-based line of the include.
+func InsertInclude(data []byte, name string) ([]byte, int) {
+	f := Parse(data)
+	index := f.InsertionLine()
+	include := []string{"include " + name}
+	if index > 0 && strings.TrimSpace(f.Lines[index-1]) != "" {
+		include = append([]string{""}, include...)
+	}
+	if index < len(f.Lines) && strings.TrimSpace(f.Lines[index]) != "" {
+		include = append(include, "")
+	}
+
+	lines := append(append(append([]string{}, f.Lines[:index]...), include...), f.Lines[index:]...)
+	line := index + 1
+	if include[0] == "" {
+		line++
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), line
+}
+
+// Collision is a target that both a Makefile and a file it includes define,
+// where the Makefile gives it a recipe.
+type Collision struct {
+	Target string
+	// Line is the line of the Makefile's rule.
+	Line int
+	// IncludedRecipe reports whether the included file gives the target a
+	// recipe too. Only one of the recipes is used; otherwise the Makefile's
+	// recipe runs after the prerequisites of both.
+	IncludedRecipe bool
+	// Overrides reports whether the Makefile's recipe wins over the included
+	// one, because its rule comes after the include.
+	Overrides bool
+}
+
+// Collisions returns the targets f defines recipes for that the included
+// file name, parsed as included, also defines, sorted by target.
+func (f *File) Collisions(name string, included *File) []Collision {
+	includeLine := f.includeLine(name)
+	theirs := included.Targets()
+	theirRecipes := included.RecipeTargets()
+	var collisions []Collision
+	for target, line := range f.RecipeTargets() {
+		if _, ok := theirs[target]; !ok {
+			continue
+		}
+		_, recipe := theirRecipes[target]
+		collisions = append(collisions, Collision{Target: target, Line: line, IncludedRecipe: recipe, Overrides: line > includeLine})
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Target < collisions[j].Target })
+	return collisions
+}
@@ -0,0 +1,99 @@
+package makefile
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const sample = `# Pattern Makefile
+NAME ?= demo
+EXTRA := a \
+	b
+define SCRIPT
+echo: not a rule
+endef
+
+.PHONY: build
+# Builds everything
+build: deps ## Build
+	@echo building
+
+	# still the recipe
+	@echo done
+test: ; go test ./...
+install: extra
+-include local.mk # Makefile-common
+ifeq ($(NAME),demo)
+%.o: %.c
+	cc $<
+endif
+`
+
+var _ = Describe("Parse", func() {
+	var f *File
+
+	BeforeEach(func() {
+		f = Parse([]byte(sample))
+	})
+
+	It("should split the Makefile into directives", func() {
+		var kinds []Kind
+		for _, d := range f.Directives {
+			kinds = append(kinds, d.Kind)
+		}
+		Expect(kinds).To(Equal([]Kind{
+			Comment, Assignment, Assignment, Assignment, Blank,
+			Rule, Comment, Rule, Rule, Rule, Include, Conditional, Rule, Conditional,
+		}))
+	})
+
+	It("should record assignments, rules and includes", func() {
+		Expect(f.Directives[1]).To(HaveField("Variable", "NAME"))
+		Expect(f.Directives[2]).To(And(HaveField("Variable", "EXTRA"), HaveField("Value", "a b"), HaveField("EndLine", 4)))
+		Expect(f.Directives[3]).To(And(HaveField("Variable", "SCRIPT"), HaveField("Line", 5), HaveField("EndLine", 7)))
+		Expect(f.Directives[7]).To(And(HaveField("Targets", []string{"build"}), HaveField("HasRecipe", true), HaveField("EndLine", 15)))
+		Expect(f.Directives[8]).To(And(HaveField("Targets", []string{"test"}), HaveField("HasRecipe", true)))
+		Expect(f.Directives[9]).To(HaveField("HasRecipe", false))
+		Expect(f.Directives[10]).To(HaveField("Files", []string{"local.mk"}))
+	})
+
+	It("should only match real include directives", func() {
+		Expect(f.Includes("Makefile-common")).To(BeFalse())
+		Expect(f.Includes("local.mk")).To(BeTrue())
+		Expect(Parse([]byte("sinclude $(CURDIR)/Makefile-common\n")).Includes("Makefile-common")).To(BeTrue())
+	})
+
+	It("should find the default goal and the targets with recipes", func() {
+		Expect(f.DefaultGoal()).To(Equal("build"))
+		Expect(Parse([]byte("build:\n\ttrue\n.DEFAULT_GOAL := test\n")).DefaultGoal()).To(Equal("test"))
+		Expect(f.RecipeTargets()).To(Equal(map[string]int{"build": 11, "test": 16}))
+	})
+})
+
+var _ = DescribeTable("InsertInclude",
+	func(content, expected string, expectedLine int) {
+		updated, line := InsertInclude([]byte(content), "Makefile-common")
+		Expect(string(updated)).To(Equal(expected))
+		Expect(line).To(Equal(expectedLine))
+	},
+	Entry("empty file", "", "include Makefile-common\n", 1),
+	Entry("rules only", "all:\n\ttrue\n", "include Makefile-common\n\nall:\n\ttrue\n", 1),
+	Entry("after variables and before the comment of the first rule",
+		"# Header\n\n.DEFAULT_GOAL := all\nNAME = x\n# Builds\nall:\n\ttrue\n",
+		"# Header\n\n.DEFAULT_GOAL := all\nNAME = x\n\ninclude Makefile-common\n\n# Builds\nall:\n\ttrue\n", 6),
+	Entry("variables only", "NAME = x\n", "NAME = x\n\ninclude Makefile-common\n", 3),
+	Entry("before a conditional", "NAME = x\n\nifdef CI\nall:\n\ttrue\nendif\n",
+		"NAME = x\n\ninclude Makefile-common\n\nifdef CI\nall:\n\ttrue\nendif\n", 3),
+)
+
+var _ = Describe("Collisions", func() {
+	It("should tell whether the local or the included recipe wins", func() {
+		common := Parse([]byte("help:\n\t@echo help\ninstall: pattern-install\nshow:\n\t@echo show\nlint:\n\t@echo lint\n"))
+		local := Parse([]byte("help:\n\t@echo mine\n\ninclude Makefile-common\n\ninstall:\n\t@echo mine\nshow: extra\nlint:\n\ttrue\nbuild:\n\ttrue\n"))
+		Expect(local.Collisions("Makefile-common", common)).To(Equal([]Collision{
+			{Target: "help", Line: 1, IncludedRecipe: true, Overrides: false},
+			{Target: "install", Line: 6, IncludedRecipe: false, Overrides: true},
+			{Target: "lint", Line: 9, IncludedRecipe: true, Overrides: true},
+		}))
+	})
+})
@@ -0,0 +1,380 @@
+package manifests
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/types"
+	"github.com/validatedpatterns/patternizer/internal/values"
+)
+
+const (
+	// ChartRepoURL is the Helm repository of the charts published by Validated Patterns.
+	ChartRepoURL = "https://charts.validatedpatterns.io"
+	// InClusterServer is the Argo CD destination of the local cluster.
+	InClusterServer = "https://kubernetes.default.svc"
+	// SingleArgoNamespace is the namespace of the Argo CD instance used when
+	// global.singleArgoCD is set.
+	SingleArgoNamespace = "openshift-gitops"
+	// PatternRef is the name of the pattern repository source of multi-source applications.
+	PatternRef = "patternref"
+)
+
+// Default fields of subscriptions, as set by the clustergroup chart.
+const (
+	defaultSubscriptionNamespace = "openshift-operators"
+	defaultSubscriptionSource    = "redhat-operators"
+	defaultSourceNamespace       = "openshift-marketplace"
+	defaultApproval              = "Automatic"
+)
+
+// Manifest is a Kubernetes object.
+type Manifest map[string]interface{}
+
+// Kind returns the kind of the object.
+func (m Manifest) Kind() string {
+	kind, _ := m["kind"].(string)
+	return kind
+}
+
+// Name returns the name of the object.
+func (m Manifest) Name() string {
+	metadata, _ := m["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+// Options holds what the clustergroup chart learns from the Pattern CR and
+// the cluster rather than from the values files.
+type Options struct {
+	// RepoURL is the Git URL of the pattern repository.
+	RepoURL string
+	// TargetRevision is the branch, tag or commit of the pattern repository.
+	TargetRevision string
+	// Cluster selects the platform, version and name of the cluster. Its
+	// ClusterGroup is taken from the cluster group values.
+	Cluster values.Cluster
+}
+
+// Render returns the Namespace, OperatorGroup, Subscription, AppProject and
+// Application objects the clustergroup chart creates for a cluster group, in
+// that order and sorted by name within each kind.
+func Render(global *types.ValuesGlobal, clusterGroup *types.ValuesClusterGroup, opts Options) ([]Manifest, error) {
+	r := &renderer{global: global, cg: &clusterGroup.ClusterGroup, opts: opts}
+	r.opts.Cluster.ClusterGroup = r.cg.Name
+	if r.global.Global.Pattern == "" {
+		return nil, fmt.Errorf("global.pattern is not set")
+	}
+	if r.cg.Name == "" {
+		return nil, fmt.Errorf("clusterGroup.name is not set")
+	}
+	r.argoNamespace = SingleArgoNamespace
+	if !global.Global.SingleArgoCD {
+		r.argoNamespace = fmt.Sprintf("%s-%s", global.Global.Pattern, r.cg.Name)
+	}
+	if err := r.buildTemplateValues(clusterGroup); err != nil {
+		return nil, err
+	}
+
+	manifests := r.namespaces()
+	manifests = append(manifests, r.operatorGroups()...)
+	manifests = append(manifests, r.subscriptions()...)
+	manifests = append(manifests, r.projects()...)
+	applications, err := r.applications()
+	if err != nil {
+		return nil, err
+	}
+	return append(manifests, applications...), nil
+}
+
+// Encode writes manifests as a multi-document YAML stream.
+func Encode(manifests []Manifest) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, manifest := range manifests {
+		if err := encoder.Encode(map[string]interface{}(manifest)); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s: %w", manifest.Kind(), manifest.Name(), err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderer holds the state shared by the objects of one cluster group.
+type renderer struct {
+	global        *types.ValuesGlobal
+	cg            *types.ClusterGroup
+	opts          Options
+	argoNamespace string
+	// templateValues is .Values for the value file templates.
+	templateValues map[string]interface{}
+}
+
+// buildTemplateValues merges the global and cluster group values, and the
+// cluster parameters, into the .Values used by value file templates.
+func (r *renderer) buildTemplateValues(clusterGroup *types.ValuesClusterGroup) error {
+	merged := values.New()
+	for _, source := range []interface{}{r.global, clusterGroup} {
+		data, err := yaml.Marshal(source)
+		if err != nil {
+			return fmt.Errorf("failed to marshal values: %w", err)
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("failed to unmarshal values: %w", err)
+		}
+		merged.Merge(tree, "")
+	}
+	for name, value := range r.parameters() {
+		merged.Set(name, value, "")
+	}
+	r.templateValues = merged.Tree
+	return nil
+}
+
+// parameters returns the global values the chart passes to every application.
+func (r *renderer) parameters() map[string]string {
+	params := map[string]string{
+		"global.repoURL":        r.opts.RepoURL,
+		"global.targetRevision": r.opts.TargetRevision,
+		"global.namespace":      r.argoNamespace,
+		"global.pattern":        r.global.Global.Pattern,
+	}
+	if r.opts.Cluster.Platform != "" {
+		params["global.clusterPlatform"] = r.opts.Cluster.Platform
+	}
+	if r.opts.Cluster.ClusterVersion != "" {
+		params["global.clusterVersion"] = r.opts.Cluster.ClusterVersion
+	}
+	if r.opts.Cluster.LocalClusterName != "" {
+		params["global.localClusterName"] = r.opts.Cluster.LocalClusterName
+	}
+	return params
+}
+
+func (r *renderer) namespaces() []Manifest {
+	manifests := []Manifest{}
+	for _, name := range slices.Sorted(maps.Keys(r.cg.Namespaces)) {
+		settings, _ := r.cg.Namespaces[name].(map[string]interface{})
+		labels := map[string]interface{}{
+			"argocd.argoproj.io/managed-by": fmt.Sprintf("%s-%s", r.global.Global.Pattern, r.cg.Name),
+		}
+		if extra, ok := settings["labels"].(map[string]interface{}); ok {
+			for key, value := range extra {
+				labels[key] = fmt.Sprint(value)
+			}
+		}
+		metadata := map[string]interface{}{"name": name, "labels": labels}
+		if annotations, ok := settings["annotations"].(map[string]interface{}); ok && len(annotations) > 0 {
+			metadata["annotations"] = annotations
+		}
+		manifests = append(manifests, Manifest{"apiVersion": "v1", "kind": "Namespace", "metadata": metadata})
+	}
+	return manifests
+}
+
+func (r *renderer) operatorGroups() []Manifest {
+	manifests := []Manifest{}
+	for _, name := range slices.Sorted(maps.Keys(r.cg.Namespaces)) {
+		settings, _ := r.cg.Namespaces[name].(map[string]interface{})
+		if enabled, _ := settings["operatorGroup"].(bool); !enabled {
+			continue
+		}
+		spec := map[string]interface{}{}
+		// A missing targetNamespaces watches the namespace itself; an empty
+		// list watches all namespaces.
+		targets, ok := settings["targetNamespaces"]
+		switch list, _ := targets.([]interface{}); {
+		case !ok || targets == nil:
+			spec["targetNamespaces"] = []interface{}{name}
+		case len(list) > 0:
+			spec["targetNamespaces"] = list
+		}
+		manifests = append(manifests, Manifest{
+			"apiVersion": "operators.coreos.com/v1",
+			"kind":       "OperatorGroup",
+			"metadata":   map[string]interface{}{"name": name + "-operator-group", "namespace": name},
+			"spec":       spec,
+		})
+	}
+	return manifests
+}
+
+func (r *renderer) subscriptions() []Manifest {
+	manifests := []Manifest{}
+	options, _ := r.global.Global.OtherFields["options"].(map[string]interface{})
+	for _, key := range slices.Sorted(maps.Keys(r.cg.Subscriptions)) {
+		sub := r.cg.Subscriptions[key]
+		approval, _ := sub.OtherFields["installPlanApproval"].(string)
+		globalApproval, _ := options["installPlanApproval"].(string)
+		sourceNamespace, _ := sub.OtherFields["sourceNamespace"].(string)
+
+		spec := map[string]interface{}{
+			"name":                sub.Name,
+			"source":              cmp.Or(sub.Source, defaultSubscriptionSource),
+			"sourceNamespace":     cmp.Or(sourceNamespace, defaultSourceNamespace),
+			"installPlanApproval": cmp.Or(approval, globalApproval, defaultApproval),
+		}
+		if sub.Channel != "" {
+			spec["channel"] = sub.Channel
+		}
+		useCSV, ok := options["useCSV"].(bool)
+		if csv, _ := sub.OtherFields["csv"].(string); csv != "" && (useCSV || !ok) {
+			spec["startingCSV"] = csv
+		}
+		manifests = append(manifests, Manifest{
+			"apiVersion": "operators.coreos.com/v1alpha1",
+			"kind":       "Subscription",
+			"metadata":   map[string]interface{}{"name": sub.Name, "namespace": cmp.Or(sub.Namespace, defaultSubscriptionNamespace)},
+			"spec":       spec,
+		})
+	}
+	return manifests
+}
+
+func (r *renderer) projects() []Manifest {
+	manifests := []Manifest{}
+	projects := append([]string{}, r.cg.Projects...)
+	sort.Strings(projects)
+	for _, project := range projects {
+		manifests = append(manifests, Manifest{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "AppProject",
+			"metadata":   map[string]interface{}{"name": project, "namespace": r.argoNamespace},
+			"spec": map[string]interface{}{
+				"sourceRepos":                []interface{}{"*"},
+				"destinations":               []interface{}{map[string]interface{}{"namespace": "*", "server": InClusterServer}},
+				"clusterResourceWhitelist":   []interface{}{map[string]interface{}{"group": "*", "kind": "*"}},
+				"namespaceResourceWhitelist": []interface{}{map[string]interface{}{"group": "*", "kind": "*"}},
+			},
+		})
+	}
+	return manifests
+}
+
+func (r *renderer) applications() ([]Manifest, error) {
+	multiSource := r.global.Main.MultiSourceConfig.Enabled
+	options, _ := r.global.Global.OtherFields["options"].(map[string]interface{})
+	globalSync, _ := options["syncPolicy"].(string)
+
+	sharedFiles, err := values.StringList(r.cg.OtherFields["sharedValueFiles"], "clusterGroup.sharedValueFiles")
+	if err != nil {
+		return nil, err
+	}
+	extraFiles, err := values.StringList(r.global.Global.OtherFields["extraValueFiles"], "global.extraValueFiles")
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []Manifest{}
+	for _, key := range slices.Sorted(maps.Keys(r.cg.Applications)) {
+		app := r.cg.Applications[key]
+		appFiles, err := values.StringList(app.OtherFields["extraValueFiles"], "extraValueFiles of application "+key)
+		if err != nil {
+			return nil, err
+		}
+		files := append(values.HierarchyFiles(r.opts.Cluster), extraFiles...)
+		for _, file := range append(append([]string{}, sharedFiles...), appFiles...) {
+			rendered, err := values.RenderValueFile(file, r.templateValues)
+			if errors.Is(err, values.ErrMissingValue) {
+				// The chart would list a file that does not exist, which
+				// ignoreMissingValueFiles skips.
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("application %s: cannot parse value file %q: %w", key, file, err)
+			}
+			files = append(files, rendered)
+		}
+
+		helm := map[string]interface{}{
+			"ignoreMissingValueFiles": true,
+			"parameters":              r.appParameters(app),
+		}
+		source := map[string]interface{}{}
+		switch {
+		case app.Chart != "":
+			source["repoURL"] = ChartRepoURL
+			source["chart"] = app.Chart
+			source["targetRevision"] = app.ChartVersion
+		case app.OtherFields["repoURL"] != nil:
+			source["repoURL"] = app.OtherFields["repoURL"]
+			source["path"] = app.Path
+			source["targetRevision"] = app.ChartVersion
+		default:
+			source["repoURL"] = r.opts.RepoURL
+			source["path"] = app.Path
+			source["targetRevision"] = r.opts.TargetRevision
+		}
+
+		spec := map[string]interface{}{
+			"project":     cmp.Or(app.Project, "default"),
+			"destination": map[string]interface{}{"server": InClusterServer, "namespace": app.Namespace},
+		}
+		if multiSource {
+			// Absolute paths are in the pattern repository, relative ones in
+			// the source of the application.
+			for i, file := range files {
+				if strings.HasPrefix(file, "/") {
+					files[i] = "$" + PatternRef + file
+				}
+			}
+			helm["valueFiles"] = files
+			source["helm"] = helm
+			spec["sources"] = []interface{}{
+				map[string]interface{}{"repoURL": r.opts.RepoURL, "targetRevision": r.opts.TargetRevision, "ref": PatternRef},
+				source,
+			}
+		} else {
+			// Without multiple sources only charts from the pattern
+			// repository can read its values files.
+			if app.Chart == "" && app.OtherFields["repoURL"] == nil {
+				helm["valueFiles"] = files
+			}
+			source["helm"] = helm
+			spec["source"] = source
+		}
+
+		syncPolicy, _ := app.OtherFields["syncPolicy"].(string)
+		if cmp.Or(syncPolicy, globalSync, "Automatic") == "Automatic" {
+			spec["syncPolicy"] = map[string]interface{}{
+				"automated": map[string]interface{}{},
+				"retry":     map[string]interface{}{"limit": 20},
+			}
+		}
+		if ignore, ok := app.OtherFields["ignoreDifferences"]; ok {
+			spec["ignoreDifferences"] = ignore
+		}
+
+		manifests = append(manifests, Manifest{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata":   map[string]interface{}{"name": cmp.Or(app.Name, key), "namespace": r.argoNamespace},
+			"spec":       spec,
+		})
+	}
+	return manifests, nil
+}
+
+// appParameters returns the Helm parameters of an application: the global
+// parameters followed by its overrides.
+func (r *renderer) appParameters(app types.Application) []interface{} {
+	params := r.parameters()
+	list := make([]interface{}, 0, len(params))
+	for _, name := range slices.Sorted(maps.Keys(params)) {
+		list = append(list, map[string]interface{}{"name": name, "value": params[name]})
+	}
+	overrides, _ := app.OtherFields["overrides"].([]interface{})
+	return append(list, overrides...)
+}
@@ -0,0 +1,231 @@
+package manifests
+
+import (
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/types"
+	"github.com/validatedpatterns/patternizer/internal/values"
+)
+
+// find returns the manifest of the given kind and name.
+func find(manifests []Manifest, kind, name string) Manifest {
+	for _, manifest := range manifests {
+		if manifest.Kind() == kind && manifest.Name() == name {
+			return manifest
+		}
+	}
+	Fail("no " + kind + " named " + name)
+	return nil
+}
+
+// field returns a nested field of a manifest.
+func field(manifest Manifest, keys ...string) interface{} {
+	var current interface{} = map[string]interface{}(manifest)
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+var _ = Describe("Render", func() {
+	var (
+		global       *types.ValuesGlobal
+		clusterGroup *types.ValuesClusterGroup
+		opts         Options
+	)
+
+	BeforeEach(func() {
+		global = types.NewDefaultValuesGlobal()
+		Expect(yaml.Unmarshal([]byte(`global:
+  pattern: demo
+  singleArgoCD: false
+  options:
+    installPlanApproval: Manual
+  extraValueFiles:
+    - /extra/values-common.yaml
+main:
+  clusterGroupName: hub
+  multiSourceConfig:
+    enabled: true
+`), global)).To(Succeed())
+
+		clusterGroup = &types.ValuesClusterGroup{}
+		Expect(yaml.Unmarshal([]byte(`clusterGroup:
+  name: hub
+  sharedValueFiles:
+    - '/overrides/values-{{ $.Values.global.clusterPlatform }}.yaml'
+  namespaces:
+    - demo
+    - operators:
+        operatorGroup: true
+        labels:
+          team: a
+    - all:
+        operatorGroup: true
+        targetNamespaces: []
+  projects:
+    - hub
+  subscriptions:
+    acm:
+      name: advanced-cluster-management
+      namespace: open-cluster-management
+      channel: release-2.11
+      csv: advanced-cluster-management.v2.11.0
+  applications:
+    app:
+      name: app
+      namespace: demo
+      project: hub
+      path: charts/app
+      extraValueFiles:
+        - values-extra.yaml
+      overrides:
+        - name: replicas
+          value: "2"
+    vault:
+      name: vault
+      namespace: vault
+      chart: hashicorp-vault
+      chartVersion: 0.1.*
+      syncPolicy: Manual
+`), clusterGroup)).To(Succeed())
+
+		opts = Options{
+			RepoURL:        "https://github.com/example/demo.git",
+			TargetRevision: "main",
+			Cluster:        values.Cluster{Platform: "AWS"},
+		}
+	})
+
+	It("should render the objects in kind order", func() {
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		var kinds []string
+		for _, manifest := range manifests {
+			kinds = append(kinds, manifest.Kind()+"/"+manifest.Name())
+		}
+		Expect(kinds).To(Equal([]string{
+			"Namespace/all", "Namespace/demo", "Namespace/operators",
+			"OperatorGroup/all-operator-group", "OperatorGroup/operators-operator-group",
+			"Subscription/advanced-cluster-management",
+			"AppProject/hub",
+			"Application/app", "Application/vault",
+		}))
+	})
+
+	It("should render namespaces and operator groups", func() {
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(field(find(manifests, "Namespace", "operators"), "metadata", "labels")).To(Equal(map[string]interface{}{
+			"argocd.argoproj.io/managed-by": "demo-hub",
+			"team":                          "a",
+		}))
+		Expect(field(find(manifests, "OperatorGroup", "operators-operator-group"), "spec", "targetNamespaces")).To(Equal([]interface{}{"operators"}))
+		Expect(field(find(manifests, "OperatorGroup", "all-operator-group"), "spec")).To(BeEmpty())
+	})
+
+	It("should render subscriptions with the chart defaults", func() {
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		spec := field(find(manifests, "Subscription", "advanced-cluster-management"), "spec")
+		Expect(spec).To(Equal(map[string]interface{}{
+			"name":                "advanced-cluster-management",
+			"channel":             "release-2.11",
+			"source":              "redhat-operators",
+			"sourceNamespace":     "openshift-marketplace",
+			"installPlanApproval": "Manual",
+			"startingCSV":         "advanced-cluster-management.v2.11.0",
+		}))
+	})
+
+	It("should render multi-source applications", func() {
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		app := find(manifests, "Application", "app")
+		Expect(field(app, "metadata", "namespace")).To(Equal("demo-hub"))
+		Expect(field(app, "spec", "project")).To(Equal("hub"))
+		Expect(field(app, "spec", "syncPolicy", "automated")).NotTo(BeNil())
+
+		sources := field(app, "spec", "sources").([]interface{})
+		Expect(sources).To(HaveLen(2))
+		Expect(sources[0]).To(Equal(map[string]interface{}{
+			"repoURL":        "https://github.com/example/demo.git",
+			"targetRevision": "main",
+			"ref":            PatternRef,
+		}))
+		source := Manifest(sources[1].(map[string]interface{}))
+		Expect(field(source, "path")).To(Equal("charts/app"))
+		Expect(field(source, "helm", "ignoreMissingValueFiles")).To(BeTrue())
+		Expect(field(source, "helm", "valueFiles")).To(Equal([]string{
+			"$patternref/values-global.yaml",
+			"$patternref/values-hub.yaml",
+			"$patternref/values-AWS.yaml",
+			"$patternref/values-AWS-hub.yaml",
+			"$patternref/extra/values-common.yaml",
+			"$patternref/overrides/values-AWS.yaml",
+			"values-extra.yaml",
+		}))
+		Expect(field(source, "helm", "parameters")).To(ContainElements(
+			map[string]interface{}{"name": "global.pattern", "value": "demo"},
+			map[string]interface{}{"name": "global.clusterPlatform", "value": "AWS"},
+			map[string]interface{}{"name": "replicas", "value": "2"},
+		))
+
+		vault := find(manifests, "Application", "vault")
+		Expect(field(vault, "spec", "syncPolicy")).To(BeNil())
+		chart := Manifest(field(vault, "spec", "sources").([]interface{})[1].(map[string]interface{}))
+		Expect(field(chart, "repoURL")).To(Equal(ChartRepoURL))
+		Expect(field(chart, "chart")).To(Equal("hashicorp-vault"))
+		Expect(field(chart, "targetRevision")).To(Equal("0.1.*"))
+	})
+
+	It("should render a single source without multi-source", func() {
+		global.Main.MultiSourceConfig.Enabled = false
+		global.Global.SingleArgoCD = true
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		app := find(manifests, "Application", "app")
+		Expect(field(app, "metadata", "namespace")).To(Equal(SingleArgoNamespace))
+		Expect(field(app, "spec", "sources")).To(BeNil())
+		Expect(field(app, "spec", "source", "helm", "valueFiles")).To(ContainElement("/values-global.yaml"))
+		Expect(field(find(manifests, "Application", "vault"), "spec", "source", "helm", "valueFiles")).To(BeNil())
+	})
+
+	It("should leave out value files that refer to unset values", func() {
+		opts.Cluster.Platform = ""
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		source := field(find(manifests, "Application", "app"), "spec", "sources").([]interface{})[1]
+		valueFiles := field(Manifest(source.(map[string]interface{})), "helm", "valueFiles")
+		Expect(valueFiles).NotTo(ContainElement(ContainSubstring("/overrides/")))
+		Expect(valueFiles).To(ContainElement("values-extra.yaml"))
+	})
+
+	It("should fail without a pattern name", func() {
+		global.Global.Pattern = ""
+		_, err := Render(global, clusterGroup, opts)
+		Expect(err).To(MatchError(ContainSubstring("global.pattern is not set")))
+	})
+
+	It("should encode a YAML stream", func() {
+		manifests, err := Render(global, clusterGroup, opts)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := Encode(manifests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix("apiVersion: v1\nkind: Namespace\n"))
+		Expect(string(data)).To(ContainSubstring("\n---\n"))
+	})
+})
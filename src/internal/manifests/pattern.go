@@ -0,0 +1,60 @@
+package manifests
+
+import (
+	"fmt"
+
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+// PatternNamespace is the namespace of the Pattern CR, where the patterns
+// operator runs.
+const PatternNamespace = "openshift-operators"
+
+// GitSource is the pattern repository the Pattern CR points the cluster at.
+type GitSource struct {
+	// TargetRepo is the HTTPS URL of the repository.
+	TargetRepo string
+	// TargetRevision is the branch of the repository.
+	TargetRevision string
+}
+
+// PatternCR returns the Pattern custom resource the pattern-install chart
+// creates on make install.
+func PatternCR(global *types.ValuesGlobal, git GitSource) (Manifest, error) {
+	if global.Global.Pattern == "" {
+		return nil, fmt.Errorf("global.pattern is not set")
+	}
+	if global.Main.ClusterGroupName == "" {
+		return nil, fmt.Errorf("main.clusterGroupName is not set")
+	}
+
+	multiSource := map[string]interface{}{"enabled": global.Main.MultiSourceConfig.Enabled}
+	if version := global.Main.MultiSourceConfig.ClusterGroupChartVersion; version != "" {
+		multiSource["clusterGroupChartVersion"] = version
+	}
+	if url, ok := global.Main.MultiSourceConfig.OtherFields["helmRepoUrl"].(string); ok && url != "" {
+		multiSource["helmRepoUrl"] = url
+	}
+
+	spec := map[string]interface{}{
+		"clusterGroupName": global.Main.ClusterGroupName,
+		"gitSpec": map[string]interface{}{
+			"targetRepo":     git.TargetRepo,
+			"targetRevision": git.TargetRevision,
+		},
+		"multiSourceConfig": multiSource,
+	}
+	if capabilities, ok := global.Main.OtherFields["experimentalCapabilities"].(string); ok && capabilities != "" {
+		spec["experimentalCapabilities"] = capabilities
+	}
+	if parameters, ok := global.Main.OtherFields["extraParameters"].([]interface{}); ok && len(parameters) > 0 {
+		spec["extraParameters"] = parameters
+	}
+
+	return Manifest{
+		"apiVersion": "gitops.hybrid-cloud-patterns.io/v1alpha1",
+		"kind":       "Pattern",
+		"metadata":   map[string]interface{}{"name": global.Global.Pattern, "namespace": PatternNamespace},
+		"spec":       spec,
+	}, nil
+}
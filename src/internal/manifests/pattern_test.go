@@ -0,0 +1,55 @@
+package manifests
+
+import (
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+var _ = Describe("PatternCR", func() {
+	var global *types.ValuesGlobal
+
+	BeforeEach(func() {
+		global = types.NewDefaultValuesGlobal()
+		Expect(yaml.Unmarshal([]byte(`global:
+  pattern: demo
+main:
+  clusterGroupName: hub
+  multiSourceConfig:
+    enabled: true
+    clusterGroupChartVersion: 0.9.*
+    helmRepoUrl: https://charts.example.com
+  experimentalCapabilities: initcontainers
+`), global)).To(Succeed())
+	})
+
+	It("should build the CR from the global values and the Git source", func() {
+		cr, err := PatternCR(global, GitSource{TargetRepo: "https://github.com/org/demo.git", TargetRevision: "main"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Kind()).To(Equal("Pattern"))
+		Expect(cr.Name()).To(Equal("demo"))
+		Expect(field(cr, "metadata", "namespace")).To(Equal(PatternNamespace))
+		Expect(field(cr, "spec")).To(Equal(map[string]interface{}{
+			"clusterGroupName": "hub",
+			"gitSpec": map[string]interface{}{
+				"targetRepo":     "https://github.com/org/demo.git",
+				"targetRevision": "main",
+			},
+			"multiSourceConfig": map[string]interface{}{
+				"enabled":                  true,
+				"clusterGroupChartVersion": "0.9.*",
+				"helmRepoUrl":              "https://charts.example.com",
+			},
+			"experimentalCapabilities": "initcontainers",
+		}))
+	})
+
+	It("should fail without a clustergroup", func() {
+		global.Main.ClusterGroupName = ""
+		_, err := PatternCR(global, GitSource{})
+		Expect(err).To(MatchError(ContainSubstring("main.clusterGroupName is not set")))
+	})
+})
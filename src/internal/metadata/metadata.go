@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+// Dir is the directory, relative to the repository root, where patternizer keeps its state.
+const Dir = ".patternizer"
+
+// FileName is the name of the metadata file inside Dir.
+const FileName = "metadata.yaml"
+
+// RelPath is the path of the metadata file relative to the repository root.
+var RelPath = filepath.Join(Dir, FileName)
+
+// Metadata records which patternizer produced the managed files of a repository.
+type Metadata struct {
+	GeneratorVersion string `yaml:"generatorVersion"`
+	ResourceVersion  string `yaml:"resourceVersion,omitempty"`
+	// Skills is the manifest of the skills patternizer installed, by name.
+	Skills map[string]skillbundle.InstalledSkill `yaml:"skills,omitempty"`
+	// RemovedSkills lists the skills removed with skills remove, which init
+	// and upgrade do not install again.
+	RemovedSkills []string               `yaml:"removedSkills,omitempty,flow"`
+	OtherFields   map[string]interface{} `yaml:",inline"`
+}
+
+// Path returns the absolute path of the metadata file for repoRoot.
+func Path(repoRoot string) string {
+	return filepath.Join(repoRoot, RelPath)
+}
+
+// Load reads the metadata file of repoRoot. It returns an empty Metadata if
+// the file does not exist.
+func Load(repoRoot string) (*Metadata, error) {
+	metadataPath := Path(repoRoot)
+	m := &Metadata{}
+
+	data, err := os.ReadFile(metadataPath)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", metadataPath, err)
+	}
+
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML from %s: %w", metadataPath, err)
+	}
+	return m, nil
+}
+
+// Save writes m to the metadata file of repoRoot, creating the state directory if needed.
+func Save(repoRoot string, m *Metadata) error {
+	metadataPath := Path(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(metadataPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(metadataPath), err)
+	}
+	return fileutils.WriteYAMLWithIndent(m, metadataPath)
+}
@@ -0,0 +1,41 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load and Save", func() {
+	It("should return empty metadata when the file does not exist", func() {
+		m, err := Load(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GeneratorVersion).To(BeEmpty())
+	})
+
+	It("should round-trip the metadata and preserve unknown fields", func() {
+		repoRoot := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(repoRoot, Dir), 0o755)).To(Succeed())
+		Expect(os.WriteFile(Path(repoRoot), []byte("generatorVersion: v1.0.0\ncustom: value\n"), 0o644)).To(Succeed())
+
+		m, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.GeneratorVersion).To(Equal("v1.0.0"))
+
+		m.GeneratorVersion = "v2.0.0"
+		Expect(Save(repoRoot, m)).To(Succeed())
+
+		reloaded, err := Load(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded.GeneratorVersion).To(Equal("v2.0.0"))
+		Expect(reloaded.OtherFields).To(HaveKeyWithValue("custom", "value"))
+	})
+
+	It("should create the state directory when saving", func() {
+		repoRoot := GinkgoT().TempDir()
+		Expect(Save(repoRoot, &Metadata{GeneratorVersion: "v1.0.0"})).To(Succeed())
+		Expect(Path(repoRoot)).To(BeARegularFile())
+	})
+})
@@ -0,0 +1,213 @@
+package pattern
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/metadata"
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// PreservedCommonDir is the directory, relative to the repository root, that
+// keeps the files of the legacy common/ directory that match no upstream
+// release.
+var PreservedCommonDir = filepath.Join(metadata.Dir, "preserved", "common")
+
+// commonLayout lists the paths, relative to common/, that the upstream common
+// repository shipped in any of its releases. Entries ending in "/**" match a
+// whole directory; the others are path.Match patterns. It is used when no
+// release digests are embedded.
+var commonLayout = []string{
+	".ansible-lint",
+	".github/**",
+	".gitignore",
+	".gitleaks.toml",
+	".mega-linter.yaml",
+	".pre-commit-config.yaml",
+	".yamllint",
+	"Changes.md",
+	"LICENSE",
+	"Makefile",
+	"README.md",
+	"reference-output.yaml",
+	"requirements.yml",
+	"values-global.yaml",
+	"acm/**",
+	"ansible/**",
+	"clustergroup/**",
+	"examples/**",
+	"golang-external-secrets/**",
+	"hashicorp-vault/**",
+	"install/**",
+	"letsencrypt/**",
+	"operator-install/**",
+	"tests/**",
+	"scripts/argocd-login.sh",
+	"scripts/deploy-pattern.sh",
+	"scripts/determine-main-clustergroup.sh",
+	"scripts/determine-pattern-name.sh",
+	"scripts/determine-secretstore-backend.sh",
+	"scripts/display-secrets-info.sh",
+	"scripts/lint.sh",
+	"scripts/load-k8s-secrets.sh",
+	"scripts/make_common_subtree.sh",
+	"scripts/manage-secret-app.sh",
+	"scripts/manage-secret-namespace.sh",
+	"scripts/pattern-util.sh",
+	"scripts/preview-all.sh",
+	"scripts/preview.sh",
+	"scripts/process-secrets.sh",
+	"scripts/set-secret-backend.sh",
+	"scripts/test.sh",
+	"scripts/vault-utils.sh",
+	"scripts/write-token-kubeconfig.sh",
+}
+
+// inCommonLayout reports whether rel, a slash-separated path relative to
+// common/, is part of commonLayout.
+func inCommonLayout(rel string) bool {
+	for _, pattern := range commonLayout {
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if strings.HasPrefix(rel, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// UpstreamCommon holds the SHA-256 digests of the files that the upstream
+// common repository shipped in its releases, by slash-separated path relative
+// to common/.
+type UpstreamCommon map[string]map[string]bool
+
+// LoadUpstreamCommon reads the <release>.sha256 files at the root of fsys. Each
+// one lists the files of a release in the format of sha256sum.
+func LoadUpstreamCommon(fsys fs.FS) (UpstreamCommon, error) {
+	manifests, err := fs.Glob(fsys, "*.sha256")
+	if err != nil {
+		return nil, err
+	}
+	upstream := UpstreamCommon{}
+	for _, manifest := range manifests {
+		data, err := fs.ReadFile(fsys, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", manifest, err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			digest, file, ok := strings.Cut(line, " ")
+			if !ok || len(digest) != 64 {
+				return nil, fmt.Errorf("%s:%d: expected a sha256sum line", manifest, i+1)
+			}
+			// sha256sum marks files read in binary mode with a '*'.
+			file = strings.TrimPrefix(strings.TrimPrefix(file, " "), "*")
+			if upstream[file] == nil {
+				upstream[file] = map[string]bool{}
+			}
+			upstream[file][strings.ToLower(digest)] = true
+		}
+	}
+	return upstream, nil
+}
+
+// EmbeddedUpstreamCommon returns the upstream common releases compiled into
+// the binary.
+func EmbeddedUpstreamCommon() (UpstreamCommon, error) {
+	releases, err := fs.Sub(embedded.Common, "common")
+	if err != nil {
+		return nil, err
+	}
+	return LoadUpstreamCommon(releases)
+}
+
+// UnrecognizedCommonFiles returns the files of commonDir whose path and
+// content do not match a file of an upstream release, as sorted
+// slash-separated paths relative to it. Files added to common/ and local
+// edits of upstream files are both unrecognized, as is anything that is not
+// a regular file. Without any release digests in upstream, files are matched
+// by path against commonLayout instead.
+func UnrecognizedCommonFiles(commonDir string, upstream UpstreamCommon) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(commonDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(commonDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(upstream) == 0 {
+			if !inCommonLayout(rel) {
+				files = append(files, rel)
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && upstream[rel] != nil {
+			digest, err := report.HashFile(p)
+			if err != nil {
+				return err
+			}
+			if upstream[rel][digest] {
+				return nil
+			}
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", commonDir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// PreserveCommonFiles moves the files of the common/ directory of repoRoot
+// that match no file of an upstream release to PreservedCommonDir, keeping
+// their paths, and returns them. Nothing is moved if one of them would
+// overwrite a file preserved earlier.
+func PreserveCommonFiles(repoRoot string, upstream UpstreamCommon) ([]string, error) {
+	commonDir := filepath.Join(repoRoot, "common")
+	files, err := UnrecognizedCommonFiles(commonDir, upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	preservedDir := filepath.Join(repoRoot, PreservedCommonDir)
+	var conflicts []string
+	for _, file := range files {
+		if _, err := os.Lstat(filepath.Join(preservedDir, filepath.FromSlash(file))); err == nil {
+			conflicts = append(conflicts, file)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("cannot preserve %s: already present in %s", strings.Join(conflicts, ", "), PreservedCommonDir)
+	}
+
+	for _, file := range files {
+		dst := filepath.Join(preservedDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", dst, err)
+		}
+		if err := os.Rename(filepath.Join(commonDir, filepath.FromSlash(file)), dst); err != nil {
+			return nil, fmt.Errorf("failed to preserve common/%s: %w", file, err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,119 @@
+package pattern
+
+import (
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+var _ = Describe("PreserveCommonFiles", func() {
+	var repoRoot string
+	var upstream UpstreamCommon
+
+	write := func(name, content string) {
+		path := filepath.Join(repoRoot, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		for _, name := range []string{
+			"common/Makefile",
+			"common/.github/workflows/lint.yml",
+			"common/clustergroup/templates/plumbing/argocd.yaml",
+			"common/scripts/pattern-util.sh",
+			"common/scripts/our-backup.sh",
+			"common/clustergroup/templates/team.yaml",
+			"common/team-notes.md",
+		} {
+			write(name, name)
+		}
+		write("common/scripts/pattern-util.sh", "edited")
+
+		// Two releases; the second changed Makefile.
+		var err error
+		upstream, err = LoadUpstreamCommon(fstest.MapFS{
+			"v0.1.0.sha256": {Data: []byte(
+				report.HashBytes([]byte("common/Makefile")) + "  Makefile\n" +
+					report.HashBytes([]byte("common/scripts/pattern-util.sh")) + "  scripts/pattern-util.sh\n" +
+					report.HashBytes([]byte("common/.github/workflows/lint.yml")) + " *.github/workflows/lint.yml\n")},
+			"v0.2.0.sha256": {Data: []byte(
+				report.HashBytes([]byte("new")) + "  Makefile\n" +
+					report.HashBytes([]byte("common/clustergroup/templates/plumbing/argocd.yaml")) + "  clustergroup/templates/plumbing/argocd.yaml\n")},
+			"README.md": {Data: []byte("not a release")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should list the files whose path or content matches no release", func() {
+		files, err := UnrecognizedCommonFiles(filepath.Join(repoRoot, "common"), upstream)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{
+			"clustergroup/templates/team.yaml",
+			"scripts/our-backup.sh",
+			"scripts/pattern-util.sh",
+			"team-notes.md",
+		}))
+	})
+
+	It("should move the unrecognized files to the preserved directory", func() {
+		files, err := PreserveCommonFiles(repoRoot, upstream)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(4))
+
+		preserved := filepath.Join(repoRoot, PreservedCommonDir)
+		data, err := os.ReadFile(filepath.Join(preserved, "scripts", "pattern-util.sh"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("edited"))
+		Expect(filepath.Join(preserved, "clustergroup", "templates", "team.yaml")).To(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, "common", "team-notes.md")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(repoRoot, "common", "Makefile")).To(BeAnExistingFile())
+	})
+
+	It("should move nothing rather than overwrite preserved files", func() {
+		write(filepath.Join(PreservedCommonDir, "team-notes.md"), "earlier")
+
+		_, err := PreserveCommonFiles(repoRoot, upstream)
+		Expect(err).To(MatchError(ContainSubstring("cannot preserve team-notes.md")))
+		Expect(filepath.Join(repoRoot, "common", "scripts", "our-backup.sh")).To(BeAnExistingFile())
+	})
+
+	It("should reject a malformed release file", func() {
+		_, err := LoadUpstreamCommon(fstest.MapFS{"v0.1.0.sha256": {Data: []byte("Makefile\n")}})
+		Expect(err).To(MatchError(ContainSubstring("v0.1.0.sha256:1")))
+	})
+})
+
+var _ = Describe("EmbeddedUpstreamCommon", func() {
+	It("should recognize the upstream files and preserve the others", func() {
+		upstream, err := EmbeddedUpstreamCommon()
+		Expect(err).NotTo(HaveOccurred())
+
+		commonDir := filepath.Join(GinkgoT().TempDir(), "common")
+		for _, name := range []string{
+			"Makefile",
+			"clustergroup/Chart.yaml",
+			"scripts/pattern-util.sh",
+			"scripts/our-backup.sh",
+			"team-notes.md",
+		} {
+			path := filepath.Join(commonDir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(name), 0o644)).To(Succeed())
+		}
+
+		files, err := UnrecognizedCommonFiles(commonDir, upstream)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(ContainElements("scripts/our-backup.sh", "team-notes.md"))
+		if len(upstream) == 0 {
+			// Without release digests the files are matched by path.
+			Expect(files).To(HaveLen(2))
+		}
+	})
+})
@@ -0,0 +1,356 @@
+package pattern
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+	"github.com/validatedpatterns/patternizer/internal/types"
+)
+
+// Finding is a reference to the legacy common/ layout found by PlanMigration.
+type Finding struct {
+	// File is the path of the file, relative to the repository root.
+	File string
+	// Line is the 1-based line of the reference, or 0 if it has none.
+	Line    int
+	Message string
+}
+
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.File, f.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+}
+
+// MigrationPlan holds the file changes that convert a repository from the
+// legacy common/ layout. Nothing is changed on disk until the edits are
+// applied with fileutils.ApplyEdits.
+type MigrationPlan struct {
+	// Legacy reports whether the repository uses the legacy common/ layout.
+	// The plan is empty otherwise.
+	Legacy bool
+	Edits  []fileutils.Edit
+	// Converted lists the references the edits rewrite.
+	Converted []Finding
+	// Unconverted lists the references that must be rewritten by hand before
+	// common/ can be removed.
+	Unconverted []Finding
+}
+
+// legacyRef matches a path into the common/ directory of the repository,
+// either relative or below a variable such as $(CURDIR), but not in a URL.
+var legacyRef = regexp.MustCompile(`(?:^|[^\w./-]|[)}]/)((?:\./)?common/[\w./*-]*)`)
+
+// textRewrites convert the references to common/ in the Makefile and scripts
+// that have a counterpart in the new layout.
+var textRewrites = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+	message     string
+}{
+	{
+		pattern:     regexp.MustCompile(`(^|[^\w./-]|[)}]/)(\./)?common/scripts/pattern-util\.sh($|[^\w.-])`),
+		replacement: "${1}${2}pattern.sh${3}",
+		message:     "replaced common/scripts/pattern-util.sh with pattern.sh",
+	},
+	{
+		pattern:     regexp.MustCompile(`(^|[^\w./-]|[)}]/)(?:\./)?common/Makefile($|[^\w.-])`),
+		replacement: "${1}Makefile-common${2}",
+		message:     "replaced common/Makefile with Makefile-common",
+	},
+}
+
+// commonCharts maps the charts that used to be vendored in common/ to the
+// versions of their releases in the chart repository.
+var commonCharts = map[string]string{
+	"acm":                     "0.1.*",
+	"golang-external-secrets": "0.1.*",
+	"hashicorp-vault":         "0.1.*",
+	"letsencrypt":             "0.1.*",
+}
+
+// commonChartPath matches the path of an application deploying a chart from common/.
+var commonChartPath = regexp.MustCompile(`^(?:\./)?common/([\w.-]+)/?$`)
+
+// PlanMigration plans converting a repository from the legacy common/
+// layout. It rewrites the references to common/ in the Makefile, the scripts
+// and the values files, deploys the charts of common/ from the chart
+// repository, enables main.multiSourceConfig and sets global.singleArgoCD.
+// The references it cannot convert are returned in Unconverted.
+//
+// A repository without a common/ directory, a pattern.sh symlink or a
+// reference to common/Makefile or common/scripts/pattern-util.sh does not
+// use the legacy layout, and its plan is empty.
+func PlanMigration(repoRoot string) (*MigrationPlan, error) {
+	plan := &MigrationPlan{}
+	if err := plan.migrateScripts(repoRoot); err != nil {
+		return nil, err
+	}
+	plan.Legacy = len(plan.Converted) > 0 || hasLegacyLayout(repoRoot)
+	if !plan.Legacy {
+		return &MigrationPlan{}, nil
+	}
+
+	docs, err := loadValuesDocs(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	global := findValuesDoc(docs, "values-global.yaml")
+	if global == nil {
+		return nil, fmt.Errorf("values-global.yaml not found in %s", repoRoot)
+	}
+	plan.migrateGlobal(repoRoot, global)
+	plan.migrateHubCluster(repoRoot, global, docs)
+	for _, doc := range docs {
+		plan.migrateApplications(repoRoot, doc)
+		walkScalars(doc.doc.Content[0], "", func(path string, node *yaml.Node) {
+			for _, match := range legacyRef.FindAllStringSubmatch(node.Value, -1) {
+				plan.Unconverted = append(plan.Unconverted, Finding{
+					File:    relPath(repoRoot, doc.path),
+					Line:    node.Line,
+					Message: fmt.Sprintf("%s references %s", path, match[1]),
+				})
+			}
+		})
+	}
+
+	edits, err := docEdits(docs)
+	if err != nil {
+		return nil, err
+	}
+	plan.Edits = append(plan.Edits, edits...)
+	return plan, nil
+}
+
+// migrateScripts rewrites the references to common/ in the Makefile and the
+// scripts of the repository.
+func (p *MigrationPlan) migrateScripts(repoRoot string) error {
+	return filepath.WalkDir(repoRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := relPath(repoRoot, path)
+		if d.IsDir() {
+			if path != repoRoot && (rel == "common" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// pattern.sh is replaced by upgrade, and symlinks point into common/.
+		if !d.Type().IsRegular() || rel == "pattern.sh" || !isScript(rel) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+
+		lines := strings.Split(string(content), "\n")
+		changed := false
+		for i, line := range lines {
+			for _, rewrite := range textRewrites {
+				// Replace twice, since adjacent references share the character
+				// between them.
+				updated := rewrite.pattern.ReplaceAllString(rewrite.pattern.ReplaceAllString(line, rewrite.replacement), rewrite.replacement)
+				if updated != line {
+					p.Converted = append(p.Converted, Finding{File: rel, Line: i + 1, Message: rewrite.message})
+					line = updated
+					changed = true
+				}
+			}
+			lines[i] = line
+			for _, match := range legacyRef.FindAllStringSubmatch(line, -1) {
+				p.Unconverted = append(p.Unconverted, Finding{File: rel, Line: i + 1, Message: "references " + match[1]})
+			}
+		}
+		if changed {
+			p.Edits = append(p.Edits, fileutils.Edit{Path: path, Content: []byte(strings.Join(lines, "\n")), Mode: info.Mode().Perm()})
+		}
+		return nil
+	})
+}
+
+// hasLegacyLayout reports whether repoRoot has a common/ directory or a
+// pattern.sh symlink, which pointed into common/.
+func hasLegacyLayout(repoRoot string) bool {
+	if info, err := os.Lstat(filepath.Join(repoRoot, "common")); err == nil && info.IsDir() {
+		return true
+	}
+	info, err := os.Lstat(filepath.Join(repoRoot, "pattern.sh"))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// isScript reports whether the file at rel is the Makefile or a script.
+func isScript(rel string) bool {
+	return rel == "Makefile" || strings.HasSuffix(rel, ".sh") || strings.HasPrefix(rel, "scripts"+string(filepath.Separator))
+}
+
+// migrateGlobal enables multiSourceConfig, so that the clustergroup chart is
+// fetched from the chart repository instead of common/clustergroup, and sets
+// global.singleArgoCD unless the pattern chose a value.
+func (p *MigrationPlan) migrateGlobal(repoRoot string, global *valuesDoc) {
+	defaults := types.NewDefaultValuesGlobal()
+	root := global.doc.Content[0]
+	file := relPath(repoRoot, global.path)
+
+	set := func(tag, value, message string, keys ...string) {
+		line := 0
+		if node := lookupMappingPath(root, keys...); node != nil {
+			line = node.Line
+		}
+		if setMappingScalar(root, tag, value, keys...) {
+			global.changed = true
+			p.Converted = append(p.Converted, Finding{File: file, Line: line, Message: message})
+		}
+	}
+	set("!!bool", "true", "enabled main.multiSourceConfig to deploy the clustergroup chart from the chart repository", "main", "multiSourceConfig", "enabled")
+	if lookupMappingPath(root, "main", "multiSourceConfig", "clusterGroupChartVersion") == nil {
+		set("!!str", defaults.Main.MultiSourceConfig.ClusterGroupChartVersion,
+			"set main.multiSourceConfig.clusterGroupChartVersion to "+defaults.Main.MultiSourceConfig.ClusterGroupChartVersion,
+			"main", "multiSourceConfig", "clusterGroupChartVersion")
+	}
+	if lookupMappingPath(root, "global", "singleArgoCD") == nil {
+		value := strconv.FormatBool(defaults.Global.SingleArgoCD)
+		set("!!bool", value, "set global.singleArgoCD to "+value, "global", "singleArgoCD")
+	}
+}
+
+// migrateHubCluster replaces clusterGroup.isHubCluster, which legacy
+// patterns used to mark the hub, with main.clusterGroupName.
+func (p *MigrationPlan) migrateHubCluster(repoRoot string, global *valuesDoc, docs []*valuesDoc) {
+	var hubs []*valuesDoc
+	for _, doc := range docs {
+		if isHub := lookupMappingPath(doc.doc.Content[0], "clusterGroup", "isHubCluster"); isHub != nil && isHub.Value == "true" {
+			hubs = append(hubs, doc)
+		}
+	}
+
+	root := global.doc.Content[0]
+	mainName := ""
+	if name := lookupMappingPath(root, "main", "clusterGroupName"); name != nil {
+		mainName = name.Value
+	}
+	if mainName == "" && len(hubs) == 1 {
+		mainName = clusterGroupName(hubs[0])
+		setMappingPath(root, mainName, "main", "clusterGroupName")
+		global.changed = true
+		p.Converted = append(p.Converted, Finding{
+			File:    relPath(repoRoot, global.path),
+			Message: fmt.Sprintf("set main.clusterGroupName to %s, the cluster group with clusterGroup.isHubCluster", mainName),
+		})
+	}
+
+	for _, doc := range hubs {
+		file := relPath(repoRoot, doc.path)
+		isHub := lookupMappingPath(doc.doc.Content[0], "clusterGroup", "isHubCluster")
+		if mainName == "" || clusterGroupName(doc) != mainName {
+			p.Unconverted = append(p.Unconverted, Finding{
+				File:    file,
+				Line:    isHub.Line,
+				Message: fmt.Sprintf("clusterGroup.isHubCluster is true but the main cluster group is %q", mainName),
+			})
+			continue
+		}
+		deleteMappingKey(mappingValue(doc.doc.Content[0], "clusterGroup"), "isHubCluster")
+		doc.changed = true
+		p.Converted = append(p.Converted, Finding{File: file, Line: isHub.Line, Message: "removed clusterGroup.isHubCluster; main.clusterGroupName selects the hub"})
+	}
+}
+
+// migrateApplications deploys the applications that use a chart vendored in
+// common/ from the chart repository.
+func (p *MigrationPlan) migrateApplications(repoRoot string, doc *valuesDoc) {
+	applications := lookupMappingPath(doc.doc.Content[0], "clusterGroup", "applications")
+	if applications == nil || applications.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(applications.Content); i += 2 {
+		app := applications.Content[i]
+		path := mappingValue(app, "path")
+		if path == nil || path.Kind != yaml.ScalarNode {
+			continue
+		}
+		match := commonChartPath.FindStringSubmatch(path.Value)
+		if match == nil {
+			continue
+		}
+		version, ok := commonCharts[match[1]]
+		if !ok {
+			continue
+		}
+		line := path.Line
+		deleteMappingKey(app, "path")
+		setMappingPath(app, match[1], "chart")
+		setMappingPath(app, version, "chartVersion")
+		doc.changed = true
+		p.Converted = append(p.Converted, Finding{
+			File:    relPath(repoRoot, doc.path),
+			Line:    line,
+			Message: fmt.Sprintf("deployed application %s from chart %s %s instead of %s", applications.Content[i-1].Value, match[1], version, match[0]),
+		})
+	}
+}
+
+// clusterGroupName returns the name of the cluster group of a values file.
+func clusterGroupName(doc *valuesDoc) string {
+	if name := lookupMappingPath(doc.doc.Content[0], "clusterGroup", "name"); name != nil && name.Value != "" {
+		return name.Value
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(doc.path), "values-"), ".yaml")
+}
+
+// walkScalars calls fn with every scalar value below node and its key path.
+func walkScalars(node *yaml.Node, path string, fn func(path string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		fn(path, node)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			walkScalars(node.Content[i+1], key, fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkScalars(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+// deleteMappingKey removes key from a mapping node.
+func deleteMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// relPath returns path relative to repoRoot.
+func relPath(repoRoot, path string) string {
+	if rel, err := filepath.Rel(repoRoot, path); err == nil {
+		return rel
+	}
+	return path
+}
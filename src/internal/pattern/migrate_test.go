@@ -0,0 +1,169 @@
+package pattern
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+)
+
+// findingStrings formats findings as file:line: message.
+func findingStrings(findings []Finding) []string {
+	var out []string
+	for _, finding := range findings {
+		out = append(out, finding.String())
+	}
+	return out
+}
+
+var _ = Describe("PlanMigration", func() {
+	var repoRoot string
+
+	write := func(name, content string) {
+		path := filepath.Join(repoRoot, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(repoRoot, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		write("values-global.yaml", `global:
+  pattern: demo
+main: {}
+`)
+		write("values-hub.yaml", `clusterGroup:
+  name: hub
+  isHubCluster: true
+  applications:
+    acm:
+      name: acm
+      namespace: open-cluster-management
+      path: common/acm
+    custom:
+      name: custom
+      namespace: demo
+      path: common/custom
+      extraValueFiles:
+        - /overrides/values-custom.yaml
+`)
+		write("values-region.yaml", `clusterGroup:
+  name: region
+  isHubCluster: false
+`)
+		write("Makefile", `.PHONY: default
+default: help
+
+%:
+	make -f common/Makefile $*
+
+install: ## installs the pattern
+	./common/scripts/pattern-util.sh make operator-deploy
+	common/scripts/display-secrets-info.sh
+`)
+		write("scripts/test.sh", "#!/bin/sh\n$(CURDIR)/common/scripts/pattern-util.sh make test\ncurl https://example.com/common/foo\n")
+		write("common/Makefile", "operator-deploy:\n")
+	})
+
+	It("should rewrite the references to common/ that have a counterpart", func() {
+		plan, err := PlanMigration(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findingStrings(plan.Converted)).To(ConsistOf(
+			"Makefile:5: replaced common/Makefile with Makefile-common",
+			"Makefile:8: replaced common/scripts/pattern-util.sh with pattern.sh",
+			"scripts/test.sh:2: replaced common/scripts/pattern-util.sh with pattern.sh",
+			"values-global.yaml: enabled main.multiSourceConfig to deploy the clustergroup chart from the chart repository",
+			"values-global.yaml: set main.multiSourceConfig.clusterGroupChartVersion to 0.9.*",
+			"values-global.yaml: set global.singleArgoCD to true",
+			"values-global.yaml: set main.clusterGroupName to hub, the cluster group with clusterGroup.isHubCluster",
+			"values-hub.yaml:3: removed clusterGroup.isHubCluster; main.clusterGroupName selects the hub",
+			"values-hub.yaml:8: deployed application acm from chart acm 0.1.* instead of common/acm",
+		))
+		Expect(findingStrings(plan.Unconverted)).To(ConsistOf(
+			"Makefile:9: references common/scripts/display-secrets-info.sh",
+			"values-hub.yaml:12: clusterGroup.applications.custom.path references common/custom",
+		))
+
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+		Expect(read("Makefile")).To(ContainSubstring("\tmake -f Makefile-common $*\n"))
+		Expect(read("Makefile")).To(ContainSubstring("\t./pattern.sh make operator-deploy\n"))
+		Expect(read("scripts/test.sh")).To(Equal("#!/bin/sh\n$(CURDIR)/pattern.sh make test\ncurl https://example.com/common/foo\n"))
+
+		global := readYAMLMap(filepath.Join(repoRoot, "values-global.yaml"))
+		Expect(global["global"]).To(HaveKeyWithValue("singleArgoCD", true))
+		Expect(global["main"]).To(HaveKeyWithValue("clusterGroupName", "hub"))
+		Expect(global["main"]).To(HaveKeyWithValue("multiSourceConfig", map[string]interface{}{"enabled": true, "clusterGroupChartVersion": "0.9.*"}))
+
+		hub := readYAMLMap(filepath.Join(repoRoot, "values-hub.yaml"))["clusterGroup"].(map[string]interface{})
+		Expect(hub).NotTo(HaveKey("isHubCluster"))
+		apps := hub["applications"].(map[string]interface{})
+		Expect(apps["acm"]).To(Equal(map[string]interface{}{
+			"name": "acm", "namespace": "open-cluster-management", "chart": "acm", "chartVersion": "0.1.*",
+		}))
+		Expect(apps["custom"]).To(HaveKeyWithValue("path", "common/custom"))
+		Expect(read("values-region.yaml")).To(ContainSubstring("isHubCluster: false"))
+	})
+
+	It("should keep the choices of the pattern", func() {
+		write("values-global.yaml", `global:
+  pattern: demo
+  singleArgoCD: false
+main:
+  clusterGroupName: region
+  multiSourceConfig:
+    enabled: true
+    clusterGroupChartVersion: 0.8.*
+`)
+		plan, err := PlanMigration(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findingStrings(plan.Converted)).NotTo(ContainElement(HavePrefix("values-global.yaml")))
+		Expect(findingStrings(plan.Unconverted)).To(ContainElement(`values-hub.yaml:3: clusterGroup.isHubCluster is true but the main cluster group is "region"`))
+	})
+
+	It("should plan nothing for a migrated repository", func() {
+		write("values-global.yaml", `global:
+  pattern: demo
+  singleArgoCD: true
+main:
+  clusterGroupName: hub
+  multiSourceConfig:
+    enabled: true
+    clusterGroupChartVersion: 0.9.*
+`)
+		write("values-hub.yaml", "clusterGroup:\n  name: hub\n")
+		write("Makefile", "include Makefile-common\n")
+		Expect(os.Remove(filepath.Join(repoRoot, "scripts", "test.sh"))).To(Succeed())
+
+		plan, err := PlanMigration(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Edits).To(BeEmpty())
+		Expect(plan.Converted).To(BeEmpty())
+		Expect(plan.Unconverted).To(BeEmpty())
+	})
+
+	It("should plan nothing for a repository without the legacy layout", func() {
+		write("Makefile", "include Makefile-common\n")
+		Expect(os.Remove(filepath.Join(repoRoot, "scripts", "test.sh"))).To(Succeed())
+		Expect(os.RemoveAll(filepath.Join(repoRoot, "common"))).To(Succeed())
+
+		plan, err := PlanMigration(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Legacy).To(BeFalse())
+		Expect(plan.Edits).To(BeEmpty())
+		Expect(plan.Converted).To(BeEmpty())
+		Expect(plan.Unconverted).To(BeEmpty())
+	})
+
+	It("should fail without values-global.yaml", func() {
+		Expect(os.Remove(filepath.Join(repoRoot, "values-global.yaml"))).To(Succeed())
+		_, err := PlanMigration(repoRoot)
+		Expect(err).To(MatchError(ContainSubstring("values-global.yaml not found")))
+	})
+})
@@ -0,0 +1,281 @@
+package pattern
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+)
+
+// OverridesDir is the directory, relative to the repository root, that holds
+// the value files registered in clusterGroup.sharedValueFiles.
+const OverridesDir = "overrides"
+
+// Platforms lists the values global.clusterPlatform can take. They are the
+// OpenShift infrastructure platform types, which are case sensitive.
+var Platforms = []string{
+	"AlibabaCloud", "AWS", "Azure", "BareMetal", "EquinixMetal", "External",
+	"GCP", "IBMCloud", "KubeVirt", "Libvirt", "None", "Nutanix", "OpenStack",
+	"oVirt", "PowerVS", "VSphere",
+}
+
+var (
+	// clusterVersionPattern matches global.clusterVersion, which holds the
+	// major and minor OpenShift version.
+	clusterVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+	// globalKeyPattern matches the keys of custom global values usable in templates.
+	globalKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// segmentPattern matches the characters allowed in a file name segment.
+	segmentPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// reservedGlobals are set by the framework at run time and have dedicated
+// Override fields.
+var reservedGlobals = map[string]string{
+	"clusterPlatform": "platform",
+	"clusterVersion":  "cluster version",
+}
+
+// rootHierarchy lists the combinations for which the framework reads a
+// values-<...>.yaml file from the repository root, in the order of the name segments.
+var rootHierarchy = [][]string{
+	{"platform"},
+	{"platform", "version"},
+	{"platform", "clustergroup"},
+	{"version", "clustergroup"},
+}
+
+// GlobalValue is a custom global.<Key> value an override applies to.
+type GlobalValue struct {
+	Key   string
+	Value string
+}
+
+// Override selects the clusters a values override file applies to.
+type Override struct {
+	// Platform is the value of global.clusterPlatform, e.g. AWS.
+	Platform string
+	// ClusterVersion is the value of global.clusterVersion, e.g. 4.16.
+	ClusterVersion string
+	// ClusterGroup is the name of the cluster group.
+	ClusterGroup string
+	// Globals are custom global values. They require Shared.
+	Globals []GlobalValue
+	// Shared creates the file in OverridesDir and registers it in
+	// clusterGroup.sharedValueFiles instead of using the root hierarchy.
+	Shared bool
+}
+
+// segment is one part of an override file name and its template.
+type segment struct {
+	kind     string
+	value    string
+	template string
+}
+
+// OverridePlan holds the changes that add an override file.
+type OverridePlan struct {
+	// Path is the override file, relative to the repository root.
+	Path string
+	// Exists reports whether the override file already exists.
+	Exists bool
+	// ValueFile is the templated path registered in sharedValueFiles, for shared overrides.
+	ValueFile string
+	// RegisteredIn is the values file ValueFile is added to, or empty if it is already registered.
+	RegisteredIn string
+	Edits        []fileutils.Edit
+}
+
+// PlanOverride plans adding the override file selected by o. Shared overrides
+// are registered in the sharedValueFiles of o.ClusterGroup, or of
+// mainClusterGroup if o does not select a cluster group. Combinations the
+// framework would never load are rejected.
+func PlanOverride(repoRoot, mainClusterGroup string, o Override) (*OverridePlan, error) {
+	segments, err := overrideSegments(repoRoot, mainClusterGroup, o)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(segments))
+	templates := make([]string, 0, len(segments))
+	kinds := make([]string, 0, len(segments))
+	for _, s := range segments {
+		names = append(names, s.value)
+		templates = append(templates, s.template)
+		kinds = append(kinds, s.kind)
+	}
+	fileName := fmt.Sprintf("values-%s.yaml", strings.Join(names, "-"))
+
+	plan := &OverridePlan{Path: fileName}
+	if !o.Shared {
+		if len(o.Globals) > 0 {
+			return nil, fmt.Errorf("custom global values are only available in shared value files; use --shared")
+		}
+		if kinds[0] == "clustergroup" && len(kinds) == 1 {
+			return nil, fmt.Errorf("%s is the values file of the cluster group itself", fileName)
+		}
+		if !slices.ContainsFunc(rootHierarchy, func(combination []string) bool { return slices.Equal(combination, kinds) }) {
+			return nil, fmt.Errorf("%s would never be loaded: the framework only reads platform, platform-version, platform-clustergroup and version-clustergroup files from the repository root; use --shared to create it in %s/ and register it in sharedValueFiles", fileName, OverridesDir)
+		}
+	} else {
+		plan.Path = filepath.Join(OverridesDir, fileName)
+		plan.ValueFile = fmt.Sprintf("/%s/values-%s.yaml", OverridesDir, strings.Join(templates, "-"))
+	}
+
+	overridePath := filepath.Join(repoRoot, plan.Path)
+	if _, err := os.Stat(overridePath); err == nil {
+		plan.Exists = true
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat %s: %w", overridePath, err)
+	} else {
+		plan.Edits = append(plan.Edits, fileutils.Edit{
+			Path:    overridePath,
+			Content: []byte(overrideHeader(segments)),
+			Mode:    0o644,
+		})
+	}
+
+	if o.Shared {
+		clusterGroup := cmp.Or(o.ClusterGroup, mainClusterGroup)
+		edit, err := registerSharedValueFile(repoRoot, clusterGroup, plan.ValueFile)
+		if err != nil {
+			return nil, err
+		}
+		if edit != nil {
+			plan.RegisteredIn = filepath.Base(edit.Path)
+			plan.Edits = append(plan.Edits, *edit)
+		}
+	}
+	return plan, nil
+}
+
+// overrideSegments validates o and returns its file name segments in the
+// order the framework names them.
+func overrideSegments(repoRoot, mainClusterGroup string, o Override) ([]segment, error) {
+	var segments []segment
+
+	if o.Platform != "" {
+		i := slices.IndexFunc(Platforms, func(p string) bool { return strings.EqualFold(p, o.Platform) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown platform %q (expected one of: %s)", o.Platform, strings.Join(Platforms, ", "))
+		}
+		segments = append(segments, segment{kind: "platform", value: Platforms[i], template: "{{ $.Values.global.clusterPlatform }}"})
+	}
+
+	if o.ClusterVersion != "" {
+		if !clusterVersionPattern.MatchString(o.ClusterVersion) {
+			return nil, fmt.Errorf("invalid cluster version %q: expected <major>.<minor>, e.g. 4.16", o.ClusterVersion)
+		}
+		segments = append(segments, segment{kind: "version", value: o.ClusterVersion, template: "{{ $.Values.global.clusterVersion }}"})
+	}
+
+	if o.ClusterGroup != "" {
+		if err := ValidateName(o.ClusterGroup); err != nil {
+			return nil, fmt.Errorf("invalid cluster group name: %w", err)
+		}
+		if o.ClusterGroup != mainClusterGroup {
+			if _, err := os.Stat(filepath.Join(repoRoot, fmt.Sprintf("values-%s.yaml", o.ClusterGroup))); err != nil {
+				return nil, fmt.Errorf("unknown cluster group %q: values-%s.yaml not found", o.ClusterGroup, o.ClusterGroup)
+			}
+		}
+		segments = append(segments, segment{kind: "clustergroup", value: o.ClusterGroup, template: "{{ $.Values.clusterGroup.name }}"})
+	}
+
+	if len(o.Globals) > 0 {
+		globals, err := LoadGlobalValues("", repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range o.Globals {
+			if flag, ok := reservedGlobals[g.Key]; ok {
+				return nil, fmt.Errorf("global.%s is set by the framework; select the %s instead", g.Key, flag)
+			}
+			if !globalKeyPattern.MatchString(g.Key) {
+				return nil, fmt.Errorf("invalid global key %q", g.Key)
+			}
+			if !segmentPattern.MatchString(g.Value) {
+				return nil, fmt.Errorf("invalid value %q for global.%s: only letters, digits, '.', '_' and '-' are allowed", g.Value, g.Key)
+			}
+			if _, ok := globals.Global.OtherFields[g.Key]; !ok {
+				return nil, fmt.Errorf("global.%s is not set in values-global.yaml, so a file selected by it would never be loaded", g.Key)
+			}
+			segments = append(segments, segment{kind: "global", value: g.Value, template: fmt.Sprintf("{{ $.Values.global.%s }}", g.Key)})
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("select at least one of a platform, cluster version, cluster group or global value")
+	}
+	return segments, nil
+}
+
+// overrideHeader returns the initial content of an override file.
+func overrideHeader(segments []segment) string {
+	var conditions []string
+	for _, s := range segments {
+		switch s.kind {
+		case "platform":
+			conditions = append(conditions, "the "+s.value+" platform")
+		case "version":
+			conditions = append(conditions, "OpenShift "+s.value)
+		case "clustergroup":
+			conditions = append(conditions, "the "+s.value+" cluster group")
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s (%s)", s.value, strings.TrimSuffix(strings.TrimPrefix(s.template, "{{ $.Values."), " }}")))
+		}
+	}
+	return fmt.Sprintf("# Values for clusters matching %s.\n", strings.Join(conditions, ", "))
+}
+
+// registerSharedValueFile returns the edit that appends valueFile to the
+// clusterGroup.sharedValueFiles of values-<clusterGroup>.yaml, or nil if it is
+// already registered.
+func registerSharedValueFile(repoRoot, clusterGroup, valueFile string) (*fileutils.Edit, error) {
+	docs, err := loadValuesDocs(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("values-%s.yaml", clusterGroup)
+	doc := findValuesDoc(docs, name)
+	if doc == nil {
+		return nil, fmt.Errorf("%s not found; run init first", name)
+	}
+
+	root := doc.doc.Content[0]
+	clusterGroupNode := mappingValue(root, "clusterGroup")
+	if clusterGroupNode == nil || clusterGroupNode.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s has no clusterGroup section", name)
+	}
+	shared := mappingValue(clusterGroupNode, "sharedValueFiles")
+	if shared == nil || shared.Kind != yaml.SequenceNode {
+		if shared != nil && !(shared.Kind == yaml.ScalarNode && shared.Tag == "!!null") {
+			return nil, fmt.Errorf("clusterGroup.sharedValueFiles in %s is not a list", name)
+		}
+		newShared := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if shared != nil {
+			*shared = *newShared
+		} else {
+			shared = newShared
+			clusterGroupNode.Content = append(clusterGroupNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "sharedValueFiles"}, shared)
+		}
+	}
+	for _, item := range shared.Content {
+		if item.Value == valueFile {
+			return nil, nil
+		}
+	}
+	shared.Content = append(shared.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.SingleQuotedStyle, Value: valueFile})
+
+	content, err := encodeValuesDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &fileutils.Edit{Path: doc.path, Content: content, Mode: doc.mode}, nil
+}
@@ -0,0 +1,117 @@
+package pattern
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+)
+
+var _ = Describe("PlanOverride", func() {
+	var repoRoot string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-global.yaml"), []byte(`global:
+  pattern: demo
+  region: eastus
+main:
+  clusterGroupName: hub
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-hub.yaml"), []byte(`clusterGroup:
+  name: hub
+  # Files shared by every application
+  sharedValueFiles:
+    - '/overrides/values-{{ $.Values.global.clusterPlatform }}.yaml'
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-edge.yaml"), []byte("clusterGroup:\n  name: edge\n"), 0o644)).To(Succeed())
+	})
+
+	DescribeTable("root hierarchy files",
+		func(o Override, expected string) {
+			plan, err := PlanOverride(repoRoot, "hub", o)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Path).To(Equal(expected))
+			Expect(plan.ValueFile).To(BeEmpty())
+			Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+			Expect(filepath.Join(repoRoot, expected)).To(BeAnExistingFile())
+		},
+		Entry("platform", Override{Platform: "aws"}, "values-AWS.yaml"),
+		Entry("platform and version", Override{Platform: "Azure", ClusterVersion: "4.16"}, "values-Azure-4.16.yaml"),
+		Entry("platform and clustergroup", Override{Platform: "baremetal", ClusterGroup: "edge"}, "values-BareMetal-edge.yaml"),
+		Entry("version and clustergroup", Override{ClusterVersion: "4.16", ClusterGroup: "hub"}, "values-4.16-hub.yaml"),
+	)
+
+	DescribeTable("combinations the framework never loads from the root",
+		func(o Override, message string) {
+			_, err := PlanOverride(repoRoot, "hub", o)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("version only", Override{ClusterVersion: "4.16"}, "values-4.16.yaml would never be loaded"),
+		Entry("all three", Override{Platform: "AWS", ClusterVersion: "4.16", ClusterGroup: "hub"}, "values-AWS-4.16-hub.yaml would never be loaded"),
+		Entry("clustergroup only", Override{ClusterGroup: "hub"}, "values file of the cluster group itself"),
+		Entry("custom global", Override{Globals: []GlobalValue{{Key: "region", Value: "eastus"}}}, "use --shared"),
+	)
+
+	DescribeTable("invalid selectors",
+		func(o Override, message string) {
+			_, err := PlanOverride(repoRoot, "hub", o)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("nothing selected", Override{}, "select at least one"),
+		Entry("unknown platform", Override{Platform: "aws-east"}, `unknown platform "aws-east"`),
+		Entry("full version", Override{Platform: "AWS", ClusterVersion: "4.16.3"}, `invalid cluster version "4.16.3"`),
+		Entry("unknown clustergroup", Override{Platform: "AWS", ClusterGroup: "factory"}, `unknown cluster group "factory"`),
+		Entry("undefined global", Override{Shared: true, Globals: []GlobalValue{{Key: "zone", Value: "a"}}}, "global.zone is not set in values-global.yaml"),
+		Entry("reserved global", Override{Shared: true, Globals: []GlobalValue{{Key: "clusterPlatform", Value: "AWS"}}}, "select the platform instead"),
+		Entry("bad global value", Override{Shared: true, Globals: []GlobalValue{{Key: "region", Value: "east/us"}}}, "invalid value"),
+	)
+
+	It("should create shared files in overrides and register them", func() {
+		plan, err := PlanOverride(repoRoot, "hub", Override{Platform: "Azure", Globals: []GlobalValue{{Key: "region", Value: "eastus"}}, Shared: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Path).To(Equal(filepath.Join("overrides", "values-Azure-eastus.yaml")))
+		Expect(plan.ValueFile).To(Equal("/overrides/values-{{ $.Values.global.clusterPlatform }}-{{ $.Values.global.region }}.yaml"))
+		Expect(plan.RegisteredIn).To(Equal("values-hub.yaml"))
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(repoRoot, plan.Path))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("# Values for clusters matching the Azure platform, eastus (global.region).\n"))
+
+		values, err := os.ReadFile(filepath.Join(repoRoot, "values-hub.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(values)).To(ContainSubstring("# Files shared by every application"))
+		hub := readYAMLMap(filepath.Join(repoRoot, "values-hub.yaml"))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "sharedValueFiles"})).To(Equal([]interface{}{
+			"/overrides/values-{{ $.Values.global.clusterPlatform }}.yaml",
+			"/overrides/values-{{ $.Values.global.clusterPlatform }}-{{ $.Values.global.region }}.yaml",
+		}))
+	})
+
+	It("should register shared files in the selected clustergroup", func() {
+		plan, err := PlanOverride(repoRoot, "hub", Override{ClusterVersion: "4.16", ClusterGroup: "edge", Shared: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.RegisteredIn).To(Equal("values-edge.yaml"))
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		edge := readYAMLMap(filepath.Join(repoRoot, "values-edge.yaml"))
+		Expect(getNestedValue(edge, []string{"clusterGroup", "sharedValueFiles"})).To(Equal([]interface{}{
+			"/overrides/values-{{ $.Values.global.clusterVersion }}-{{ $.Values.clusterGroup.name }}.yaml",
+		}))
+	})
+
+	It("should keep existing files and registrations", func() {
+		Expect(os.MkdirAll(filepath.Join(repoRoot, "overrides"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "overrides", "values-AWS.yaml"), []byte("custom: true\n"), 0o644)).To(Succeed())
+
+		plan, err := PlanOverride(repoRoot, "hub", Override{Platform: "AWS", Shared: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Exists).To(BeTrue())
+		Expect(plan.RegisteredIn).To(BeEmpty())
+		Expect(plan.Edits).To(BeEmpty())
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 
@@ -11,6 +12,18 @@ import (
 	"github.com/validatedpatterns/patternizer/internal/types"
 )
 
+// namePattern matches DNS-1123 labels, which pattern, cluster group and
+// namespace names must be.
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateName checks that name can be used as a pattern, cluster group or namespace name.
+func ValidateName(name string) error {
+	if len(name) > 63 || !namePattern.MatchString(name) {
+		return fmt.Errorf("%q must consist of at most 63 lower case alphanumeric characters or '-', and must start and end with an alphanumeric character", name)
+	}
+	return nil
+}
+
 // GetPatternNameAndRepoRoot returns the pattern name and repository root directory.
 // The pattern name is derived from the basename of the current working directory.
 func GetPatternNameAndRepoRoot() (patternName, repoRoot string, err error) {
@@ -23,41 +36,97 @@ func GetPatternNameAndRepoRoot() (patternName, repoRoot string, err error) {
 	return patternName, repoRoot, nil
 }
 
+// Settings holds explicit choices of the user. Non-empty fields take
+// precedence over both the existing values files and the defaults.
+type Settings struct {
+	// PatternName replaces global.pattern.
+	PatternName string
+	// ClusterGroupName replaces main.clusterGroupName.
+	ClusterGroupName string
+	// ClusterGroupChartVersion replaces main.multiSourceConfig.clusterGroupChartVersion.
+	ClusterGroupChartVersion string
+	// ChartNamespaces maps chart paths to the namespace their application is deployed to.
+	ChartNamespaces map[string]string
+}
+
 // ProcessGlobalValues processes the global values YAML file.
-// It returns the pattern name and cluster group name that should be used (from the file if they exist, or the detected/default names).
-func ProcessGlobalValues(patternName, repoRoot string, withSecrets bool) (actualPatternName, clusterGroupName string, err error) {
+// It returns the pattern name and cluster group name that should be used (from settings, from the file if they exist, or the detected/default names).
+func ProcessGlobalValues(patternName, repoRoot string, withSecrets bool, settings Settings) (actualPatternName, clusterGroupName string, err error) {
+	globalValuesPath := filepath.Join(repoRoot, "values-global.yaml")
+	values, err := LoadGlobalValues(patternName, repoRoot)
+	if err != nil {
+		return "", "", err
+	}
+
+	if settings.PatternName != "" {
+		values.Global.Pattern = settings.PatternName
+	}
+	if settings.ClusterGroupName != "" {
+		values.Main.ClusterGroupName = settings.ClusterGroupName
+	}
+	if settings.ClusterGroupChartVersion != "" {
+		values.Main.MultiSourceConfig.ClusterGroupChartVersion = settings.ClusterGroupChartVersion
+	}
+
+	// Set secretLoader.disabled based on withSecrets flag
+	// If withSecrets is true, we want secretLoader to be enabled (disabled = false)
+	// If withSecrets is false, we want secretLoader to be disabled (disabled = true)
+	values.Global.SecretLoader.Disabled = !withSecrets
+
+	if err = fileutils.WriteYAMLWithIndent(values, globalValuesPath); err != nil {
+		return "", "", fmt.Errorf("failed to write to %s: %w", globalValuesPath, err)
+	}
+
+	return values.Global.Pattern, values.Main.ClusterGroupName, nil
+}
+
+// LoadGlobalValues reads the global values YAML file without modifying it.
+// Missing settings are filled in with the defaults, and the pattern name falls
+// back to patternName.
+func LoadGlobalValues(patternName, repoRoot string) (*types.ValuesGlobal, error) {
 	globalValuesPath := filepath.Join(repoRoot, "values-global.yaml")
 	values := types.NewDefaultValuesGlobal()
 
 	yamlFile, err := os.ReadFile(globalValuesPath)
 	if err != nil && !os.IsNotExist(err) {
-		return "", "", fmt.Errorf("failed to read %s: %w", globalValuesPath, err)
+		return nil, fmt.Errorf("failed to read %s: %w", globalValuesPath, err)
 	}
 
 	if err == nil {
 		if err = yaml.Unmarshal(yamlFile, values); err != nil {
-			return "", "", fmt.Errorf("failed to unmarshal YAML from %s: %w", globalValuesPath, err)
+			return nil, fmt.Errorf("failed to unmarshal YAML from %s: %w", globalValuesPath, err)
 		}
 	}
 
 	if values.Global.Pattern == "" {
 		values.Global.Pattern = patternName
 	}
+	return values, nil
+}
 
-	// Set secretLoader.disabled based on withSecrets flag
-	// If withSecrets is true, we want secretLoader to be enabled (disabled = false)
-	// If withSecrets is false, we want secretLoader to be disabled (disabled = true)
-	values.Global.SecretLoader.Disabled = !withSecrets
+// LoadClusterGroupValues reads the values file of a cluster group without
+// modifying it. It returns nil if the file does not exist.
+func LoadClusterGroupValues(clusterGroupName, repoRoot string) (*types.ValuesClusterGroup, error) {
+	clusterGroupValuesPath := filepath.Join(repoRoot, fmt.Sprintf("values-%s.yaml", clusterGroupName))
 
-	if err = fileutils.WriteYAMLWithIndent(values, globalValuesPath); err != nil {
-		return "", "", fmt.Errorf("failed to write to %s: %w", globalValuesPath, err)
+	yamlFile, err := os.ReadFile(clusterGroupValuesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", clusterGroupValuesPath, err)
 	}
 
-	return values.Global.Pattern, values.Main.ClusterGroupName, nil
+	var values types.ValuesClusterGroup
+	if err = yaml.Unmarshal(yamlFile, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML from %s: %w", clusterGroupValuesPath, err)
+	}
+	return &values, nil
 }
 
 // ProcessClusterGroupValues processes the cluster group values YAML file.
-func ProcessClusterGroupValues(patternName, clusterGroupName, repoRoot string, chartPaths []string, useSecrets bool) error {
+// Namespaces chosen in settings replace those of the existing applications.
+func ProcessClusterGroupValues(patternName, clusterGroupName, repoRoot string, chartPaths []string, useSecrets bool, settings Settings) error {
 	clusterGroupValuesPath := filepath.Join(repoRoot, fmt.Sprintf("values-%s.yaml", clusterGroupName))
 	values := types.NewDefaultValuesClusterGroup(patternName, clusterGroupName, chartPaths, useSecrets)
 
@@ -75,6 +144,8 @@ func ProcessClusterGroupValues(patternName, clusterGroupName, repoRoot string, c
 		mergeClusterGroupValues(values, &existingValues)
 	}
 
+	applyChartNamespaces(values, chartPaths, settings.ChartNamespaces)
+
 	if err = fileutils.WriteYAMLWithIndent(values, clusterGroupValuesPath); err != nil {
 		return fmt.Errorf("failed to write to %s: %w", clusterGroupValuesPath, err)
 	}
@@ -123,3 +194,21 @@ func mergeClusterGroupValues(defaults, existing *types.ValuesClusterGroup) {
 		}
 	}
 }
+
+// applyChartNamespaces deploys the application of every chart in chartPaths to
+// the namespace chosen for it, creating the namespace if needed.
+func applyChartNamespaces(values *types.ValuesClusterGroup, chartPaths []string, namespaces map[string]string) {
+	for _, path := range chartPaths {
+		namespace, ok := namespaces[path]
+		if !ok || namespace == "" {
+			continue
+		}
+		name := filepath.Base(path)
+		app := values.ClusterGroup.Applications[name]
+		app.Namespace = namespace
+		values.ClusterGroup.Applications[name] = app
+		if _, exists := values.ClusterGroup.Namespaces[namespace]; !exists {
+			values.ClusterGroup.Namespaces[namespace] = nil
+		}
+	}
+}
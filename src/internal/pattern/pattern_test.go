@@ -3,6 +3,7 @@ package pattern
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -68,7 +69,7 @@ var _ = Describe("ProcessGlobalValues", func() {
 		})
 
 		It("should preserve all custom fields", func() {
-			actualPatternName, clusterGroupName, err := ProcessGlobalValues("new-pattern", tempDir, false)
+			actualPatternName, clusterGroupName, err := ProcessGlobalValues("new-pattern", tempDir, false, Settings{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(actualPatternName).To(Equal("existing-pattern"))
 			Expect(clusterGroupName).To(Equal("custom-cluster-group"))
@@ -117,7 +118,7 @@ var _ = Describe("ProcessGlobalValues", func() {
 		})
 
 		It("should create the file with defaults", func() {
-			actualPatternName, clusterGroupName, err := ProcessGlobalValues("test-pattern", tempDir, false)
+			actualPatternName, clusterGroupName, err := ProcessGlobalValues("test-pattern", tempDir, false, Settings{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(actualPatternName).To(Equal("test-pattern"))
 			Expect(clusterGroupName).To(Equal("prod"))
@@ -147,7 +148,7 @@ var _ = Describe("ProcessGlobalValues", func() {
 		})
 
 		It("should set SecretLoader.Disabled to false", func() {
-			actualPatternName, clusterGroupName, err := ProcessGlobalValues("test-pattern", tempDir, true)
+			actualPatternName, clusterGroupName, err := ProcessGlobalValues("test-pattern", tempDir, true, Settings{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(actualPatternName).To(Equal("test-pattern"))
 			Expect(clusterGroupName).To(Equal("prod"))
@@ -215,7 +216,7 @@ var _ = Describe("ProcessClusterGroupValues", func() {
 
 		It("should preserve custom fields", func() {
 			chartPaths := []string{"charts/app1", "charts/app2"}
-			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false)).To(Succeed())
+			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false, Settings{})).To(Succeed())
 
 			processedData, err := os.ReadFile(valuesPath)
 			Expect(err).NotTo(HaveOccurred())
@@ -247,7 +248,7 @@ var _ = Describe("ProcessClusterGroupValues", func() {
 
 		It("should preserve custom application fields", func() {
 			chartPaths := []string{"charts/app1", "charts/app2"}
-			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false)).To(Succeed())
+			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false, Settings{})).To(Succeed())
 
 			processedData, err := os.ReadFile(valuesPath)
 			Expect(err).NotTo(HaveOccurred())
@@ -264,7 +265,7 @@ var _ = Describe("ProcessClusterGroupValues", func() {
 
 		It("should preserve custom subscriptions", func() {
 			chartPaths := []string{"charts/app1", "charts/app2"}
-			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false)).To(Succeed())
+			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false, Settings{})).To(Succeed())
 
 			processedData, err := os.ReadFile(valuesPath)
 			Expect(err).NotTo(HaveOccurred())
@@ -281,7 +282,7 @@ var _ = Describe("ProcessClusterGroupValues", func() {
 
 		It("should add new applications while preserving existing ones", func() {
 			chartPaths := []string{"charts/app1", "charts/app2"}
-			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false)).To(Succeed())
+			Expect(ProcessClusterGroupValues("test-pattern", "prod", tempDir, chartPaths, false, Settings{})).To(Succeed())
 
 			processedData, err := os.ReadFile(valuesPath)
 			Expect(err).NotTo(HaveOccurred())
@@ -298,3 +299,84 @@ var _ = Describe("ProcessClusterGroupValues", func() {
 		})
 	})
 })
+
+var _ = Describe("Settings", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+	})
+
+	It("should override the pattern and clustergroup names of an existing values file", func() {
+		globalValuesPath := filepath.Join(tempDir, "values-global.yaml")
+		Expect(os.WriteFile(globalValuesPath, []byte("global:\n  pattern: old\nmain:\n  clusterGroupName: prod\n"), 0o644)).To(Succeed())
+
+		actualPatternName, clusterGroupName, err := ProcessGlobalValues("dir-name", tempDir, false, Settings{PatternName: "new", ClusterGroupName: "hub"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualPatternName).To(Equal("new"))
+		Expect(clusterGroupName).To(Equal("hub"))
+
+		values, err := LoadGlobalValues("dir-name", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.Global.Pattern).To(Equal("new"))
+		Expect(values.Main.ClusterGroupName).To(Equal("hub"))
+	})
+
+	It("should deploy charts to the chosen namespaces", func() {
+		existing := "clusterGroup:\n  name: hub\n  namespaces:\n    - demo\n  applications:\n    app1:\n      name: app1\n      namespace: demo\n      path: charts/app1\n      syncPolicy: manual\n"
+		Expect(os.WriteFile(filepath.Join(tempDir, "values-hub.yaml"), []byte(existing), 0o644)).To(Succeed())
+
+		settings := Settings{ChartNamespaces: map[string]string{"charts/app1": "frontend", "charts/app2": "backend"}}
+		Expect(ProcessClusterGroupValues("demo", "hub", tempDir, []string{"charts/app1", "charts/app2", "charts/app3"}, false, settings)).To(Succeed())
+
+		values, err := LoadClusterGroupValues("hub", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		apps := values.ClusterGroup.Applications
+		Expect(apps["app1"].Namespace).To(Equal("frontend"))
+		Expect(apps["app1"].OtherFields).To(HaveKeyWithValue("syncPolicy", "manual"))
+		Expect(apps["app2"].Namespace).To(Equal("backend"))
+		Expect(apps["app3"].Namespace).To(Equal("demo"))
+		Expect(values.ClusterGroup.Namespaces).To(HaveKey("frontend"))
+		Expect(values.ClusterGroup.Namespaces).To(HaveKey("backend"))
+		Expect(values.ClusterGroup.Namespaces).To(HaveKey("demo"))
+	})
+
+	It("should report a missing clustergroup values file as nil", func() {
+		values, err := LoadClusterGroupValues("hub", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(BeNil())
+	})
+})
+
+var _ = Describe("ValidateName", func() {
+	DescribeTable("should accept DNS-1123 labels only",
+		func(name string, valid bool) {
+			if valid {
+				Expect(ValidateName(name)).To(Succeed())
+			} else {
+				Expect(ValidateName(name)).NotTo(Succeed())
+			}
+		},
+		Entry("simple", "hub", true),
+		Entry("with dashes", "multicloud-gitops", true),
+		Entry("digits", "group1", true),
+		Entry("empty", "", false),
+		Entry("upper case", "Hub", false),
+		Entry("underscore", "my_pattern", false),
+		Entry("leading dash", "-hub", false),
+		Entry("trailing dash", "hub-", false),
+		Entry("too long", strings.Repeat("a", 64), false),
+	)
+})
+
+var _ = Describe("ProcessGlobalValues with a chart version", func() {
+	It("should set the clustergroup chart version", func() {
+		tempDir := GinkgoT().TempDir()
+		_, _, err := ProcessGlobalValues("demo", tempDir, false, Settings{ClusterGroupChartVersion: "0.10.*"})
+		Expect(err).NotTo(HaveOccurred())
+
+		values, err := LoadGlobalValues("demo", tempDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values.Main.MultiSourceConfig.ClusterGroupChartVersion).To(Equal("0.10.*"))
+	})
+})
@@ -0,0 +1,397 @@
+package pattern
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+)
+
+// ErrClusterGroupNotFound is returned by PlanClusterGroupRename when no values
+// file refers to the cluster group.
+var ErrClusterGroupNotFound = errors.New("cluster group not found")
+
+// Move records a file that a rename moves to a new path.
+type Move struct {
+	From string
+	To   string
+}
+
+// RenamePlan holds the file changes that make up a rename. Nothing is changed
+// on disk until the edits are applied with fileutils.ApplyEdits.
+type RenamePlan struct {
+	Edits []fileutils.Edit
+	// Moves lists the files that are renamed.
+	Moves []Move
+}
+
+// valuesDoc is a parsed values file whose comments and key order are kept
+// when it is written back.
+type valuesDoc struct {
+	path    string
+	doc     yaml.Node
+	mode    os.FileMode
+	changed bool
+}
+
+// PlanPatternRename plans renaming the pattern oldName to newName. It updates
+// global.pattern, the namespace map key and the application and subscription
+// namespaces named after the pattern in every values file, and renames the
+// values-secret-<pattern>.yaml files found in homeDir.
+func PlanPatternRename(repoRoot, homeDir, oldName, newName string) (*RenamePlan, error) {
+	if err := ValidateName(newName); err != nil {
+		return nil, fmt.Errorf("invalid pattern name: %w", err)
+	}
+	if oldName == newName {
+		return nil, fmt.Errorf("the pattern is already named %q", newName)
+	}
+
+	docs, err := loadValuesDocs(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	global := findValuesDoc(docs, "values-global.yaml")
+	if global == nil {
+		return nil, fmt.Errorf("values-global.yaml not found in %s", repoRoot)
+	}
+	global.changed = setMappingPath(global.doc.Content[0], newName, "global", "pattern") || global.changed
+
+	for _, doc := range docs {
+		if renameNamespaceRefs(doc.doc.Content[0], oldName, newName) {
+			doc.changed = true
+		}
+	}
+
+	plan := &RenamePlan{}
+	if err := plan.addDocs(docs); err != nil {
+		return nil, err
+	}
+
+	if homeDir == "" {
+		return plan, nil
+	}
+	for _, dir := range secretsDirs {
+		oldPath := filepath.Join(homeDir, dir, fmt.Sprintf("values-secret-%s.yaml", oldName))
+		newPath := filepath.Join(homeDir, dir, fmt.Sprintf("values-secret-%s.yaml", newName))
+		if err := plan.addMove(oldPath, newPath, nil); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// PlanClusterGroupRename plans renaming the cluster group oldName to newName.
+// It renames values-<oldName>.yaml and updates its clusterGroup.name,
+// main.clusterGroupName, and the managedClusterGroups entries of every values
+// file that refer to the cluster group.
+func PlanClusterGroupRename(repoRoot, oldName, newName string) (*RenamePlan, error) {
+	if err := ValidateName(newName); err != nil {
+		return nil, fmt.Errorf("invalid cluster group name: %w", err)
+	}
+	if oldName == newName {
+		return nil, fmt.Errorf("the cluster group is already named %q", newName)
+	}
+
+	docs, err := loadValuesDocs(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := false
+	if global := findValuesDoc(docs, "values-global.yaml"); global != nil {
+		name := lookupMappingPath(global.doc.Content[0], "main", "clusterGroupName")
+		if name != nil && name.Value == oldName {
+			name.Value = newName
+			global.changed = true
+			referenced = true
+		}
+	}
+	for _, doc := range docs {
+		if renameManagedClusterGroup(doc.doc.Content[0], oldName, newName) {
+			doc.changed = true
+			referenced = true
+		}
+	}
+
+	plan := &RenamePlan{}
+	renamed := findValuesDoc(docs, fmt.Sprintf("values-%s.yaml", oldName))
+	if renamed != nil {
+		referenced = true
+		name := lookupMappingPath(renamed.doc.Content[0], "clusterGroup", "name")
+		if name != nil && name.Value == oldName {
+			name.Value = newName
+		}
+		content, err := encodeValuesDoc(renamed)
+		if err != nil {
+			return nil, err
+		}
+		newPath := filepath.Join(repoRoot, fmt.Sprintf("values-%s.yaml", newName))
+		if err := plan.addMove(renamed.path, newPath, content); err != nil {
+			return nil, err
+		}
+		renamed.changed = false
+	}
+	if !referenced {
+		return nil, fmt.Errorf("%w: no cluster group named %q found in the values files", ErrClusterGroupNotFound, oldName)
+	}
+
+	if err := plan.addDocs(docs); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// addDocs adds an edit for every changed values file.
+func (p *RenamePlan) addDocs(docs []*valuesDoc) error {
+	edits, err := docEdits(docs)
+	if err != nil {
+		return err
+	}
+	p.Edits = append(p.Edits, edits...)
+	return nil
+}
+
+// docEdits returns an edit for every changed values file.
+func docEdits(docs []*valuesDoc) ([]fileutils.Edit, error) {
+	var edits []fileutils.Edit
+	for _, doc := range docs {
+		if !doc.changed {
+			continue
+		}
+		content, err := encodeValuesDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, fileutils.Edit{Path: doc.path, Content: content, Mode: doc.mode})
+	}
+	return edits, nil
+}
+
+// addMove adds the edits that move oldPath to newPath, writing content to the
+// new file or, if content is nil, the current content of oldPath. Missing
+// files are skipped, and existing destinations are never overwritten.
+func (p *RenamePlan) addMove(oldPath, newPath string, content []byte) error {
+	info, err := os.Stat(oldPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", oldPath, err)
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("cannot rename %s to %s: the file already exists", oldPath, newPath)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat %s: %w", newPath, err)
+	}
+	if content == nil {
+		if content, err = os.ReadFile(oldPath); err != nil {
+			return fmt.Errorf("failed to read %s: %w", oldPath, err)
+		}
+	}
+
+	p.Edits = append(p.Edits,
+		fileutils.Edit{Path: newPath, Content: content, Mode: info.Mode().Perm()},
+		fileutils.Edit{Path: oldPath, Delete: true},
+	)
+	p.Moves = append(p.Moves, Move{From: oldPath, To: newPath})
+	return nil
+}
+
+// loadValuesDocs parses every values-*.yaml file in repoRoot. Empty files are skipped.
+func loadValuesDocs(repoRoot string) ([]*valuesDoc, error) {
+	paths, err := filepath.Glob(filepath.Join(repoRoot, "values-*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list values files: %w", err)
+	}
+	sort.Strings(paths)
+
+	docs := make([]*valuesDoc, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		doc := &valuesDoc{path: path, mode: info.Mode().Perm()}
+		if err := yaml.Unmarshal(content, &doc.doc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML from %s: %w", path, err)
+		}
+		if len(doc.doc.Content) == 0 {
+			continue
+		}
+		if doc.doc.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s does not contain a YAML mapping", path)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// findValuesDoc returns the document loaded from the file with the given base name.
+func findValuesDoc(docs []*valuesDoc, name string) *valuesDoc {
+	for _, doc := range docs {
+		if filepath.Base(doc.path) == name {
+			return doc
+		}
+	}
+	return nil
+}
+
+// encodeValuesDoc serializes a values document with 2-space indentation, like
+// fileutils.WriteYAMLWithIndent.
+func encodeValuesDoc(doc *valuesDoc) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc.doc); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML for %s: %w", doc.path, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML for %s: %w", doc.path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renameNamespaceRefs renames the namespace oldName in the namespaces,
+// applications and subscriptions of a cluster group values document.
+func renameNamespaceRefs(root *yaml.Node, oldName, newName string) bool {
+	clusterGroup := mappingValue(root, "clusterGroup")
+	if clusterGroup == nil {
+		return false
+	}
+	changed := false
+
+	// Namespaces are either a map keyed by name or a list of names and
+	// single-key maps.
+	if namespaces := mappingValue(clusterGroup, "namespaces"); namespaces != nil {
+		switch namespaces.Kind {
+		case yaml.MappingNode:
+			changed = renameMappingKey(namespaces, oldName, newName) || changed
+		case yaml.SequenceNode:
+			for _, item := range namespaces.Content {
+				switch {
+				case item.Kind == yaml.ScalarNode && item.Value == oldName:
+					item.Value = newName
+					changed = true
+				case item.Kind == yaml.MappingNode:
+					changed = renameMappingKey(item, oldName, newName) || changed
+				}
+			}
+		}
+	}
+
+	for _, section := range []string{"applications", "subscriptions"} {
+		entries := mappingValue(clusterGroup, section)
+		if entries == nil || entries.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 1; i < len(entries.Content); i += 2 {
+			namespace := mappingValue(entries.Content[i], "namespace")
+			if namespace != nil && namespace.Kind == yaml.ScalarNode && namespace.Value == oldName {
+				namespace.Value = newName
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// renameManagedClusterGroup renames the managed cluster group oldName. The
+// managedClusterGroups of a cluster group are either a map of entries or a list.
+func renameManagedClusterGroup(root *yaml.Node, oldName, newName string) bool {
+	managed := lookupMappingPath(root, "clusterGroup", "managedClusterGroups")
+	if managed == nil {
+		return false
+	}
+	changed := false
+
+	var entries []*yaml.Node
+	switch managed.Kind {
+	case yaml.MappingNode:
+		changed = renameMappingKey(managed, oldName, newName)
+		for i := 1; i < len(managed.Content); i += 2 {
+			entries = append(entries, managed.Content[i])
+		}
+	case yaml.SequenceNode:
+		entries = managed.Content
+	}
+	for _, entry := range entries {
+		name := mappingValue(entry, "name")
+		if name != nil && name.Kind == yaml.ScalarNode && name.Value == oldName {
+			name.Value = newName
+			changed = true
+		}
+	}
+	return changed
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lookupMappingPath follows keys through nested mapping nodes.
+func lookupMappingPath(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		node = mappingValue(node, key)
+	}
+	return node
+}
+
+// setMappingPath sets the string at keys to value, creating missing mappings.
+// It reports whether the document changed.
+func setMappingPath(node *yaml.Node, value string, keys ...string) bool {
+	return setMappingScalar(node, "!!str", value, keys...)
+}
+
+// setMappingScalar sets the scalar at keys to value with the given tag,
+// creating missing mappings. It reports whether the document changed.
+func setMappingScalar(node *yaml.Node, tag, value string, keys ...string) bool {
+	for i, key := range keys {
+		next := mappingValue(node, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if i == len(keys)-1 {
+				next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		} else if i < len(keys)-1 && next.Kind != yaml.MappingNode {
+			*next = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		node = next
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == tag && node.Value == value {
+		return false
+	}
+	*node = yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Style: node.Style, HeadComment: node.HeadComment, LineComment: node.LineComment, FootComment: node.FootComment}
+	return true
+}
+
+// renameMappingKey renames the key oldName of a mapping node.
+func renameMappingKey(node *yaml.Node, oldName, newName string) bool {
+	changed := false
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == oldName {
+			node.Content[i].Value = newName
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,219 @@
+package pattern
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/fileutils"
+)
+
+// readYAMLMap reads a YAML file into a generic map.
+func readYAMLMap(path string) map[string]interface{} {
+	data, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	var values map[string]interface{}
+	Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+	return values
+}
+
+var _ = Describe("PlanPatternRename", func() {
+	var repoRoot, homeDir string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		homeDir = GinkgoT().TempDir()
+
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-global.yaml"), []byte(`# Global settings
+global:
+  pattern: demo # the pattern name
+main:
+  clusterGroupName: hub
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-hub.yaml"), []byte(`clusterGroup:
+  name: hub
+  namespaces:
+    - open-cluster-management
+    - demo
+  subscriptions:
+    acm:
+      name: advanced-cluster-management
+      namespace: open-cluster-management
+  applications:
+    app:
+      name: app
+      namespace: demo
+      path: charts/app
+    other:
+      name: other
+      namespace: other
+      path: charts/other
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-edge.yaml"), []byte(`clusterGroup:
+  name: edge
+  namespaces:
+    demo:
+    edge-ns:
+  applications:
+    app:
+      name: app
+      namespace: demo
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(homeDir, "values-secret-demo.yaml"), []byte("secrets: []\n"), 0o600)).To(Succeed())
+	})
+
+	It("should rename every reference to the pattern", func() {
+		plan, err := PlanPatternRename(repoRoot, homeDir, "demo", "shop")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Moves).To(ConsistOf(Move{
+			From: filepath.Join(homeDir, "values-secret-demo.yaml"),
+			To:   filepath.Join(homeDir, "values-secret-shop.yaml"),
+		}))
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		global, err := os.ReadFile(filepath.Join(repoRoot, "values-global.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(global)).To(ContainSubstring("pattern: shop # the pattern name"))
+		Expect(string(global)).To(ContainSubstring("# Global settings"))
+
+		hub := readYAMLMap(filepath.Join(repoRoot, "values-hub.yaml"))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "namespaces"})).To(Equal([]interface{}{"open-cluster-management", "shop"}))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "applications", "app", "namespace"})).To(Equal("shop"))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "applications", "other", "namespace"})).To(Equal("other"))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "subscriptions", "acm", "namespace"})).To(Equal("open-cluster-management"))
+
+		edge := readYAMLMap(filepath.Join(repoRoot, "values-edge.yaml"))
+		Expect(getNestedValue(edge, []string{"clusterGroup", "namespaces"})).To(And(HaveKey("shop"), HaveKey("edge-ns"), Not(HaveKey("demo"))))
+		Expect(getNestedValue(edge, []string{"clusterGroup", "applications", "app", "namespace"})).To(Equal("shop"))
+
+		Expect(filepath.Join(homeDir, "values-secret-demo.yaml")).NotTo(BeAnExistingFile())
+		info, err := os.Stat(filepath.Join(homeDir, "values-secret-shop.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+	})
+
+	It("should not plan any change for unrelated values files", func() {
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-other.yaml"), []byte("clusterGroup:\n    name: other\n"), 0o644)).To(Succeed())
+
+		plan, err := PlanPatternRename(repoRoot, homeDir, "demo", "shop")
+		Expect(err).NotTo(HaveOccurred())
+		for _, edit := range plan.Edits {
+			Expect(edit.Path).NotTo(Equal(filepath.Join(repoRoot, "values-other.yaml")))
+		}
+	})
+
+	It("should refuse to overwrite an existing secrets file", func() {
+		Expect(os.WriteFile(filepath.Join(homeDir, "values-secret-shop.yaml"), []byte("secrets: []\n"), 0o600)).To(Succeed())
+
+		_, err := PlanPatternRename(repoRoot, homeDir, "demo", "shop")
+		Expect(err).To(MatchError(ContainSubstring("already exists")))
+	})
+
+	It("should reject invalid and unchanged names", func() {
+		_, err := PlanPatternRename(repoRoot, homeDir, "demo", "Shop")
+		Expect(err).To(MatchError(ContainSubstring("invalid pattern name")))
+		_, err = PlanPatternRename(repoRoot, homeDir, "demo", "demo")
+		Expect(err).To(MatchError(ContainSubstring("already named")))
+	})
+
+	It("should fail without values-global.yaml", func() {
+		Expect(os.Remove(filepath.Join(repoRoot, "values-global.yaml"))).To(Succeed())
+		_, err := PlanPatternRename(repoRoot, homeDir, "demo", "shop")
+		Expect(err).To(MatchError(ContainSubstring("values-global.yaml not found")))
+	})
+})
+
+var _ = Describe("PlanClusterGroupRename", func() {
+	var repoRoot string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-global.yaml"), []byte(`global:
+  pattern: demo
+main:
+  clusterGroupName: hub
+`), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-hub.yaml"), []byte(`clusterGroup:
+  name: hub
+  isHubCluster: true
+  managedClusterGroups:
+    edge:
+      name: edge
+      helmOverrides:
+        - name: clusterGroup.isHubCluster
+          value: false
+`), 0o640)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-edge.yaml"), []byte("clusterGroup:\n  name: edge\n"), 0o644)).To(Succeed())
+	})
+
+	It("should rename the main cluster group and its values file", func() {
+		plan, err := PlanClusterGroupRename(repoRoot, "hub", "datacenter")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Moves).To(ConsistOf(Move{
+			From: filepath.Join(repoRoot, "values-hub.yaml"),
+			To:   filepath.Join(repoRoot, "values-datacenter.yaml"),
+		}))
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		Expect(filepath.Join(repoRoot, "values-hub.yaml")).NotTo(BeAnExistingFile())
+		info, err := os.Stat(filepath.Join(repoRoot, "values-datacenter.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+
+		values := readYAMLMap(filepath.Join(repoRoot, "values-datacenter.yaml"))
+		Expect(getNestedValue(values, []string{"clusterGroup", "name"})).To(Equal("datacenter"))
+		Expect(getNestedValue(values, []string{"clusterGroup", "isHubCluster"})).To(BeTrue())
+
+		global := readYAMLMap(filepath.Join(repoRoot, "values-global.yaml"))
+		Expect(getNestedValue(global, []string{"main", "clusterGroupName"})).To(Equal("datacenter"))
+	})
+
+	It("should rename a managed cluster group", func() {
+		plan, err := PlanClusterGroupRename(repoRoot, "edge", "factory")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		hub := readYAMLMap(filepath.Join(repoRoot, "values-hub.yaml"))
+		managed := getNestedValue(hub, []string{"clusterGroup", "managedClusterGroups"})
+		Expect(managed).To(And(HaveKey("factory"), Not(HaveKey("edge"))))
+		Expect(getNestedValue(hub, []string{"clusterGroup", "managedClusterGroups", "factory", "name"})).To(Equal("factory"))
+
+		factory := readYAMLMap(filepath.Join(repoRoot, "values-factory.yaml"))
+		Expect(getNestedValue(factory, []string{"clusterGroup", "name"})).To(Equal("factory"))
+		global := readYAMLMap(filepath.Join(repoRoot, "values-global.yaml"))
+		Expect(getNestedValue(global, []string{"main", "clusterGroupName"})).To(Equal("hub"))
+	})
+
+	It("should rename managed cluster groups listed as a sequence", func() {
+		Expect(os.WriteFile(filepath.Join(repoRoot, "values-hub.yaml"), []byte(`clusterGroup:
+  name: hub
+  managedClusterGroups:
+    - name: edge
+      acmlabels:
+        - name: clusterGroup
+          value: edge
+`), 0o644)).To(Succeed())
+
+		plan, err := PlanClusterGroupRename(repoRoot, "edge", "factory")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileutils.ApplyEdits(plan.Edits)).To(Succeed())
+
+		hub := readYAMLMap(filepath.Join(repoRoot, "values-hub.yaml"))
+		managed := getNestedValue(hub, []string{"clusterGroup", "managedClusterGroups"}).([]interface{})
+		Expect(managed[0]).To(HaveKeyWithValue("name", "factory"))
+	})
+
+	It("should fail for an unknown cluster group", func() {
+		_, err := PlanClusterGroupRename(repoRoot, "missing", "factory")
+		Expect(err).To(MatchError(ErrClusterGroupNotFound))
+		Expect(err).To(MatchError(ContainSubstring(`no cluster group named "missing"`)))
+	})
+
+	It("should refuse to overwrite an existing values file", func() {
+		_, err := PlanClusterGroupRename(repoRoot, "hub", "edge")
+		Expect(err).To(MatchError(ContainSubstring("already exists")))
+	})
+})
@@ -0,0 +1,28 @@
+package pattern
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// VaultChart is the chart of the application that stores the secrets the
+// secret loader pushes.
+const VaultChart = "hashicorp-vault"
+
+// secretsDirs lists the directories, relative to the home directory, in which
+// the secret loader looks for values-secret-<pattern>.yaml.
+var secretsDirs = []string{
+	"",
+	filepath.Join(".config", "hybrid-cloud-patterns"),
+	filepath.Join(".config", "validated-patterns"),
+}
+
+// SecretsFiles returns the paths at which the secret loader looks for the
+// secrets of a pattern, in order.
+func SecretsFiles(homeDir, patternName string) []string {
+	files := make([]string, 0, len(secretsDirs))
+	for _, dir := range secretsDirs {
+		files = append(files, filepath.Join(homeDir, dir, fmt.Sprintf("values-secret-%s.yaml", patternName)))
+	}
+	return files
+}
@@ -0,0 +1,106 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Prompter asks the user questions and returns the answers.
+type Prompter interface {
+	// Info shows an explanatory message.
+	Info(format string, args ...interface{})
+	// Input asks for a line of text. An empty answer selects defaultValue.
+	// If validate is not nil, the question is repeated until it accepts the answer.
+	Input(question, defaultValue string, validate func(string) error) (string, error)
+	// Confirm asks a yes/no question. An empty answer selects defaultValue.
+	Confirm(question string, defaultValue bool) (bool, error)
+}
+
+// New returns a Prompter that writes questions to out and reads one answer
+// per line from in.
+func New(in io.Reader, out io.Writer) Prompter {
+	return &linePrompter{in: bufio.NewReader(in), out: out}
+}
+
+// linePrompter is a line-oriented Prompter for terminals and piped input.
+type linePrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// Info implements Prompter.
+func (p *linePrompter) Info(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, format+"\n", args...)
+}
+
+// Input implements Prompter.
+func (p *linePrompter) Input(question, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+
+		answer, err := p.readLine(question)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(p.out, "Invalid answer: %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// Confirm implements Prompter.
+func (p *linePrompter) Confirm(question string, defaultValue bool) (bool, error) {
+	choices := "y/N"
+	if defaultValue {
+		choices = "Y/n"
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, choices)
+
+		answer, err := p.readLine(question)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultValue, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		default:
+			fmt.Fprintln(p.out, "Please answer yes or no.")
+		}
+	}
+}
+
+// readLine reads one answer. Running out of input is an error so that
+// scripted answers that are too short do not loop forever.
+func (p *linePrompter) readLine(question string) (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(p.out)
+			return "", fmt.Errorf("no answer to %q: %w", question, io.ErrUnexpectedEOF)
+		}
+		return "", fmt.Errorf("error reading answer to %q: %w", question, err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,89 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prompter", func() {
+	var out *bytes.Buffer
+
+	newPrompter := func(input string) Prompter {
+		out = &bytes.Buffer{}
+		return New(strings.NewReader(input), out)
+	}
+
+	Describe("Input", func() {
+		It("should return the answer without surrounding whitespace", func() {
+			answer, err := newPrompter("  hub  \n").Input("Clustergroup", "prod", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer).To(Equal("hub"))
+			Expect(out.String()).To(Equal("Clustergroup [prod]: "))
+		})
+
+		It("should return the default for an empty answer", func() {
+			answer, err := newPrompter("\n").Input("Clustergroup", "prod", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer).To(Equal("prod"))
+		})
+
+		It("should accept a last answer without a trailing newline", func() {
+			answer, err := newPrompter("hub").Input("Clustergroup", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer).To(Equal("hub"))
+			Expect(out.String()).To(Equal("Clustergroup: "))
+		})
+
+		It("should repeat the question until the answer is valid", func() {
+			validate := func(s string) error {
+				if s != strings.ToLower(s) {
+					return errors.New("must be lower case")
+				}
+				return nil
+			}
+			answer, err := newPrompter("Hub\nhub\n").Input("Clustergroup", "prod", validate)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer).To(Equal("hub"))
+			Expect(out.String()).To(Equal("Clustergroup [prod]: Invalid answer: must be lower case\nClustergroup [prod]: "))
+		})
+
+		It("should fail when the input ends", func() {
+			_, err := newPrompter("").Input("Clustergroup", "prod", nil)
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+			Expect(err).To(MatchError(ContainSubstring(`no answer to "Clustergroup"`)))
+		})
+	})
+
+	Describe("Confirm", func() {
+		DescribeTable("should parse the answer",
+			func(input string, defaultValue, expected bool) {
+				answer, err := newPrompter(input).Confirm("Enable secrets", defaultValue)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(answer).To(Equal(expected))
+			},
+			Entry("yes", "yes\n", false, true),
+			Entry("y", "Y\n", false, true),
+			Entry("no", "no\n", true, false),
+			Entry("n", "n\n", true, false),
+			Entry("default yes", "\n", true, true),
+			Entry("default no", "\n", false, false),
+		)
+
+		It("should show the default and repeat the question on other answers", func() {
+			answer, err := newPrompter("maybe\ny\n").Confirm("Enable secrets", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer).To(BeTrue())
+			Expect(out.String()).To(Equal("Enable secrets [y/N]: Please answer yes or no.\nEnable secrets [y/N]: "))
+		})
+	})
+
+	It("should print informational messages", func() {
+		newPrompter("").Info("Found %d charts", 2)
+		Expect(out.String()).To(Equal("Found 2 charts\n"))
+	})
+})
@@ -0,0 +1,24 @@
+package report
+
+import "fmt"
+
+// Error wraps an error with a stable code for machine-readable output.
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf formats an error like fmt.Errorf and tags it with code.
+func Errorf(code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,253 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Supported output formats for the global --output flag.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Error and warning codes emitted in reports.
+const (
+	CodeInternal          = "internal"
+	CodeRepository        = "repository"
+	CodeChartDiscovery    = "chart-discovery"
+	CodeGlobalValues      = "global-values"
+	CodeClusterGroup      = "clustergroup-values"
+	CodeResourceCopy      = "resource-copy"
+	CodeMakefile          = "makefile"
+	CodeSecrets           = "secrets"
+	CodeSkills            = "skills"
+	CodeLegacyCommon      = "legacy-common-removed"
+	CodeLegacySymlink     = "legacy-pattern-sh-symlink"
+	CodeMakefileInclude   = "makefile-include-added"
+	CodeMakefileTarget    = "makefile-target-collision"
+	CodeMetadata          = "metadata"
+	CodeVersionUnknown    = "version-unknown"
+	CodeDowngrade         = "downgrade"
+	CodeResourceVersion   = "resource-version"
+	CodeConfig            = "config"
+	CodeResourceOverrides = "resource-overrides"
+	CodeResourceTemplate  = "resource-template"
+	CodeRename            = "rename"
+	CodeOverride          = "override"
+	CodeMigration         = "migration"
+	CodeBackup            = "backup"
+	CodeDirtyTree         = "dirty-tree"
+	CodeCommit            = "commit"
+)
+
+// Action describes what happened to a file during a command run.
+type Action string
+
+// File actions recorded in a report.
+const (
+	ActionCreated  Action = "created"
+	ActionModified Action = "modified"
+	ActionDeleted  Action = "deleted"
+)
+
+// FileChange records a single file that was created, modified or deleted.
+type FileChange struct {
+	Path    string `json:"path"`
+	Action  Action `json:"action"`
+	OldHash string `json:"oldSha256,omitempty"`
+	NewHash string `json:"sha256,omitempty"`
+}
+
+// Message is a coded warning or error.
+type Message struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Report is the machine-readable summary of a command run.
+type Report struct {
+	Command      string       `json:"command"`
+	Success      bool         `json:"success"`
+	RepoRoot     string       `json:"repoRoot,omitempty"`
+	PatternName  string       `json:"patternName,omitempty"`
+	ClusterGroup string       `json:"clusterGroup,omitempty"`
+	Charts       []string     `json:"charts"`
+	Files        []FileChange `json:"files"`
+	Skills       []string     `json:"skills"`
+	// Commit is the ID of the commit made with --commit.
+	Commit   string    `json:"commit,omitempty"`
+	Warnings []Message `json:"warnings"`
+	Errors   []Message `json:"errors"`
+	Messages []string  `json:"messages"`
+}
+
+// New creates an empty report for the named command.
+// Slices are initialized so that they serialize as [] rather than null.
+func New(command string) *Report {
+	return &Report{
+		Command:  command,
+		Charts:   []string{},
+		Files:    []FileChange{},
+		Skills:   []string{},
+		Warnings: []Message{},
+		Errors:   []Message{},
+		Messages: []string{},
+	}
+}
+
+// Infof appends a human-readable progress message.
+func (r *Report) Infof(format string, args ...interface{}) {
+	r.Messages = append(r.Messages, fmt.Sprintf(format, args...))
+}
+
+// Warnf appends a coded warning.
+func (r *Report) Warnf(code, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, Message{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Fail records err in the report, using its code if it is a coded Error.
+func (r *Report) Fail(err error) {
+	code := CodeInternal
+	var coded *Error
+	if errors.As(err, &coded) {
+		code = coded.Code
+	}
+	r.Errors = append(r.Errors, Message{Code: code, Message: err.Error()})
+	r.Success = false
+}
+
+// Write renders the report to w in the given format.
+// The text format prints warnings and messages; the JSON format prints the whole report.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatText, "":
+		for _, warning := range r.Warnings {
+			if _, err := fmt.Fprintf(w, "Warning: %s\n", warning.Message); err != nil {
+				return err
+			}
+		}
+		for _, msg := range r.Messages {
+			if _, err := fmt.Fprintln(w, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// ValidateFormat returns an error if format is not a supported output format.
+func ValidateFormat(format string) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// Snapshot records the SHA-256 of every regular file under the given paths,
+// which are relative to root. Directories are walked recursively and missing
+// paths are ignored. The returned map is keyed by slash-separated relative path.
+func Snapshot(root string, paths []string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, p := range paths {
+		start := filepath.Join(root, p)
+		if _, err := os.Lstat(start); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("lstat %s: %w", start, err)
+		}
+
+		err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return fmt.Errorf("compute relative path for %s: %w", path, err)
+			}
+			var hash string
+			if d.Type()&fs.ModeSymlink != 0 {
+				target, err := os.Readlink(path)
+				if err != nil {
+					return fmt.Errorf("readlink %s: %w", path, err)
+				}
+				hash = HashBytes([]byte(target))
+			} else {
+				hash, err = HashFile(path)
+				if err != nil {
+					return err
+				}
+			}
+			hashes[filepath.ToSlash(rel)] = hash
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", start, err)
+		}
+	}
+	return hashes, nil
+}
+
+// RecordChanges compares two snapshots and appends the differences to the report,
+// sorted by path.
+func (r *Report) RecordChanges(before, after map[string]string) {
+	paths := make(map[string]struct{}, len(before)+len(after))
+	for p := range before {
+		paths[p] = struct{}{}
+	}
+	for p := range after {
+		paths[p] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	for _, p := range sorted {
+		oldHash, existed := before[p]
+		newHash, exists := after[p]
+		switch {
+		case !existed && exists:
+			r.Files = append(r.Files, FileChange{Path: p, Action: ActionCreated, NewHash: newHash})
+		case existed && !exists:
+			r.Files = append(r.Files, FileChange{Path: p, Action: ActionDeleted, OldHash: oldHash})
+		case oldHash != newHash:
+			r.Files = append(r.Files, FileChange{Path: p, Action: ActionModified, OldHash: oldHash, NewHash: newHash})
+		}
+	}
+}
+
+// HashFile returns the hex-encoded SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return HashBytes(data), nil
+}
+
+// HashBytes returns the hex-encoded SHA-256 of data.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
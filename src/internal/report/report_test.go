@@ -0,0 +1,84 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+var _ = Describe("Snapshot", func() {
+	It("should hash files and walk directories, ignoring missing paths", func() {
+		root := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, "dir", "sub"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "dir", "sub", "b.txt"), []byte("b"), 0o644)).To(Succeed())
+
+		hashes, err := report.Snapshot(root, []string{"a.txt", "dir", "missing"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hashes).To(Equal(map[string]string{
+			"a.txt":         report.HashBytes([]byte("a")),
+			"dir/sub/b.txt": report.HashBytes([]byte("b")),
+		}))
+	})
+})
+
+var _ = Describe("RecordChanges", func() {
+	It("should record created, modified and deleted files sorted by path", func() {
+		rep := report.New("test")
+		rep.RecordChanges(
+			map[string]string{"deleted": "1", "modified": "2", "same": "3"},
+			map[string]string{"created": "4", "modified": "5", "same": "3"},
+		)
+
+		Expect(rep.Files).To(Equal([]report.FileChange{
+			{Path: "created", Action: report.ActionCreated, NewHash: "4"},
+			{Path: "deleted", Action: report.ActionDeleted, OldHash: "1"},
+			{Path: "modified", Action: report.ActionModified, OldHash: "2", NewHash: "5"},
+		}))
+	})
+})
+
+var _ = Describe("Report", func() {
+	It("should record the code of a coded error", func() {
+		rep := report.New("test")
+		rep.Fail(fmt.Errorf("outer: %w", report.Errorf(report.CodeSkills, "inner")))
+		rep.Fail(fmt.Errorf("plain"))
+
+		Expect(rep.Errors).To(Equal([]report.Message{
+			{Code: report.CodeSkills, Message: "outer: inner"},
+			{Code: report.CodeInternal, Message: "plain"},
+		}))
+	})
+
+	It("should print warnings and messages in text mode", func() {
+		rep := report.New("test")
+		rep.Warnf("code", "careful %s", "now")
+		rep.Infof("done")
+
+		var buf bytes.Buffer
+		Expect(rep.Write(&buf, report.FormatText)).To(Succeed())
+		Expect(buf.String()).To(Equal("Warning: careful now\ndone\n"))
+	})
+
+	It("should serialize empty lists as arrays in JSON mode", func() {
+		var buf bytes.Buffer
+		Expect(report.New("test").Write(&buf, report.FormatJSON)).To(Succeed())
+
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded["files"]).To(Equal([]interface{}{}))
+		Expect(decoded["warnings"]).To(Equal([]interface{}{}))
+	})
+
+	It("should reject unknown formats", func() {
+		Expect(report.ValidateFormat("yaml")).To(HaveOccurred())
+		Expect(report.New("test").Write(&bytes.Buffer{}, "yaml")).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,161 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/version"
+)
+
+// Dir is the directory inside every resource set that holds the resource files.
+const Dir = "resources"
+
+// ErrUnknownVersion is returned when a requested resource version is not in the catalog.
+var ErrUnknownVersion = errors.New("unknown resource version")
+
+// Catalog maps resource versions to resource sets. Every set is an fs.FS
+// containing the files under Dir, like embedded.Resources.
+type Catalog struct {
+	currentVersion string
+	current        fs.FS
+	history        fs.FS
+}
+
+// NewCatalog creates a catalog from the current resource set and a history
+// filesystem laid out as <version>/resources/<file>.
+func NewCatalog(currentVersion string, current, history fs.FS) *Catalog {
+	return &Catalog{currentVersion: canonical(currentVersion), current: current, history: history}
+}
+
+// Embedded returns the catalog of resource sets compiled into the binary.
+func Embedded() *Catalog {
+	history, err := fs.Sub(embedded.History, "history")
+	if err != nil {
+		// fs.Sub only fails for invalid paths, and "history" is a constant valid path.
+		panic(err)
+	}
+	return NewCatalog(embedded.ResourceVersion, embedded.Resources, history)
+}
+
+// Current returns the version of the newest resource set.
+func (c *Catalog) Current() string {
+	return c.currentVersion
+}
+
+// Versions returns every known resource version in ascending order.
+func (c *Catalog) Versions() ([]string, error) {
+	versions := []string{c.currentVersion}
+
+	entries, err := fs.ReadDir(c.history, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading resource history: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !semver.IsValid(entry.Name()) || entry.Name() == c.currentVersion {
+			continue
+		}
+		versions = append(versions, entry.Name())
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// Set returns the resource set for version, together with its canonical version string.
+func (c *Catalog) Set(resourceVersion string) (fs.FS, string, error) {
+	v := canonical(resourceVersion)
+	if v == c.currentVersion {
+		return c.current, v, nil
+	}
+
+	if _, err := fs.Stat(c.history, path.Join(v, Dir)); err != nil {
+		versions, listErr := c.Versions()
+		if listErr != nil {
+			return nil, "", listErr
+		}
+		return nil, "", fmt.Errorf("%w %q (available: %s)", ErrUnknownVersion, resourceVersion, strings.Join(versions, ", "))
+	}
+
+	set, err := fs.Sub(c.history, v)
+	if err != nil {
+		return nil, "", fmt.Errorf("error opening resource set %s: %w", v, err)
+	}
+	return set, v, nil
+}
+
+// Detect returns the newest resource version whose files all match the
+// corresponding files in repoRoot. Makefile and values-secret.yaml.template are
+// ignored because they are meant to be edited by users. It returns false if no
+// version matches.
+func (c *Catalog) Detect(repoRoot string) (string, bool, error) {
+	versions, err := c.Versions()
+	if err != nil {
+		return "", false, err
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		set, _, err := c.Set(versions[i])
+		if err != nil {
+			return "", false, err
+		}
+		// Repositories generated with the default template data are the
+		// only ones that can be recognized by content.
+		set, err = Render(set, TemplateData{})
+		if err != nil {
+			return "", false, err
+		}
+		matches, err := matchesRepo(set, repoRoot)
+		if err != nil {
+			return "", false, err
+		}
+		if matches {
+			return versions[i], true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// matchesRepo reports whether every refreshed file of set is present in repoRoot with identical contents.
+func matchesRepo(set fs.FS, repoRoot string) (bool, error) {
+	for _, name := range RefreshedFiles {
+		want, err := fs.ReadFile(set, path.Join(Dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("error reading resource %s: %w", name, err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(repoRoot, name))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		if string(got) != string(want) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// canonical returns the canonical form of a resource version, or version
+// itself, trimmed, if it is not a valid semantic version.
+func canonical(v string) string {
+	if c, ok := version.Canonical(v); ok {
+		return c
+	}
+	return strings.TrimSpace(v)
+}
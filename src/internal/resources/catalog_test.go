@@ -0,0 +1,81 @@
+package resources
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func testCatalog() *Catalog {
+	current := fstest.MapFS{
+		"resources/pattern.sh":      &fstest.MapFile{Data: []byte("#!/bin/bash\n# v3\n")},
+		"resources/Makefile-common": &fstest.MapFile{Data: []byte("install:\n\t@echo v3\n")},
+		"resources/ansible.cfg":     &fstest.MapFile{Data: []byte("[defaults]\n")},
+	}
+	history := fstest.MapFS{
+		"README.md":                        &fstest.MapFile{Data: []byte("docs")},
+		"v1.0.0/resources/pattern.sh":      &fstest.MapFile{Data: []byte("#!/bin/bash\n# v1\n")},
+		"v1.0.0/resources/Makefile-common": &fstest.MapFile{Data: []byte("install:\n\t@echo v1\n")},
+		"v1.0.0/resources/ansible.cfg":     &fstest.MapFile{Data: []byte("[defaults]\n")},
+		"v1.10.0/resources/pattern.sh":     &fstest.MapFile{Data: []byte("#!/bin/bash\n# v1.10\n")},
+	}
+	return NewCatalog("3.0.0", current, history)
+}
+
+var _ = Describe("Catalog", func() {
+	It("should list versions in semantic version order", func() {
+		versions, err := testCatalog().Versions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]string{"v1.0.0", "v1.10.0", "v3.0.0"}))
+	})
+
+	It("should return resource sets by version, with or without the v prefix", func() {
+		catalog := testCatalog()
+
+		set, v, err := catalog.Set("1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal("v1.0.0"))
+		data, err := fs.ReadFile(set, "resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("# v1"))
+
+		_, v, err = catalog.Set("v3.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal(catalog.Current()))
+	})
+
+	It("should reject unknown versions and list the available ones", func() {
+		_, _, err := testCatalog().Set("v2.0.0")
+		Expect(err).To(MatchError(ErrUnknownVersion))
+		Expect(err.Error()).To(ContainSubstring("v1.0.0, v1.10.0, v3.0.0"))
+	})
+
+	It("should detect the version that matches the files in a repository", func() {
+		repoRoot := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(repoRoot, "pattern.sh"), []byte("#!/bin/bash\n# v1\n"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "Makefile-common"), []byte("install:\n\t@echo v1\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repoRoot, "ansible.cfg"), []byte("[defaults]\n"), 0o644)).To(Succeed())
+
+		v, ok, err := testCatalog().Detect(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal("v1.0.0"))
+
+		Expect(os.WriteFile(filepath.Join(repoRoot, "pattern.sh"), []byte("customized\n"), 0o755)).To(Succeed())
+		_, ok, err = testCatalog().Detect(repoRoot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should contain the embedded current resource set", func() {
+		catalog := Embedded()
+		set, _, err := catalog.Set(catalog.Current())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = fs.Stat(set, "resources/Makefile-common")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,175 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Status values used in a changelog.
+const (
+	StatusAdded     = "added"
+	StatusRemoved   = "removed"
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+)
+
+// FileChange summarizes how one resource file differs between two resource sets.
+type FileChange struct {
+	Name           string   `json:"name"`
+	Status         string   `json:"status"`
+	LinesAdded     int      `json:"linesAdded"`
+	LinesRemoved   int      `json:"linesRemoved"`
+	TargetsAdded   []string `json:"targetsAdded,omitempty"`
+	TargetsRemoved []string `json:"targetsRemoved,omitempty"`
+}
+
+// Changelog compares every file of two resource sets, sorted by name.
+// For Makefiles it also lists the make targets that were added or removed.
+func Changelog(from, to fs.FS) ([]FileChange, error) {
+	fromFiles, err := readSet(from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := readSet(to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	for name := range fromFiles {
+		names[name] = struct{}{}
+	}
+	for name := range toFiles {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changes := make([]FileChange, 0, len(sorted))
+	for _, name := range sorted {
+		oldContent, inFrom := fromFiles[name]
+		newContent, inTo := toFiles[name]
+		change := FileChange{Name: name}
+
+		switch {
+		case !inFrom:
+			change.Status = StatusAdded
+		case !inTo:
+			change.Status = StatusRemoved
+		case oldContent == newContent:
+			change.Status = StatusUnchanged
+		default:
+			change.Status = StatusChanged
+		}
+
+		change.LinesAdded, change.LinesRemoved = diffLineCounts(splitLines(oldContent), splitLines(newContent))
+		if strings.HasPrefix(name, "Makefile") {
+			change.TargetsAdded, change.TargetsRemoved = diffStrings(makeTargets(oldContent), makeTargets(newContent))
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// readSet reads every file under Dir of a resource set.
+func readSet(set fs.FS) (map[string]string, error) {
+	entries, err := fs.ReadDir(set, Dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading resource set: %w", err)
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(set, path.Join(Dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading resource %s: %w", entry.Name(), err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files, nil
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLineCounts returns the number of lines added and removed between a and b,
+// based on their longest common subsequence.
+func diffLineCounts(a, b []string) (added, removed int) {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	common := lcs[0][0]
+	return len(b) - common, len(a) - common
+}
+
+// targetLine matches a rule line such as "install: pattern-install ## ..." or
+// "operator-deploy operator-upgrade:", but not variable assignments.
+var targetLine = regexp.MustCompile(`^([A-Za-z0-9_.%/ -]+?)\s*::?([^:=]|$)`)
+
+// makeTargets returns the targets defined by rules in a Makefile, excluding special targets like .PHONY.
+func makeTargets(content string) []string {
+	var targets []string
+	for _, line := range splitLines(content) {
+		match := targetLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, target := range strings.Fields(match[1]) {
+			if !strings.HasPrefix(target, ".") {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// diffStrings returns the elements only in b (added) and only in a (removed), sorted.
+func diffStrings(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	for s := range inB {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range inA {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,50 @@
+package resources
+
+import (
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Changelog", func() {
+	It("should summarize added, removed, changed and unchanged files", func() {
+		from := fstest.MapFS{
+			"resources/Makefile-common": &fstest.MapFile{Data: []byte("VAR := 1\n\n.PHONY: show\nshow:\n\t@echo show\n\nold-target:\n\t@echo old\n")},
+			"resources/ansible.cfg":     &fstest.MapFile{Data: []byte("[defaults]\n")},
+			"resources/removed.txt":     &fstest.MapFile{Data: []byte("gone\n")},
+		}
+		to := fstest.MapFS{
+			"resources/Makefile-common": &fstest.MapFile{Data: []byte("VAR := 1\n\n.PHONY: show\nshow:\n\t@echo show\n\noperator-deploy operator-upgrade: ## deploy\n\t@echo deploy\n")},
+			"resources/ansible.cfg":     &fstest.MapFile{Data: []byte("[defaults]\n")},
+			"resources/pattern.sh":      &fstest.MapFile{Data: []byte("#!/bin/bash\n")},
+		}
+
+		changes, err := Changelog(from, to)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(Equal([]FileChange{
+			{
+				Name:           "Makefile-common",
+				Status:         StatusChanged,
+				LinesAdded:     2,
+				LinesRemoved:   2,
+				TargetsAdded:   []string{"operator-deploy", "operator-upgrade"},
+				TargetsRemoved: []string{"old-target"},
+			},
+			{Name: "ansible.cfg", Status: StatusUnchanged},
+			{Name: "pattern.sh", Status: StatusAdded, LinesAdded: 1},
+			{Name: "removed.txt", Status: StatusRemoved, LinesRemoved: 1},
+		}))
+	})
+})
+
+var _ = DescribeTable("makeTargets",
+	func(content string, expected []string) {
+		Expect(makeTargets(content)).To(Equal(expected))
+	},
+	Entry("simple rule", "install: pattern-install ## Install\n", []string{"install"}),
+	Entry("multiple targets", "a b: c\n", []string{"a", "b"}),
+	Entry("special targets are skipped", ".PHONY: install\n", nil),
+	Entry("variable assignments are skipped", "VAR := value\nOTHER ?= x\nX::=y\n", nil),
+	Entry("recipe lines are skipped", "\t@echo a: b\n", nil),
+)
@@ -0,0 +1,73 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// KnownFiles lists every file name that can appear in a resource set.
+var KnownFiles = []string{"Makefile", "Makefile-common", "ansible.cfg", "pattern.sh", "values-secret.yaml.template"}
+
+// WithOverrides returns a resource set in which files at the root of overrides
+// replace the corresponding files of base. Overrides must provide every file in
+// RefreshedFiles; the remaining known files fall back to base. The names of
+// files in overrides that do not correspond to any resource are returned so
+// that callers can warn about them.
+func WithOverrides(base, overrides fs.FS) (fs.FS, []string, error) {
+	var missing []string
+	for _, name := range RefreshedFiles {
+		info, err := fs.Stat(overrides, name)
+		if err != nil || !info.Mode().IsRegular() {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("resource overrides are missing required files: %s", strings.Join(missing, ", "))
+	}
+
+	entries, err := fs.ReadDir(overrides, ".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading resource overrides: %w", err)
+	}
+	var unknown []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if entry.IsDir() || !slices.Contains(KnownFiles, entry.Name()) {
+			unknown = append(unknown, entry.Name())
+		}
+	}
+
+	return &overlayFS{base: base, overrides: overrides}, unknown, nil
+}
+
+// overlayFS serves resources/<name> from overrides when present and from base otherwise.
+type overlayFS struct {
+	base      fs.FS
+	overrides fs.FS
+}
+
+// Open implements fs.FS.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if rest, ok := strings.CutPrefix(name, Dir+"/"); ok && slices.Contains(KnownFiles, rest) {
+		f, err := o.overrides.Open(rest)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS. The overlay never adds files, so the
+// listing of base is returned.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(o.base, path.Clean(name))
+}
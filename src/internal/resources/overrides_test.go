@@ -0,0 +1,49 @@
+package resources
+
+import (
+	"io/fs"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithOverrides", func() {
+	base := fstest.MapFS{
+		"resources/Makefile":        &fstest.MapFile{Data: []byte("base Makefile")},
+		"resources/Makefile-common": &fstest.MapFile{Data: []byte("base Makefile-common")},
+		"resources/ansible.cfg":     &fstest.MapFile{Data: []byte("base ansible.cfg")},
+		"resources/pattern.sh":      &fstest.MapFile{Data: []byte("base pattern.sh")},
+	}
+
+	It("should serve overridden files and fall back to the base for the rest", func() {
+		overrides := fstest.MapFS{
+			"Makefile-common": &fstest.MapFile{Data: []byte("org Makefile-common")},
+			"ansible.cfg":     &fstest.MapFile{Data: []byte("org ansible.cfg")},
+			"pattern.sh":      &fstest.MapFile{Data: []byte("org pattern.sh")},
+			"README.md":       &fstest.MapFile{Data: []byte("docs")},
+			".gitignore":      &fstest.MapFile{Data: []byte("")},
+		}
+
+		set, unknown, err := WithOverrides(base, overrides)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unknown).To(Equal([]string{"README.md"}))
+
+		data, err := fs.ReadFile(set, "resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("org pattern.sh"))
+
+		data, err = fs.ReadFile(set, "resources/Makefile")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("base Makefile"))
+	})
+
+	It("should require every refreshed file", func() {
+		overrides := fstest.MapFS{
+			"pattern.sh": &fstest.MapFile{Data: []byte("org pattern.sh")},
+		}
+
+		_, _, err := WithOverrides(base, overrides)
+		Expect(err).To(MatchError(ContainSubstring("missing required files: Makefile-common, ansible.cfg")))
+	})
+})
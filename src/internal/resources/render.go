@@ -0,0 +1,176 @@
+package resources
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultUtilityContainer is the utility container image pattern.sh runs when
+// neither the repository configuration nor the environment selects another one.
+const DefaultUtilityContainer = "quay.io/validatedpatterns/utility-container"
+
+// TemplateData is the context every resource file is rendered with.
+type TemplateData struct {
+	// PatternName is the name of the pattern (global.pattern).
+	PatternName string
+	// ClusterGroup is the name of the main cluster group (main.clusterGroupName).
+	ClusterGroup string
+	// WithSecrets reports whether the secret loader is enabled.
+	WithSecrets bool
+	// UtilityContainer is the default utility container image of pattern.sh.
+	UtilityContainer string
+	// DisconnectedHome is the default PATTERN_DISCONNECTED_HOME of pattern.sh.
+	// It is left unset when empty.
+	DisconnectedHome string
+	// ContainerRuntime selects the container runtime pattern.sh uses: podman,
+	// docker, or auto to detect one at run time. Empty means podman.
+	ContainerRuntime string
+	// EnvPassthrough lists the environment variables pattern.sh passes
+	// through to the container. Render always sets it to EnvPassthrough.
+	EnvPassthrough []string
+}
+
+// Supported container runtimes. RuntimeAuto prefers podman and falls back to
+// docker at run time.
+const (
+	RuntimePodman = "podman"
+	RuntimeDocker = "docker"
+	RuntimeAuto   = "auto"
+)
+
+// ContainerRuntimes lists the values accepted for TemplateData.ContainerRuntime.
+var ContainerRuntimes = []string{RuntimePodman, RuntimeDocker, RuntimeAuto}
+
+// DefaultContainerRuntime is the container runtime used unless the repository
+// configuration or a flag selects another one.
+const DefaultContainerRuntime = RuntimePodman
+
+// EnvPassthrough lists the environment variables forwarded to the utility
+// container. Entries ending in "*" forward every variable with that prefix.
+var EnvPassthrough = []string{
+	"ANSIBLE_STDOUT_CALLBACK",
+	"DISABLE_VALIDATE_ORIGIN",
+	"EXTRA_HELM_OPTS",
+	"EXTRA_PLAYBOOK_OPTS",
+	"K8S_AUTH_HOST",
+	"K8S_AUTH_PASSWORD",
+	"K8S_AUTH_SSL_CA_CERT",
+	"K8S_AUTH_TOKEN",
+	"K8S_AUTH_USERNAME",
+	"K8S_AUTH_VERIFY_SSL",
+	"KUBECONFIG",
+	"PATTERN_DIR",
+	"PATTERN_DISCONNECTED_HOME",
+	"PATTERN_INSTALL_CHART",
+	"PATTERN_NAME",
+	"TARGET_BRANCH",
+	"TARGET_CLUSTERGROUP",
+	"TARGET_VARIANT",
+	"TARGET_ORIGIN",
+	"TOKEN_NAMESPACE",
+	"TOKEN_SECRET",
+	"UUID_FILE",
+	"VALUES_SECRET",
+	"VP_*",
+}
+
+// Render returns a resource set in which every known file of set has been
+// rendered as a text/template with data. Templates are rendered eagerly so
+// that errors are reported before any file is written. Files without template
+// actions are returned unchanged.
+func Render(set fs.FS, data TemplateData) (fs.FS, error) {
+	if data.UtilityContainer == "" {
+		data.UtilityContainer = DefaultUtilityContainer
+	}
+	if data.ContainerRuntime == "" {
+		data.ContainerRuntime = DefaultContainerRuntime
+	}
+	if !slices.Contains(ContainerRuntimes, data.ContainerRuntime) {
+		return nil, fmt.Errorf("unsupported container runtime %q (expected one of: %s)", data.ContainerRuntime, strings.Join(ContainerRuntimes, ", "))
+	}
+	data.EnvPassthrough = EnvPassthrough
+
+	rendered := &renderedFS{base: set, files: make(map[string][]byte)}
+	for _, name := range KnownFiles {
+		resourcePath := path.Join(Dir, name)
+		content, err := fs.ReadFile(set, resourcePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading resource %s: %w", name, err)
+		}
+
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing resource template %s: %w", name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("error rendering resource template %s: %w", name, err)
+		}
+		rendered.files[resourcePath] = buf.Bytes()
+	}
+	return rendered, nil
+}
+
+// renderedFS serves the rendered resource files from memory and everything
+// else, including directory listings, from base.
+type renderedFS struct {
+	base  fs.FS
+	files map[string][]byte
+}
+
+// Open implements fs.FS.
+func (r *renderedFS) Open(name string) (fs.File, error) {
+	content, ok := r.files[name]
+	if !ok {
+		return r.base.Open(name)
+	}
+	info, err := fs.Stat(r.base, name)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{
+		Reader: bytes.NewReader(content),
+		info:   memFileInfo{name: path.Base(name), size: int64(len(content)), mode: info.Mode(), modTime: info.ModTime()},
+	}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. Rendering never adds files, so the
+// listing of base is returned.
+func (r *renderedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.base, path.Clean(name))
+}
+
+// memFile is a read-only in-memory fs.File.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *memFile) Close() error { return nil }
+
+// memFileInfo describes a memFile.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
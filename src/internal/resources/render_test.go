@@ -0,0 +1,136 @@
+package resources
+
+import (
+	"io/fs"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+)
+
+var _ = Describe("Render", func() {
+	It("should leave the embedded resources byte-identical with the default settings", func() {
+		rendered, err := Render(embedded.Resources, TemplateData{})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range RefreshedFiles {
+			raw, err := fs.ReadFile(embedded.Resources, "resources/"+name)
+			Expect(err).NotTo(HaveOccurred())
+			got, err := fs.ReadFile(rendered, "resources/"+name)
+			Expect(err).NotTo(HaveOccurred())
+			if name == "pattern.sh" {
+				Expect(string(got)).To(ContainSubstring(`PATTERN_UTILITY_CONTAINER="` + DefaultUtilityContainer + `"`))
+				Expect(string(got)).NotTo(ContainSubstring("{{"))
+				continue
+			}
+			Expect(got).To(Equal(raw), "%s should not change", name)
+		}
+	})
+
+	It("should render the pattern settings into pattern.sh and the Makefile", func() {
+		rendered, err := Render(embedded.Resources, TemplateData{
+			PatternName:      "multicloud-gitops",
+			UtilityContainer: "registry.example.com/mirror/utility-container:v1",
+			DisconnectedHome: "registry.example.com/mirror",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		script, err := fs.ReadFile(rendered, "resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(script)).To(ContainSubstring(`PATTERN_UTILITY_CONTAINER="registry.example.com/mirror/utility-container:v1"`))
+		Expect(string(script)).To(ContainSubstring("fi\nif [ -z \"${PATTERN_DISCONNECTED_HOME:-}\" ]; then\n    PATTERN_DISCONNECTED_HOME=\"registry.example.com/mirror\"\nfi\n# If PATTERN_DISCONNECTED_HOME"))
+
+		makefile, err := fs.ReadFile(rendered, "resources/Makefile")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(makefile)).To(HavePrefix("# Generated by patternizer for the multicloud-gitops pattern\n"))
+	})
+
+	Context("when selecting the container runtime", func() {
+		renderScript := func(runtime string) string {
+			rendered, err := Render(embedded.Resources, TemplateData{ContainerRuntime: runtime})
+			Expect(err).NotTo(HaveOccurred())
+			script, err := fs.ReadFile(rendered, "resources/pattern.sh")
+			Expect(err).NotTo(HaveOccurred())
+			return string(script)
+		}
+
+		It("should require podman by default", func() {
+			script := renderScript("podman")
+			Expect(script).To(Equal(renderScript("")))
+			Expect(script).To(ContainSubstring("\nCONTAINER_RUNTIME=podman\nis_available \"${CONTAINER_RUNTIME}\"\n"))
+			Expect(script).NotTo(ContainSubstring("PATTERN_CONTAINER_RUNTIME"))
+			Expect(script).To(ContainSubstring(`--userns "keep-id:uid=${MYUID},gid=${MYGID}"`))
+			Expect(script).To(ContainSubstring("PULL_POLICY=newer"))
+		})
+
+		It("should generate a docker script", func() {
+			script := renderScript("docker")
+			Expect(script).To(ContainSubstring("\nCONTAINER_RUNTIME=docker\nis_available \"${CONTAINER_RUNTIME}\"\n"))
+			Expect(script).NotTo(ContainSubstring("PATTERN_CONTAINER_RUNTIME"))
+			Expect(script).To(ContainSubstring(`RUNTIME_ARGS=(--user "${MYUID}:${MYGID}" -e HOME=/pattern-home)`))
+			Expect(script).To(ContainSubstring("for var in $(compgen -e); do"))
+			Expect(script).To(ContainSubstring("PULL_POLICY=always"))
+			Expect(script).To(ContainSubstring("\n\"${CONTAINER_RUNTIME}\" run -it --rm --pull=\"${PULL_POLICY}\" \\\n"))
+			Expect(script).To(HaveSuffix("    \"$@\"\n"))
+		})
+
+		It("should generate a script that detects the runtime", func() {
+			script := renderScript("auto")
+			Expect(script).To(ContainSubstring(`CONTAINER_RUNTIME="${PATTERN_CONTAINER_RUNTIME:-}"`))
+			Expect(script).To(ContainSubstring("if command -v podman >/dev/null 2>&1; then\n        CONTAINER_RUNTIME=podman\n    elif command -v docker"))
+			Expect(script).To(ContainSubstring("if [ \"${CONTAINER_RUNTIME}\" = \"podman\" ]; then"))
+		})
+
+		It("should run the container once, with the passthrough variables listed once", func() {
+			for _, runtime := range ContainerRuntimes {
+				script := renderScript(runtime)
+				Expect(strings.Count(script, " run -it --rm ")).To(Equal(1), "%s script", runtime)
+				for _, name := range EnvPassthrough {
+					Expect(strings.Count(script, "\n    '"+name+"'\n")).To(Equal(1), "%s script should list %s", runtime, name)
+				}
+			}
+		})
+
+		It("should reject unknown runtimes", func() {
+			_, err := Render(embedded.Resources, TemplateData{ContainerRuntime: "lxc"})
+			Expect(err).To(MatchError(ContainSubstring("unsupported container runtime")))
+		})
+	})
+
+	It("should serve files that are not resources from the underlying set", func() {
+		set := fstest.MapFS{
+			"resources/pattern.sh": &fstest.MapFile{Data: []byte("{{ .PatternName }}"), Mode: 0o755},
+			"other/file.txt":       &fstest.MapFile{Data: []byte("{{ untouched }}")},
+		}
+		rendered, err := Render(set, TemplateData{PatternName: "demo"})
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := fs.ReadFile(rendered, "resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("demo"))
+
+		info, err := fs.Stat(rendered, "resources/pattern.sh")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(4)))
+		Expect(info.Mode()).To(Equal(fs.FileMode(0o755)))
+
+		data, err = fs.ReadFile(rendered, "other/file.txt")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("{{ untouched }}"))
+
+		entries, err := fs.ReadDir(rendered, "resources")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should report templates that reference unknown fields", func() {
+		set := fstest.MapFS{
+			"resources/pattern.sh": &fstest.MapFile{Data: []byte("podman inspect --format '{{.Id}}'")},
+		}
+		_, err := Render(set, TemplateData{})
+		Expect(err).To(MatchError(ContainSubstring("pattern.sh")))
+	})
+})
@@ -0,0 +1,5 @@
+package resources
+
+// RefreshedFiles are the resource files that upgrade always overwrites with
+// the selected resource set.
+var RefreshedFiles = []string{"pattern.sh", "Makefile-common", "ansible.cfg"}
@@ -0,0 +1,389 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/validatedpatterns/patternizer/internal/resources"
+)
+
+// PatternHome is where the user's home directory is mounted in the container.
+const PatternHome = "/pattern-home"
+
+// unsupportedPodmanVersions are podman releases known not to work with the utility container.
+var unsupportedPodmanVersions = []string{"1.6", "1.5"}
+
+// minKeepIDPodmanVersion is the first podman release that supports --userns keep-id:uid=...,gid=...
+const minKeepIDPodmanVersion = "v4.3.0"
+
+// pkiDirs are the host certificate directories checked, in order, for bind
+// mounting into the container. /etc/pki/tls is checked instead of /etc/pki
+// because on Ubuntu /etc/pki/fwupd sometimes exists without any certificates.
+var pkiDirs = []struct{ probe, mount string }{
+	{"/etc/pki/tls", "/etc/pki"},
+	{"/etc/ssl", "/etc/ssl"},
+	{"/usr/share/ca-certificates", "/usr/share/ca-certificates"},
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// Host abstracts the environment the container invocation is built from, so
+// that the builder can be tested without a container runtime.
+type Host interface {
+	// Getenv returns the value of an environment variable.
+	Getenv(key string) string
+	// Environ returns the environment as KEY=value strings.
+	Environ() []string
+	// LookPath searches for an executable in PATH.
+	LookPath(file string) (string, error)
+	// Output runs a command and returns its standard output.
+	Output(name string, args ...string) (string, error)
+	// IsDir reports whether path is an existing directory.
+	IsDir(path string) bool
+	// InContainer reports whether patternizer itself runs in a container.
+	InContainer() bool
+	// IsTerminal reports whether standard input is a terminal.
+	IsTerminal() bool
+	// User returns the name, uid and gid of the current user.
+	User() (name, uid, gid string, err error)
+	// Getwd returns the physical working directory.
+	Getwd() (string, error)
+	// HomeDir returns the home directory of the current user.
+	HomeDir() (string, error)
+}
+
+// Options configures the container invocation.
+type Options struct {
+	// Runtime is one of resources.ContainerRuntimes; empty means
+	// resources.DefaultContainerRuntime.
+	Runtime string
+	// Image is the default utility container image. PATTERN_UTILITY_CONTAINER
+	// takes precedence, and an empty Image means
+	// resources.DefaultUtilityContainer.
+	Image string
+	// DisconnectedHome is the default PATTERN_DISCONNECTED_HOME.
+	DisconnectedHome string
+}
+
+// Invocation is a fully resolved command line.
+type Invocation struct {
+	// Path is the executable to run.
+	Path string
+	// Args are the arguments, not including the executable name.
+	Args []string
+	// Env holds additional KEY=value pairs for the process environment.
+	Env []string
+	// Notes are informational messages to show before running.
+	Notes []string
+}
+
+// Build resolves the command line that runs args in the utility container,
+// mirroring what pattern.sh does. When patternizer already runs in a
+// container, args are run directly.
+func Build(host Host, opts Options, args []string) (*Invocation, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command to run")
+	}
+	if opts.Runtime != "" && !slices.Contains(resources.ContainerRuntimes, opts.Runtime) {
+		return nil, fmt.Errorf("unsupported container runtime %q (expected one of: %s)", opts.Runtime, strings.Join(resources.ContainerRuntimes, ", "))
+	}
+
+	if host.InContainer() {
+		path, err := host.LookPath(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s is required but it's not installed: %w", args[0], err)
+		}
+		return &Invocation{Path: path, Args: args[1:], Notes: []string{"Already running in a container"}}, nil
+	}
+
+	inv := &Invocation{}
+
+	image := host.Getenv("PATTERN_UTILITY_CONTAINER")
+	if image == "" {
+		image = opts.Image
+	}
+	if image == "" {
+		image = resources.DefaultUtilityContainer
+	}
+	disconnectedHome := host.Getenv("PATTERN_DISCONNECTED_HOME")
+	if disconnectedHome == "" && opts.DisconnectedHome != "" {
+		disconnectedHome = opts.DisconnectedHome
+		inv.Env = append(inv.Env, "PATTERN_DISCONNECTED_HOME="+disconnectedHome)
+	}
+	if disconnectedHome != "" {
+		image = disconnectedHome + "/utility-container"
+		installChart := "oci://" + disconnectedHome + "/pattern-install"
+		inv.Env = append(inv.Env, "PATTERN_INSTALL_CHART="+installChart)
+		inv.Notes = append(inv.Notes,
+			"PATTERN_DISCONNECTED_HOME is set to "+disconnectedHome,
+			"Setting the following variables:",
+			"  PATTERN_UTILITY_CONTAINER: "+image,
+			"  PATTERN_INSTALL_CHART: "+installChart)
+	}
+
+	runtime, path, err := findRuntime(host, opts.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	inv.Path = path
+
+	home, err := host.HomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	wd, err := host.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current directory: %w", err)
+	}
+	if outside := KubeconfigsOutsideHome(host.Getenv("KUBECONFIG"), home, wd); len(outside) > 0 {
+		return nil, fmt.Errorf("%s is pointing outside of the HOME folder, this will make it unavailable from the container; "+
+			"please move it somewhere inside your %s folder, as that is what gets bind-mounted inside the container", strings.Join(outside, ", "), home)
+	}
+
+	userArgs, err := userArgs(host, runtime, home)
+	if err != nil {
+		return nil, err
+	}
+
+	runArgs := []string{"run", "-i"}
+	if host.IsTerminal() {
+		runArgs = append(runArgs, "-t")
+	}
+	runArgs = append(runArgs, "--rm", pullArg(runtime), "--security-opt", "label=disable")
+	for _, name := range passthroughNames(runtime, host.Environ()) {
+		runArgs = append(runArgs, "-e", name)
+	}
+	runArgs = append(runArgs, pkiMountArgs(host, runtime)...)
+	runArgs = append(runArgs,
+		"-v", wd+":"+wd,
+		"-v", home+":"+home,
+		"-v", home+":"+PatternHome)
+	runArgs = append(runArgs, userArgs...)
+	runArgs = append(runArgs, strings.Fields(host.Getenv("EXTRA_ARGS"))...)
+	runArgs = append(runArgs, "-w", wd, image)
+	inv.Args = append(runArgs, args...)
+
+	return inv, nil
+}
+
+// findRuntime returns the runtime to use and the path of its executable.
+func findRuntime(host Host, runtime string) (string, string, error) {
+	if runtime == "" {
+		runtime = resources.DefaultContainerRuntime
+	}
+	candidates := []string{runtime}
+	if runtime == resources.RuntimeAuto {
+		candidates = []string{resources.RuntimePodman, resources.RuntimeDocker}
+	}
+
+	for _, candidate := range candidates {
+		if path, err := host.LookPath(candidate); err == nil {
+			return candidate, path, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s is required but it's not installed", strings.Join(candidates, " or "))
+}
+
+// userArgs maps the host user into the container so that files written to
+// the mounted directories keep their ownership.
+func userArgs(host Host, runtime, home string) ([]string, error) {
+	name, uid, gid, err := host.User()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current user: %w", err)
+	}
+
+	if runtime == resources.RuntimeDocker {
+		// Docker has no keep-id user namespace; running as the host user with
+		// HOME pointing at the mounted home directory is the closest match.
+		return []string{"--user", uid + ":" + gid, "-e", "HOME=" + PatternHome}, nil
+	}
+
+	out, err := host.Output(resources.RuntimePodman, "--version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get podman version: %w", err)
+	}
+	// podman --version outputs "podman version 4.8.2".
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("failed to parse podman version %q", out)
+	}
+	podmanVersion := versionPattern.FindString(fields[len(fields)-1])
+	for _, unsupported := range unsupportedPodmanVersions {
+		if podmanVersion == unsupported || strings.HasPrefix(podmanVersion, unsupported+".") {
+			return nil, fmt.Errorf("unsupported podman version %s; we recommend > 4.3.0", podmanVersion)
+		}
+	}
+
+	if semver.Compare("v"+podmanVersion, minKeepIDPodmanVersion) < 0 {
+		return []string{"-v", home + ":/root"}, nil
+	}
+	return []string{
+		"--passwd-entry", fmt.Sprintf("%s:x:%s:%s::%s:/bin/bash", name, uid, gid, PatternHome),
+		"--user", uid + ":" + gid,
+		"--userns", fmt.Sprintf("keep-id:uid=%s,gid=%s", uid, gid),
+	}, nil
+}
+
+// pullArg returns the flag that pulls the image only when a newer one exists.
+func pullArg(runtime string) string {
+	if runtime == resources.RuntimeDocker {
+		// Docker has no "newer" policy; "always" still only downloads changed layers.
+		return "--pull=always"
+	}
+	return "--pull=newer"
+}
+
+// passthroughNames expands resources.EnvPassthrough for runtime. Podman understands
+// prefix wildcards itself, while docker needs every matching variable listed.
+func passthroughNames(runtime string, environ []string) []string {
+	var names []string
+	for _, name := range resources.EnvPassthrough {
+		prefix, ok := strings.CutSuffix(name, "*")
+		if !ok || runtime != resources.RuntimeDocker {
+			names = append(names, name)
+			continue
+		}
+		var matches []string
+		for _, kv := range environ {
+			key, _, _ := strings.Cut(kv, "=")
+			if strings.HasPrefix(key, prefix) {
+				matches = append(matches, key)
+			}
+		}
+		sort.Strings(matches)
+		names = append(names, matches...)
+	}
+	return names
+}
+
+// pkiMountArgs bind mounts the host certificates when the runtime runs
+// containers on this host. Remote runtimes, such as podman machine, cannot
+// see the host directories.
+func pkiMountArgs(host Host, runtime string) []string {
+	if isRemote(host, runtime) {
+		return nil
+	}
+	for _, dir := range pkiDirs[:len(pkiDirs)-1] {
+		if host.IsDir(dir.probe) {
+			return []string{"-v", dir.mount + ":" + dir.mount + ":ro"}
+		}
+	}
+	last := pkiDirs[len(pkiDirs)-1]
+	return []string{"-v", last.mount + ":" + last.mount + ":ro"}
+}
+
+// isRemote reports whether the runtime talks to a remote machine.
+func isRemote(host Host, runtime string) bool {
+	if runtime == resources.RuntimeDocker {
+		dockerHost := host.Getenv("DOCKER_HOST")
+		return dockerHost != "" && !strings.HasPrefix(dockerHost, "unix://")
+	}
+	out, err := host.Output(resources.RuntimePodman, "system", "connection", "list")
+	if err != nil {
+		return false
+	}
+	// The first line is the table header.
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return len(lines) > 1
+}
+
+// OSHost is the Host backed by the real operating system.
+type OSHost struct{}
+
+// KubeconfigsOutsideHome returns the files listed in kubeconfig, a KUBECONFIG
+// value, that do not lie under home, the only directory that is mounted into
+// the container. Relative entries are resolved against wd.
+func KubeconfigsOutsideHome(kubeconfig, home, wd string) []string {
+	var outside []string
+	for _, file := range filepath.SplitList(kubeconfig) {
+		if file == "" {
+			continue
+		}
+		abs := file
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(wd, abs)
+		}
+		rel, err := filepath.Rel(home, abs)
+		if home == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			outside = append(outside, file)
+		}
+	}
+	return outside
+}
+
+// Getenv implements Host.
+func (OSHost) Getenv(key string) string { return os.Getenv(key) }
+
+// Environ implements Host.
+func (OSHost) Environ() []string { return os.Environ() }
+
+// LookPath implements Host.
+func (OSHost) LookPath(file string) (string, error) { return exec.LookPath(file) }
+
+// Output implements Host.
+func (OSHost) Output(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return string(out), err
+}
+
+// IsDir implements Host.
+func (OSHost) IsDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// InContainer implements Host. It matches the checks of pattern.sh, which
+// mostly matter for CI, where containers should not be nested.
+func (OSHost) InContainer() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal implements Host.
+func (OSHost) IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// User implements Host.
+func (OSHost) User() (name, uid, gid string, err error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", "", "", err
+	}
+	return u.Username, u.Uid, u.Gid, nil
+}
+
+// Getwd implements Host. Symlinks are resolved like pwd -P.
+func (OSHost) Getwd() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(wd)
+}
+
+// HomeDir implements Host.
+func (OSHost) HomeDir() (string, error) {
+	if home := os.Getenv("HOME"); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
@@ -0,0 +1,241 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/validatedpatterns/patternizer/internal/resources"
+)
+
+// fakeHost is a Host with canned answers.
+type fakeHost struct {
+	env         map[string]string
+	executables map[string]string
+	outputs     map[string]string
+	dirs        map[string]bool
+	inContainer bool
+	terminal    bool
+}
+
+func newFakeHost() *fakeHost {
+	return &fakeHost{
+		env:         map[string]string{},
+		executables: map[string]string{"podman": "/usr/bin/podman"},
+		outputs: map[string]string{
+			"podman --version":              "podman version 4.8.2\n",
+			"podman system connection list": "Name  URI  Identity  Default\n",
+		},
+		dirs: map[string]bool{"/etc/pki/tls": true},
+	}
+}
+
+func (h *fakeHost) Getenv(key string) string { return h.env[key] }
+
+func (h *fakeHost) Environ() []string {
+	var environ []string
+	for k, v := range h.env {
+		environ = append(environ, k+"="+v)
+	}
+	return environ
+}
+
+func (h *fakeHost) LookPath(file string) (string, error) {
+	if path, ok := h.executables[file]; ok {
+		return path, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (h *fakeHost) Output(name string, args ...string) (string, error) {
+	if out, ok := h.outputs[strings.Join(append([]string{name}, args...), " ")]; ok {
+		return out, nil
+	}
+	return "", errors.New("command failed")
+}
+
+func (h *fakeHost) IsDir(path string) bool { return h.dirs[path] }
+
+func (h *fakeHost) InContainer() bool { return h.inContainer }
+
+func (h *fakeHost) IsTerminal() bool { return h.terminal }
+
+func (h *fakeHost) User() (string, string, string, error) { return "alice", "1000", "1000", nil }
+
+func (h *fakeHost) Getwd() (string, error) { return "/home/alice/pattern", nil }
+
+func (h *fakeHost) HomeDir() (string, error) { return "/home/alice", nil }
+
+var _ = Describe("Build", func() {
+	var host *fakeHost
+
+	BeforeEach(func() {
+		host = newFakeHost()
+	})
+
+	It("should build the same podman invocation as pattern.sh", func() {
+		host.terminal = true
+		inv, err := Build(host, Options{}, []string{"make", "install"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Path).To(Equal("/usr/bin/podman"))
+
+		expected := []string{"run", "-i", "-t", "--rm", "--pull=newer", "--security-opt", "label=disable"}
+		for _, name := range resources.EnvPassthrough {
+			expected = append(expected, "-e", name)
+		}
+		expected = append(expected,
+			"-v", "/etc/pki:/etc/pki:ro",
+			"-v", "/home/alice/pattern:/home/alice/pattern",
+			"-v", "/home/alice:/home/alice",
+			"-v", "/home/alice:/pattern-home",
+			"--passwd-entry", "alice:x:1000:1000::/pattern-home:/bin/bash",
+			"--user", "1000:1000",
+			"--userns", "keep-id:uid=1000,gid=1000",
+			"-w", "/home/alice/pattern",
+			resources.DefaultUtilityContainer,
+			"make", "install")
+		Expect(inv.Args).To(Equal(expected))
+		Expect(inv.Env).To(BeEmpty())
+	})
+
+	It("should mount the home directory as /root for podman older than 4.3.0", func() {
+		host.outputs["podman --version"] = "podman version 4.2.1\n"
+		inv, err := Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Join(inv.Args, " ")).To(ContainSubstring("-v /home/alice:/root -w"))
+		Expect(inv.Args).NotTo(ContainElement("--userns"))
+	})
+
+	It("should refuse unsupported podman versions", func() {
+		host.outputs["podman --version"] = "podman version 1.6.4\n"
+		_, err := Build(host, Options{}, []string{"make"})
+		Expect(err).To(MatchError(ContainSubstring("unsupported podman version 1.6.4")))
+	})
+
+	It("should skip the certificate mounts when podman uses a remote machine", func() {
+		host.outputs["podman system connection list"] = "Name  URI  Identity  Default\npodman-machine-default  ssh://core@127.0.0.1  key  true\n"
+		inv, err := Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Join(inv.Args, " ")).NotTo(ContainSubstring(":ro"))
+	})
+
+	It("should fall back to the next certificate directory", func() {
+		host.dirs = map[string]bool{"/etc/ssl": true}
+		inv, err := Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Join(inv.Args, " ")).To(ContainSubstring("-v /etc/ssl:/etc/ssl:ro"))
+
+		host.dirs = map[string]bool{}
+		inv, err = Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Join(inv.Args, " ")).To(ContainSubstring("-v /usr/share/ca-certificates:/usr/share/ca-certificates:ro"))
+	})
+
+	It("should require podman by default", func() {
+		host.executables = map[string]string{"docker": "/usr/local/bin/docker"}
+		_, err := Build(host, Options{}, []string{"make"})
+		Expect(err).To(MatchError("podman is required but it's not installed"))
+	})
+
+	It("should fall back to docker when podman is not installed", func() {
+		host.executables = map[string]string{"docker": "/usr/local/bin/docker"}
+		host.env["VP_B"] = "b"
+		host.env["VP_A"] = "a"
+		inv, err := Build(host, Options{Runtime: resources.RuntimeAuto}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Path).To(Equal("/usr/local/bin/docker"))
+
+		args := strings.Join(inv.Args, " ")
+		Expect(args).To(ContainSubstring("--pull=always"))
+		Expect(args).To(ContainSubstring("-e VALUES_SECRET -e VP_A -e VP_B -v"))
+		Expect(args).NotTo(ContainSubstring("VP_*"))
+		Expect(args).To(ContainSubstring("--user 1000:1000 -e HOME=/pattern-home"))
+		Expect(args).NotTo(ContainSubstring("--userns"))
+	})
+
+	It("should honour an explicit runtime", func() {
+		host.executables["docker"] = "/usr/bin/docker"
+		inv, err := Build(host, Options{Runtime: resources.RuntimeDocker}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Path).To(Equal("/usr/bin/docker"))
+
+		_, err = Build(host, Options{Runtime: "lxc"}, []string{"make"})
+		Expect(err).To(MatchError(ContainSubstring("unsupported container runtime")))
+
+		host.executables = map[string]string{}
+		_, err = Build(host, Options{Runtime: resources.RuntimePodman}, []string{"make"})
+		Expect(err).To(MatchError("podman is required but it's not installed"))
+	})
+
+	It("should select the image from the environment, then the options", func() {
+		inv, err := Build(host, Options{Image: "mirror.example.com/utility"}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Args).To(ContainElement("mirror.example.com/utility"))
+
+		host.env["PATTERN_UTILITY_CONTAINER"] = "env.example.com/utility"
+		inv, err = Build(host, Options{Image: "mirror.example.com/utility"}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Args).To(ContainElement("env.example.com/utility"))
+	})
+
+	It("should derive the image and install chart from the disconnected home", func() {
+		inv, err := Build(host, Options{DisconnectedHome: "mirror.example.com/vp"}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Args).To(ContainElement("mirror.example.com/vp/utility-container"))
+		Expect(inv.Env).To(ConsistOf(
+			"PATTERN_DISCONNECTED_HOME=mirror.example.com/vp",
+			"PATTERN_INSTALL_CHART=oci://mirror.example.com/vp/pattern-install"))
+		Expect(inv.Notes).To(ContainElement("PATTERN_DISCONNECTED_HOME is set to mirror.example.com/vp"))
+	})
+
+	It("should refuse a KUBECONFIG outside of the home directory", func() {
+		host.env["KUBECONFIG"] = "/tmp/kubeconfig"
+		_, err := Build(host, Options{}, []string{"make"})
+		Expect(err).To(MatchError(ContainSubstring("/tmp/kubeconfig is pointing outside of the HOME folder")))
+	})
+
+	It("should check every file of a KUBECONFIG list by path", func() {
+		host.env["KUBECONFIG"] = "/home/alice/.kube/config:/home/alice2/kubeconfig"
+		_, err := Build(host, Options{}, []string{"make"})
+		Expect(err).To(MatchError(HavePrefix("/home/alice2/kubeconfig is pointing outside of the HOME folder")))
+
+		host.env["KUBECONFIG"] = "/home/alice/.kube/config:kubeconfig"
+		_, err = Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should append EXTRA_ARGS before the working directory", func() {
+		host.env["EXTRA_ARGS"] = "--network host  -v /data:/data"
+		inv, err := Build(host, Options{}, []string{"make"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Join(inv.Args, " ")).To(ContainSubstring("--userns keep-id:uid=1000,gid=1000 --network host -v /data:/data -w /home/alice/pattern"))
+	})
+
+	It("should run the command directly inside a container", func() {
+		host.inContainer = true
+		host.executables["make"] = "/usr/bin/make"
+		inv, err := Build(host, Options{}, []string{"make", "install"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inv.Path).To(Equal("/usr/bin/make"))
+		Expect(inv.Args).To(Equal([]string{"install"}))
+		Expect(inv.Notes).To(ConsistOf("Already running in a container"))
+	})
+
+	It("should require a command", func() {
+		_, err := Build(host, Options{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = DescribeTable("KubeconfigsOutsideHome",
+	func(kubeconfig string, expected []string) {
+		Expect(KubeconfigsOutsideHome(kubeconfig, "/home/alice", "/home/alice/pattern")).To(Equal(expected))
+	},
+	Entry("a file under home", "/home/alice/.kube/config", nil),
+	Entry("a relative file", "kubeconfig", nil),
+	Entry("a sibling with the same prefix", "/home/alice2/config", []string{"/home/alice2/config"}),
+	Entry("a relative file leaving home", "../../config", []string{"../../config"}),
+	Entry("a list", "/home/alice/a::/etc/b", []string{"/etc/b"}),
+)
@@ -0,0 +1,119 @@
+package skillbundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/validatedpatterns/patternizer/internal/report"
+)
+
+// InstalledSkill is the manifest of a skill that patternizer installed. It
+// records what was written so that later runs can tell whether the skill is
+// outdated or locally modified, and which files to prune or remove.
+type InstalledSkill struct {
+	// Version is the version in the SKILL.md front matter, if any.
+	Version string `yaml:"version,omitempty"`
+	// Source is the configured skill source the skill came from, or empty
+	// for an embedded skill.
+	Source string `yaml:"source,omitempty"`
+	// SHA256 is the digest of the whole skill directory.
+	SHA256  string   `yaml:"sha256"`
+	Targets []string `yaml:"targets,flow"`
+	// Files maps the slash-separated paths of the files, relative to the
+	// skill directory, to their SHA-256.
+	Files map[string]string `yaml:"files"`
+}
+
+// DisplayVersion returns the version of the skill or, if the skill has none,
+// an abbreviated digest.
+func (s InstalledSkill) DisplayVersion() string {
+	if s.Version != "" {
+		return s.Version
+	}
+	if len(s.SHA256) > 12 {
+		return "sha256:" + s.SHA256[:12]
+	}
+	return "unknown"
+}
+
+// DirDigest computes a stable SHA-256 over the relative paths and contents of
+// every file under dir.
+func DirDigest(fsys fs.FS, dir string) (string, error) {
+	files := make(map[string]string)
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimPrefix(p, dir+"/")] = report.HashBytes(data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", dir, err)
+	}
+
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		fmt.Fprintf(h, "%s\x00%s\n", name, files[name])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Version returns the value of the top-level version key in the SKILL.md
+// front matter of skillDir, or an empty string.
+func Version(fsys fs.FS, skillDir string) string {
+	data, err := fs.ReadFile(fsys, path.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		return ""
+	}
+	frontMatter, err := FrontMatter(data)
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(frontMatter, &fields); err != nil {
+		return ""
+	}
+	return fields.Version
+}
+
+// FrontMatter returns the YAML front matter of a SKILL.md: the lines between
+// the leading --- line and the next one. A byte order mark and CRLF line
+// endings are accepted.
+func FrontMatter(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	lines := strings.SplitAfter(string(data), "\n")
+	if !isFrontMatterDelimiter(lines[0]) {
+		return nil, errors.New("missing front matter; the file must start with ---")
+	}
+	for i, line := range lines[1:] {
+		if isFrontMatterDelimiter(line) {
+			return []byte(strings.Join(lines[1:i+1], "")), nil
+		}
+	}
+	return nil, errors.New("unterminated front matter")
+}
+
+// isFrontMatterDelimiter reports whether line, including its line ending, is
+// a --- line.
+func isFrontMatterDelimiter(line string) bool {
+	return strings.TrimRight(line, " \t\r\n") == "---"
+}
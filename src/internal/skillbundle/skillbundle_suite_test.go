@@ -0,0 +1,13 @@
+package skillbundle
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSkillBundle(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SkillBundle Suite")
+}
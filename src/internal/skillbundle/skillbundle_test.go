@@ -0,0 +1,69 @@
+package skillbundle
+
+import (
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DirDigest", func() {
+	fsys := fstest.MapFS{
+		"skills/one/SKILL.md":     &fstest.MapFile{Data: []byte("---\nname: one\nversion: \"1.2.0\"\n---\n# One\n")},
+		"skills/one/reference.md": &fstest.MapFile{Data: []byte("ref")},
+	}
+
+	It("should change the digest when any file in the skill changes", func() {
+		before, err := DirDigest(fsys, "skills/one")
+		Expect(err).NotTo(HaveOccurred())
+
+		changed := fstest.MapFS{
+			"skills/one/SKILL.md":     fsys["skills/one/SKILL.md"],
+			"skills/one/reference.md": &fstest.MapFile{Data: []byte("ref v2")},
+		}
+		after, err := DirDigest(changed, "skills/one")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).NotTo(Equal(before))
+	})
+
+	It("should change the digest when a file is renamed", func() {
+		before, err := DirDigest(fsys, "skills/one")
+		Expect(err).NotTo(HaveOccurred())
+
+		renamed := fstest.MapFS{
+			"skills/one/SKILL.md": fsys["skills/one/SKILL.md"],
+			"skills/one/notes.md": fsys["skills/one/reference.md"],
+		}
+		after, err := DirDigest(renamed, "skills/one")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).NotTo(Equal(before))
+	})
+})
+
+var _ = DescribeTable("FrontMatter",
+	func(data, expected, expectedErr string) {
+		frontMatter, err := FrontMatter([]byte(data))
+		if expectedErr != "" {
+			Expect(err).To(MatchError(expectedErr))
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(frontMatter)).To(Equal(expected))
+	},
+	Entry("LF line endings", "---\nname: one\n---\n# One\n", "name: one\n", ""),
+	Entry("CRLF line endings and a byte order mark", "\ufeff---\r\nname: one\r\n---\r\n# One\r\n", "name: one\r\n", ""),
+	Entry("no front matter", "# One\n", "", "missing front matter; the file must start with ---"),
+	Entry("unterminated front matter", "---\nname: one\n", "", "unterminated front matter"),
+)
+
+var _ = Describe("Version", func() {
+	It("should return the version of the front matter, or an empty string", func() {
+		fsys := fstest.MapFS{
+			"one/SKILL.md": &fstest.MapFile{Data: []byte("---\nname: one\nversion: \"1.2.0\"\n---\n")},
+			"two/SKILL.md": &fstest.MapFile{Data: []byte("# Two\n")},
+		}
+		Expect(Version(fsys, "one")).To(Equal("1.2.0"))
+		Expect(Version(fsys, "two")).To(BeEmpty())
+		Expect(Version(fsys, "three")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,121 @@
+package values
+
+import (
+	"strings"
+)
+
+// Values is a tree of Helm values that records which source set each leaf.
+type Values struct {
+	// Tree holds the merged values.
+	Tree map[string]interface{}
+	// Sources maps the path of every leaf to the source that set it. Paths
+	// use Helm's --set syntax: keys are joined with dots, and dots within a
+	// key are escaped with a backslash.
+	Sources map[string]string
+}
+
+// SplitPath splits a path in Helm's --set syntax into its keys.
+func SplitPath(path string) []string {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	return append(keys, key.String())
+}
+
+// joinPath appends key to a path in Helm's --set syntax.
+func joinPath(prefix, key string) string {
+	key = strings.ReplaceAll(key, ".", `\.`)
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// New returns empty values.
+func New() *Values {
+	return &Values{Tree: map[string]interface{}{}, Sources: map[string]string{}}
+}
+
+// Merge deep-merges overlay, read from source, into v the way Helm merges
+// value files: maps are merged key by key, any other value (including lists)
+// replaces the current one, and null removes the key.
+func (v *Values) Merge(overlay map[string]interface{}, source string) {
+	v.merge(v.Tree, overlay, "", source)
+}
+
+// Set sets the value at a path, creating intermediate maps, the way Helm
+// applies --set parameters.
+func (v *Values) Set(path, value, source string) {
+	keys := SplitPath(path)
+	overlay := map[string]interface{}{keys[len(keys)-1]: value}
+	for i := len(keys) - 2; i >= 0; i-- {
+		overlay = map[string]interface{}{keys[i]: overlay}
+	}
+	v.Merge(overlay, source)
+}
+
+// Lookup returns the value at the given keys, or nil.
+func (v *Values) Lookup(keys ...string) interface{} {
+	var current interface{} = v.Tree
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func (v *Values) merge(dst, src map[string]interface{}, prefix, source string) {
+	for key, value := range src {
+		path := joinPath(prefix, key)
+		if value == nil {
+			delete(dst, key)
+			v.clearSources(path)
+			continue
+		}
+
+		overlay, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			v.clearSources(path)
+			v.Sources[path] = source
+			continue
+		}
+
+		current, ok := dst[key].(map[string]interface{})
+		if !ok {
+			current = map[string]interface{}{}
+			dst[key] = current
+			v.clearSources(path)
+		}
+		if len(overlay) == 0 && len(current) == 0 {
+			v.Sources[path] = source
+			continue
+		}
+		delete(v.Sources, path)
+		v.merge(current, overlay, path, source)
+	}
+}
+
+// clearSources forgets the sources of path and everything below it.
+func (v *Values) clearSources(path string) {
+	delete(v.Sources, path)
+	for p := range v.Sources {
+		if strings.HasPrefix(p, path+".") {
+			delete(v.Sources, p)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package values
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Values", func() {
+	It("should deep-merge maps and replace other values", func() {
+		v := New()
+		v.Merge(map[string]interface{}{
+			"image":    map[string]interface{}{"repository": "app", "tag": "v1"},
+			"replicas": 1,
+			"args":     []interface{}{"a", "b"},
+		}, "first.yaml")
+		v.Merge(map[string]interface{}{
+			"image": map[string]interface{}{"tag": "v2"},
+			"args":  []interface{}{"c"},
+		}, "second.yaml")
+
+		Expect(v.Tree).To(Equal(map[string]interface{}{
+			"image":    map[string]interface{}{"repository": "app", "tag": "v2"},
+			"replicas": 1,
+			"args":     []interface{}{"c"},
+		}))
+		Expect(v.Sources).To(Equal(map[string]string{
+			"image.repository": "first.yaml",
+			"image.tag":        "second.yaml",
+			"replicas":         "first.yaml",
+			"args":             "second.yaml",
+		}))
+	})
+
+	It("should delete keys set to null", func() {
+		v := New()
+		v.Merge(map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}, "keep": true}, "first.yaml")
+		v.Merge(map[string]interface{}{"image": nil}, "second.yaml")
+
+		Expect(v.Tree).To(Equal(map[string]interface{}{"keep": true}))
+		Expect(v.Sources).To(Equal(map[string]string{"keep": "first.yaml"}))
+	})
+
+	It("should replace maps by scalars and scalars by maps", func() {
+		v := New()
+		v.Merge(map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": "x"}, "first.yaml")
+		v.Merge(map[string]interface{}{"a": "flat", "c": map[string]interface{}{"d": 2}}, "second.yaml")
+
+		Expect(v.Tree).To(Equal(map[string]interface{}{"a": "flat", "c": map[string]interface{}{"d": 2}}))
+		Expect(v.Sources).To(Equal(map[string]string{"a": "second.yaml", "c.d": "second.yaml"}))
+	})
+
+	It("should escape dots within keys", func() {
+		v := New()
+		v.Merge(map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "app"}}, "values.yaml")
+		Expect(v.Sources).To(HaveKeyWithValue(`labels.app\.kubernetes\.io/name`, "values.yaml"))
+		Expect(v.Lookup(SplitPath(`labels.app\.kubernetes\.io/name`)...)).To(Equal("app"))
+
+		v.Set(`labels.app\.kubernetes\.io/name`, "other", "overrides")
+		Expect(v.Lookup("labels", "app.kubernetes.io/name")).To(Equal("other"))
+		Expect(v.Sources).To(HaveKeyWithValue(`labels.app\.kubernetes\.io/name`, "overrides"))
+	})
+
+	It("should set values at a path", func() {
+		v := New()
+		v.Merge(map[string]interface{}{"global": map[string]interface{}{"pattern": "demo"}}, "values-global.yaml")
+		v.Set("global.clusterPlatform", "AWS", "parameters")
+
+		Expect(v.Lookup("global", "clusterPlatform")).To(Equal("AWS"))
+		Expect(v.Lookup("global", "pattern")).To(Equal("demo"))
+		Expect(v.Sources).To(HaveKeyWithValue("global.clusterPlatform", "parameters"))
+	})
+})
@@ -0,0 +1,287 @@
+package values
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceParameters is the source of the values the framework passes to every
+// application as parameters rather than in a file.
+const SourceParameters = "parameters"
+
+// SourceOverrides is the source of the values set by the overrides of an application.
+const SourceOverrides = "overrides"
+
+// ErrMissingValue is returned by RenderValueFile when a value file name refers
+// to a value that is not set.
+var ErrMissingValue = errors.New("a value it refers to is not set")
+
+// Cluster selects the cluster the values are resolved for.
+type Cluster struct {
+	// ClusterGroup is the name of the cluster group.
+	ClusterGroup string `json:"clusterGroup"`
+	// Platform is global.clusterPlatform, e.g. AWS.
+	Platform string `json:"platform,omitempty"`
+	// ClusterVersion is global.clusterVersion, e.g. 4.16.
+	ClusterVersion string `json:"clusterVersion,omitempty"`
+	// LocalClusterName is global.localClusterName.
+	LocalClusterName string `json:"localClusterName,omitempty"`
+}
+
+// ValueFile is a values file in the order the framework passes it to Helm.
+type ValueFile struct {
+	// Path is the file as listed in the application, e.g. /values-global.yaml.
+	Path string `json:"path"`
+	// Found reports whether the file exists. Missing files are ignored, like
+	// the framework does.
+	Found bool `json:"found"`
+}
+
+// Application holds the effective values of an application.
+type Application struct {
+	Key        string                 `json:"key"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Path       string                 `json:"path,omitempty"`
+	Chart      string                 `json:"chart,omitempty"`
+	ValueFiles []ValueFile            `json:"valueFiles"`
+	Values     map[string]interface{} `json:"values"`
+	// Sources maps the path of every value, in Helm's --set syntax, to the file
+	// or source that set it.
+	Sources map[string]string `json:"sources"`
+}
+
+// Result is the outcome of Resolve.
+type Result struct {
+	Cluster      Cluster       `json:"cluster"`
+	Applications []Application `json:"applications"`
+	Warnings     []string      `json:"warnings"`
+}
+
+// Resolve computes the values every application of a cluster group sees. The
+// value files are loaded in the framework's precedence order: values-global,
+// the cluster group, the platform, platform-version, platform-clustergroup,
+// version-clustergroup and local cluster files, global.extraValueFiles,
+// clusterGroup.sharedValueFiles and the extraValueFiles of the application.
+// A local chart's own values.yaml is loaded first, and the overrides of the
+// application and the cluster parameters are applied last.
+func Resolve(repoRoot string, cluster Cluster) (*Result, error) {
+	result := &Result{Cluster: cluster, Applications: []Application{}, Warnings: []string{}}
+
+	globalFiles := HierarchyFiles(cluster)
+	base := New()
+	for _, file := range globalFiles {
+		if _, err := mergeFile(base, repoRoot, "", file); err != nil {
+			return nil, err
+		}
+	}
+	setParameters(base, cluster)
+
+	// global.extraValueFiles are part of every application's global files.
+	extra, err := StringList(base.Lookup("global", "extraValueFiles"), "global.extraValueFiles")
+	if err != nil {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	for _, file := range extra {
+		if _, err := mergeFile(base, repoRoot, "", file); err != nil {
+			return nil, err
+		}
+		globalFiles = append(globalFiles, file)
+	}
+	setParameters(base, cluster)
+
+	shared, err := StringList(base.Lookup("clusterGroup", "sharedValueFiles"), "clusterGroup.sharedValueFiles")
+	if err != nil {
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	sharedFiles := renderFiles(shared, base, "clusterGroup.sharedValueFiles", &result.Warnings)
+
+	applications, _ := base.Lookup("clusterGroup", "applications").(map[string]interface{})
+	for _, key := range slices.Sorted(maps.Keys(applications)) {
+		spec, ok := applications[key].(map[string]interface{})
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("application %s is not a map", key))
+			continue
+		}
+		app, err := resolveApplication(repoRoot, cluster, key, spec, base, globalFiles, sharedFiles, &result.Warnings)
+		if err != nil {
+			return nil, err
+		}
+		result.Applications = append(result.Applications, *app)
+	}
+	return result, nil
+}
+
+// resolveApplication computes the values of a single application.
+func resolveApplication(repoRoot string, cluster Cluster, key string, spec map[string]interface{}, base *Values, globalFiles, sharedFiles []string, warnings *[]string) (*Application, error) {
+	app := &Application{Key: key, Name: key}
+	if name, ok := spec["name"].(string); ok && name != "" {
+		app.Name = name
+	}
+	app.Namespace, _ = spec["namespace"].(string)
+	app.Path, _ = spec["path"].(string)
+	app.Chart, _ = spec["chart"].(string)
+
+	extra, err := StringList(spec["extraValueFiles"], fmt.Sprintf("extraValueFiles of application %s", key))
+	if err != nil {
+		*warnings = append(*warnings, err.Error())
+	}
+	files := append(append(append([]string{}, globalFiles...), sharedFiles...), renderFiles(extra, base, "extraValueFiles of application "+key, warnings)...)
+
+	values := New()
+	if app.Path != "" {
+		chartValues := path.Join(app.Path, "values.yaml")
+		if _, err := mergeFile(values, repoRoot, "", "/"+chartValues); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		found, err := mergeFile(values, repoRoot, app.Path, file)
+		if err != nil {
+			return nil, err
+		}
+		app.ValueFiles = append(app.ValueFiles, ValueFile{Path: file, Found: found})
+	}
+
+	overrides, _ := spec["overrides"].([]interface{})
+	for _, item := range overrides {
+		override, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := override["name"].(string)
+		if name == "" {
+			continue
+		}
+		values.Set(name, fmt.Sprint(override["value"]), SourceOverrides)
+	}
+	setParameters(values, cluster)
+
+	app.Values = values.Tree
+	app.Sources = values.Sources
+	return app, nil
+}
+
+// HierarchyFiles lists the root values files the framework loads for a cluster,
+// in order of precedence.
+func HierarchyFiles(cluster Cluster) []string {
+	files := []string{"/values-global.yaml", fmt.Sprintf("/values-%s.yaml", cluster.ClusterGroup)}
+	if cluster.Platform != "" {
+		files = append(files, fmt.Sprintf("/values-%s.yaml", cluster.Platform))
+		if cluster.ClusterVersion != "" {
+			files = append(files, fmt.Sprintf("/values-%s-%s.yaml", cluster.Platform, cluster.ClusterVersion))
+		}
+		files = append(files, fmt.Sprintf("/values-%s-%s.yaml", cluster.Platform, cluster.ClusterGroup))
+	}
+	if cluster.ClusterVersion != "" {
+		files = append(files, fmt.Sprintf("/values-%s-%s.yaml", cluster.ClusterVersion, cluster.ClusterGroup))
+	}
+	if cluster.LocalClusterName != "" {
+		files = append(files, fmt.Sprintf("/values-%s.yaml", cluster.LocalClusterName))
+	}
+	return files
+}
+
+// setParameters sets the global values the framework passes as parameters.
+func setParameters(values *Values, cluster Cluster) {
+	if cluster.Platform != "" {
+		values.Set("global.clusterPlatform", cluster.Platform, SourceParameters)
+	}
+	if cluster.ClusterVersion != "" {
+		values.Set("global.clusterVersion", cluster.ClusterVersion, SourceParameters)
+	}
+	if cluster.LocalClusterName != "" {
+		values.Set("global.localClusterName", cluster.LocalClusterName, SourceParameters)
+	}
+}
+
+// mergeFile merges a values file into values and reports whether it exists.
+// Absolute paths are relative to the repository root, other paths to the
+// application path, and $patternref points at the repository root in
+// multi-source applications.
+func mergeFile(values *Values, repoRoot, appPath, file string) (bool, error) {
+	rel := strings.TrimPrefix(file, "$patternref")
+	if !strings.HasPrefix(rel, "/") {
+		rel = path.Join(appPath, rel)
+	}
+	fullPath := filepath.Join(repoRoot, filepath.FromSlash(strings.TrimPrefix(rel, "/")))
+
+	data, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", fullPath, err)
+	}
+	var overlay map[string]interface{}
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return false, fmt.Errorf("failed to unmarshal YAML from %s: %w", fullPath, err)
+	}
+	values.Merge(overlay, file)
+	return true, nil
+}
+
+// renderFiles renders value file names with RenderValueFile, using values as
+// .Values. Names that cannot be rendered are reported as warnings and skipped.
+func renderFiles(files []string, values *Values, field string, warnings *[]string) []string {
+	rendered := make([]string, 0, len(files))
+	for _, file := range files {
+		name, err := RenderValueFile(file, values.Tree)
+		if errors.Is(err, ErrMissingValue) {
+			*warnings = append(*warnings, fmt.Sprintf("skipping %q in %s: %v", file, field, ErrMissingValue))
+			continue
+		}
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("cannot parse %q in %s: %v", file, field, err))
+			continue
+		}
+		rendered = append(rendered, name)
+	}
+	return rendered
+}
+
+// RenderValueFile renders a value file name like the framework's tpl calls,
+// using tree as .Values. Helm renders missing values as empty strings, which
+// names a file that does not exist and is ignored; such names fail with
+// ErrMissingValue instead so that callers can leave them out.
+func RenderValueFile(file string, tree map[string]interface{}) (string, error) {
+	tmpl, err := template.New("valueFile").Option("missingkey=error").Parse(file)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{"Values": tree}); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMissingValue, err)
+	}
+	return buf.String(), nil
+}
+
+// StringList converts a YAML list of strings. field names the list in errors.
+func StringList(value interface{}, field string) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list", field)
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s contains a non-string entry", field)
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
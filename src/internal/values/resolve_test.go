@@ -0,0 +1,125 @@
+package values
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeFiles writes files relative to dir, creating directories as needed.
+func writeFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+}
+
+var _ = Describe("Resolve", func() {
+	var repoRoot string
+
+	BeforeEach(func() {
+		repoRoot = GinkgoT().TempDir()
+		writeFiles(repoRoot, map[string]string{
+			"values-global.yaml": `global:
+  pattern: demo
+  extraValueFiles:
+    - /extra/values-common.yaml
+main:
+  clusterGroupName: hub
+level: global
+`,
+			"values-hub.yaml": `clusterGroup:
+  name: hub
+  sharedValueFiles:
+    - '/overrides/values-{{ $.Values.global.clusterPlatform }}.yaml'
+    - '/overrides/values-{{ $.Values.global.localClusterName }}.yaml'
+  applications:
+    app:
+      name: web
+      namespace: demo
+      path: charts/app
+      extraValueFiles:
+        - /overrides/app-{{ $.Values.global.clusterVersion }}.yaml
+      overrides:
+        - name: image.pullPolicy
+          value: Always
+    remote:
+      name: remote
+      namespace: demo
+      chart: remote-chart
+level: clustergroup
+`,
+			"values-AWS.yaml":             "level: platform\n",
+			"values-AWS-4.21.yaml":        "level: platform-version\n",
+			"values-AWS-hub.yaml":         "level: platform-clustergroup\n",
+			"values-4.21-hub.yaml":        "level: version-clustergroup\n",
+			"extra/values-common.yaml":    "extra: true\n",
+			"overrides/values-AWS.yaml":   "shared: aws\n",
+			"overrides/app-4.21.yaml":     "image:\n  tag: v2\n",
+			"charts/app/values.yaml":      "image:\n  repository: web\n  tag: v1\n  pullPolicy: IfNotPresent\nlevel: chart\n",
+			"values-other-cluster.yaml":   "level: other\n",
+			"values-local-cluster-1.yaml": "level: local\n",
+		})
+	})
+
+	It("should merge the values files in the framework's order", func() {
+		result, err := Resolve(repoRoot, Cluster{ClusterGroup: "hub", Platform: "AWS", ClusterVersion: "4.21"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Applications).To(HaveLen(2))
+
+		app := result.Applications[0]
+		Expect(app.Key).To(Equal("app"))
+		Expect(app.Name).To(Equal("web"))
+		Expect(app.ValueFiles).To(Equal([]ValueFile{
+			{Path: "/values-global.yaml", Found: true},
+			{Path: "/values-hub.yaml", Found: true},
+			{Path: "/values-AWS.yaml", Found: true},
+			{Path: "/values-AWS-4.21.yaml", Found: true},
+			{Path: "/values-AWS-hub.yaml", Found: true},
+			{Path: "/values-4.21-hub.yaml", Found: true},
+			{Path: "/extra/values-common.yaml", Found: true},
+			{Path: "/overrides/values-AWS.yaml", Found: true},
+			{Path: "/overrides/app-4.21.yaml", Found: true},
+		}))
+
+		Expect(app.Values).To(HaveKeyWithValue("level", "version-clustergroup"))
+		Expect(app.Sources).To(HaveKeyWithValue("level", "/values-4.21-hub.yaml"))
+		Expect(app.Values).To(HaveKeyWithValue("image", map[string]interface{}{"repository": "web", "tag": "v2", "pullPolicy": "Always"}))
+		Expect(app.Sources).To(HaveKeyWithValue("image.repository", "/charts/app/values.yaml"))
+		Expect(app.Sources).To(HaveKeyWithValue("image.tag", "/overrides/app-4.21.yaml"))
+		Expect(app.Sources).To(HaveKeyWithValue("image.pullPolicy", SourceOverrides))
+		Expect(app.Sources).To(HaveKeyWithValue("extra", "/extra/values-common.yaml"))
+		Expect(app.Sources).To(HaveKeyWithValue("shared", "/overrides/values-AWS.yaml"))
+		Expect(app.Sources).To(HaveKeyWithValue("global.clusterPlatform", SourceParameters))
+
+		remote := result.Applications[1]
+		Expect(remote.Chart).To(Equal("remote-chart"))
+		Expect(remote.Values).NotTo(HaveKey("image"))
+		Expect(remote.Values).To(HaveKeyWithValue("shared", "aws"))
+
+		Expect(result.Warnings).To(ConsistOf(ContainSubstring("values-{{ $.Values.global.localClusterName }}.yaml")))
+	})
+
+	It("should load the local cluster file and skip missing files", func() {
+		result, err := Resolve(repoRoot, Cluster{ClusterGroup: "hub", LocalClusterName: "local-cluster-1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		app := result.Applications[0]
+		Expect(app.Values).To(HaveKeyWithValue("level", "local"))
+		Expect(app.ValueFiles).To(ContainElement(ValueFile{Path: "/overrides/values-local-cluster-1.yaml", Found: false}))
+		Expect(app.Values).NotTo(HaveKey("shared"))
+		Expect(result.Warnings).To(ConsistOf(
+			ContainSubstring("global.clusterPlatform"),
+			ContainSubstring("global.clusterVersion"),
+		))
+	})
+
+	It("should fail on invalid YAML", func() {
+		writeFiles(repoRoot, map[string]string{"values-AWS.yaml": "level: [\n"})
+		_, err := Resolve(repoRoot, Cluster{ClusterGroup: "hub", Platform: "AWS"})
+		Expect(err).To(MatchError(ContainSubstring("values-AWS.yaml")))
+	})
+})
@@ -0,0 +1,13 @@
+package values
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestValues(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Values Suite")
+}
@@ -0,0 +1,215 @@
+package version
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/validatedpatterns/patternizer/internal/embedded"
+	"github.com/validatedpatterns/patternizer/internal/report"
+	"github.com/validatedpatterns/patternizer/internal/skillbundle"
+)
+
+// Build metadata, populated at build time via:
+//
+//	-ldflags "-X github.com/validatedpatterns/patternizer/internal/version.Version=v2.2.0
+//	          -X github.com/validatedpatterns/patternizer/internal/version.Commit=<sha>
+//	          -X github.com/validatedpatterns/patternizer/internal/version.BuildDate=<rfc3339>"
+//
+// When they are not set, Get falls back to the module and VCS information
+// recorded by the Go toolchain.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// DevVersion is reported when no version information is available at all.
+const DevVersion = "dev"
+
+// Asset describes an embedded resource file or skill directory.
+type Asset struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// Info is the full version report for the running binary.
+type Info struct {
+	Version   string  `json:"version"`
+	Commit    string  `json:"commit"`
+	BuildDate string  `json:"buildDate"`
+	GoVersion string  `json:"goVersion"`
+	Platform  string  `json:"platform"`
+	Resources []Asset `json:"resources"`
+	Skills    []Asset `json:"skills"`
+}
+
+// Get returns the build metadata of the running binary, without the embedded asset hashes.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		applyBuildInfo(&info, buildInfo)
+	}
+
+	if info.Version == "" {
+		info.Version = DevVersion
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// applyBuildInfo fills in any fields that were not set via ldflags from the Go build info.
+func applyBuildInfo(info *Info, buildInfo *debug.BuildInfo) {
+	if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+		info.Version = buildInfo.Main.Version
+	}
+
+	var revision, vcsTime string
+	var modified bool
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			vcsTime = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+
+	if info.Commit == "" && revision != "" {
+		info.Commit = revision
+		if modified {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = vcsTime
+	}
+}
+
+// GetWithAssets returns the build metadata together with the hashes of every
+// embedded resource and skill.
+func GetWithAssets() (Info, error) {
+	info := Get()
+
+	resources, err := ResourceAssets(embedded.Resources, "resources")
+	if err != nil {
+		return info, err
+	}
+	info.Resources = resources
+
+	skills, err := SkillAssets(embedded.Skills, "skills")
+	if err != nil {
+		return info, err
+	}
+	info.Skills = skills
+
+	return info, nil
+}
+
+// ResourceAssets hashes every file in dir of fsys.
+func ResourceAssets(fsys fs.FS, dir string) ([]Asset, error) {
+	var assets []Asset
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		assets = append(assets, Asset{Name: strings.TrimPrefix(p, dir+"/"), SHA256: report.HashBytes(data)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing embedded resources: %w", err)
+	}
+	return assets, nil
+}
+
+// SkillAssets returns one asset per skill directory in dir of fsys. The hash
+// covers the path and contents of every file in the skill, and the version is
+// taken from the SKILL.md front matter when present.
+func SkillAssets(fsys fs.FS, dir string) ([]Asset, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedded skills: %w", err)
+	}
+
+	var assets []Asset
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		skillDir := path.Join(dir, entry.Name())
+		digest, err := skillbundle.DirDigest(fsys, skillDir)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, Asset{
+			Name:    entry.Name(),
+			Version: skillbundle.Version(fsys, skillDir),
+			SHA256:  digest,
+		})
+	}
+	return assets, nil
+}
+
+// String returns a one-line summary suitable for --version.
+func (i Info) String() string {
+	return fmt.Sprintf("patternizer version %s (commit %s, built %s, %s, %s)", i.Version, i.Commit, i.BuildDate, i.GoVersion, i.Platform)
+}
+
+// gitDescribeSuffix matches the "-<commits>-g<sha>[-dirty]" suffix that
+// `git describe --tags` appends to builds made after a tag.
+var gitDescribeSuffix = regexp.MustCompile(`-[0-9]+-g[0-9a-f]+(-dirty)?$`)
+
+// Canonical converts v to a canonical semantic version with a leading "v",
+// dropping any git describe suffix. It returns false if v is not a semantic version
+// (for example DevVersion).
+func Canonical(v string) (string, bool) {
+	v = strings.TrimSpace(v)
+	v = strings.TrimSuffix(v, "-dirty")
+	v = gitDescribeSuffix.ReplaceAllString(v, "")
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return "", false
+	}
+	return semver.Canonical(v), true
+}
+
+// Compare compares two semantic versions like semver.Compare. The second
+// return value is false if either version cannot be compared.
+func Compare(a, b string) (int, bool) {
+	canonicalA, okA := Canonical(a)
+	canonicalB, okB := Canonical(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	return semver.Compare(canonicalA, canonicalB), true
+}
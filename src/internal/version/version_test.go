@@ -0,0 +1,92 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("applyBuildInfo", func() {
+	It("should fall back to the module version and VCS settings", func() {
+		info := Info{}
+		applyBuildInfo(&info, &debug.BuildInfo{
+			Main: debug.Module{Version: "v2.2.0"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		})
+
+		Expect(info.Version).To(Equal("v2.2.0"))
+		Expect(info.Commit).To(Equal("abc123-dirty"))
+		Expect(info.BuildDate).To(Equal("2026-01-02T03:04:05Z"))
+	})
+
+	It("should not override values set via ldflags", func() {
+		info := Info{Version: "v9.9.9", Commit: "deadbeef", BuildDate: "today"}
+		applyBuildInfo(&info, &debug.BuildInfo{
+			Main:     debug.Module{Version: "v2.2.0"},
+			Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+		})
+
+		Expect(info).To(Equal(Info{Version: "v9.9.9", Commit: "deadbeef", BuildDate: "today"}))
+	})
+
+	It("should ignore the (devel) placeholder version", func() {
+		info := Info{}
+		applyBuildInfo(&info, &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}})
+		Expect(info.Version).To(BeEmpty())
+	})
+})
+
+var _ = Describe("SkillAssets", func() {
+	fsys := fstest.MapFS{
+		"skills/one/SKILL.md":     &fstest.MapFile{Data: []byte("---\nname: one\nversion: \"1.2.0\"\n---\n# One\n")},
+		"skills/one/reference.md": &fstest.MapFile{Data: []byte("ref")},
+		"skills/two/SKILL.md":     &fstest.MapFile{Data: []byte("# No front matter\n")},
+		"skills/three/SKILL.md":   &fstest.MapFile{Data: []byte("\ufeff---\r\nname: three\r\nversion: 1.10\r\n---\r\n# Three\r\n")},
+	}
+
+	It("should report one asset per skill with its front matter version", func() {
+		assets, err := SkillAssets(fsys, "skills")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(assets).To(HaveLen(3))
+		Expect(assets[0].Name).To(Equal("one"))
+		Expect(assets[0].Version).To(Equal("1.2.0"))
+		Expect(assets[1].Name).To(Equal("three"))
+		Expect(assets[1].Version).To(Equal("1.10"))
+		Expect(assets[2].Version).To(BeEmpty())
+	})
+})
+
+var _ = Describe("GetWithAssets", func() {
+	It("should include every embedded resource and skill", func() {
+		info, err := GetWithAssets()
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, asset := range info.Resources {
+			names = append(names, asset.Name)
+		}
+		Expect(names).To(ContainElements("pattern.sh", "Makefile-common", "ansible.cfg", "Makefile"))
+		Expect(info.Skills).NotTo(BeEmpty())
+	})
+})
+
+var _ = DescribeTable("Compare",
+	func(a, b string, expected int, comparable bool) {
+		got, ok := Compare(a, b)
+		Expect(ok).To(Equal(comparable))
+		Expect(got).To(Equal(expected))
+	},
+	Entry("equal versions", "v2.2.0", "2.2.0", 0, true),
+	Entry("older version", "v2.1.9", "v2.2.0", -1, true),
+	Entry("newer version", "v2.10.0", "v2.2.0", 1, true),
+	Entry("git describe suffix is ignored", "v2.2.0-4-gabc1234-dirty", "v2.2.0", 0, true),
+	Entry("prerelease is older", "v2.2.0-rc.1", "v2.2.0", -1, true),
+	Entry("development build", DevVersion, "v2.2.0", 0, false),
+	Entry("bare commit from git describe --always", "d2d88c1", "v2.2.0", 0, false),
+)